@@ -3,17 +3,52 @@ package app
 import (
 	"fmt"
 	"github.com/Abacode7/bookstore_users-api/controllers"
+	"github.com/Abacode7/bookstore_users-api/datasources/cache"
 	"github.com/Abacode7/bookstore_users-api/datasources/mysql"
+	"github.com/Abacode7/bookstore_users-api/domain/addresses"
+	"github.com/Abacode7/bookstore_users-api/domain/exports"
+	"github.com/Abacode7/bookstore_users-api/domain/organizations"
+	"github.com/Abacode7/bookstore_users-api/domain/outbox"
 	"github.com/Abacode7/bookstore_users-api/domain/users"
+	"github.com/Abacode7/bookstore_users-api/domain/webhooks"
+	"github.com/Abacode7/bookstore_users-api/grpc_server"
+	"github.com/Abacode7/bookstore_users-api/middlewares"
+	"github.com/Abacode7/bookstore_users-api/notifiers"
+	"github.com/Abacode7/bookstore_users-api/openapi"
+	"github.com/Abacode7/bookstore_users-api/publishers"
 	"github.com/Abacode7/bookstore_users-api/services"
 	"github.com/Abacode7/bookstore_utils-go/v2/logger"
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
 	"log"
+	"net/http"
 	"os"
+	"time"
 )
 
-var router = gin.Default()
+const (
+	defaultPasswordHistoryDepth    = 5
+	defaultDbMaxOpenConns          = 25
+	defaultDbMaxIdleConns          = 25
+	defaultDbConnMaxLifetime       = 5 * time.Minute
+	defaultUserCacheSize           = 10000
+	defaultUserCacheTTL            = time.Minute
+	defaultOutboxRelayInterval     = time.Second
+	defaultOutboxWebhookTimeout    = 10 * time.Second
+	defaultWebhookInterval         = 5 * time.Second
+	defaultWebhookTimeout          = 10 * time.Second
+	defaultUserChangesLogSize      = 1000
+	defaultStatusSchedulerInterval = time.Minute
+	defaultExportTTL               = 7 * 24 * time.Hour
+	defaultExportInterval          = 10 * time.Second
+	defaultGrpcAddress             = "127.0.0.1:9091"
+)
+
+var (
+	router = gin.Default()
+	/// apiV1DeprecatedAt is when /v2 replaced the unprefixed v1 routes
+	apiV1DeprecatedAt = time.Date(2026, time.October, 19, 0, 0, 0, 0, time.UTC)
+)
 
 func StartApplication() {
 	/// Load .env config file into the os
@@ -29,21 +64,132 @@ func StartApplication() {
 	dbPort := os.Getenv("DB_PORT")
 	dbName := os.Getenv("DB_NAME")
 
+	passwordHistoryDepth := envInt("PASSWORD_HISTORY_DEPTH", defaultPasswordHistoryDepth)
+	userCacheSize := envInt("USER_CACHE_SIZE", defaultUserCacheSize)
+	userCacheTTL := envDuration("USER_CACHE_TTL", defaultUserCacheTTL)
+
 	/// Generates connections string and opens the connection
 	/// using the provided data source
 	dataSource := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s", dbUser, dbPassword, dbHost, dbPort, dbName)
-	db, sqlErr := mysql.Init(dataSource)
+	db, sqlErr := mysql.Init(dataSource, mysql.PoolConfig{
+		MaxOpenConns:    envInt("DB_MAX_OPEN_CONNS", defaultDbMaxOpenConns),
+		MaxIdleConns:    envInt("DB_MAX_IDLE_CONNS", defaultDbMaxIdleConns),
+		ConnMaxLifetime: envDuration("DB_CONN_MAX_LIFETIME", defaultDbConnMaxLifetime),
+	})
 	if sqlErr != nil {
 		log.Fatalln(sqlErr)
 	}
 
 	/// Factory and DI: Initializes all applications layers
-	userDao := users.NewUserDao(db)
-	userService := services.NewUserService(userDao)
-	userController := controllers.NewUserController(userService)
+	notifier := notifiers.NewLogNotifier()
+	searchIndex := users.NewMySQLSearchIndex(db)
+	eventDao := outbox.NewEventDao(db)
+	mysqlUserDao, daoErr := users.NewUserDao(db, eventDao)
+	if daoErr != nil {
+		log.Fatalln(daoErr)
+	}
+	/// Users are cached in process, a shared cache.ICache is needed
+	/// once the api runs on more than one instance
+	userCacheMetrics := &cache.Metrics{}
+	userDao := users.NewCachingUserDao(
+		users.NewIndexingUserDao(mysqlUserDao, searchIndex),
+		cache.NewLRUCache(userCacheSize), userCacheTTL, userCacheMetrics)
+	passwordHistoryDao := users.NewPasswordHistoryDao(db)
+	emailChangeDao := users.NewEmailChangeDao(db)
+	userInvitationDao := users.NewUserInvitationDao(db)
+	profileDao := users.NewProfileDao(db)
+	addressDao := addresses.NewAddressDao(db)
+	organizationDao := organizations.NewOrganizationDao(db)
+	invitationDao := organizations.NewInvitationDao(db)
+	subscriptionDao := webhooks.NewSubscriptionDao(db)
+	deliveryDao := webhooks.NewDeliveryDao(db)
+
+	changeFeed := services.NewUserChangeFeed(envInt("USER_CHANGES_LOG_SIZE", defaultUserChangesLogSize))
+	passwordService := services.NewPasswordService(passwordHistoryDao, passwordHistoryDepth)
+	emailChangeService := services.NewEmailChangeService(emailChangeDao, userDao, notifier, changeFeed)
+	userService := services.NewUserService(userDao, passwordService, emailChangeService, changeFeed)
+	userInvitationService := services.NewUserInvitationService(userInvitationDao, userDao, passwordService, notifier, changeFeed)
+	profileService := services.NewProfileService(profileDao)
+	addressService := services.NewAddressService(addressDao, userDao)
+	organizationService := services.NewOrganizationService(organizationDao, invitationDao, userDao, notifier)
+	userSearchService := services.NewUserSearchService(searchIndex)
+	userIncludeService := services.NewUserIncludeService(addressService, organizationService)
+	webhookService := services.NewWebhookService(subscriptionDao, deliveryDao)
+	webhookDispatcher := services.NewWebhookDispatcher(subscriptionDao, deliveryDao,
+		&http.Client{Timeout: defaultWebhookTimeout}, envDuration("WEBHOOK_INTERVAL", defaultWebhookInterval))
+	statusScheduleDao := users.NewStatusScheduleDao(db)
+	statusScheduleService := services.NewStatusScheduleService(statusScheduleDao, userService,
+		envDuration("STATUS_SCHEDULER_INTERVAL", defaultStatusSchedulerInterval))
+	exportService := services.NewExportService(exports.NewExportDao(db), services.ExportSources{
+		UserDao:         userDao,
+		ProfileDao:      profileDao,
+		EmailChangeDao:  emailChangeDao,
+		ScheduleDao:     statusScheduleDao,
+		AddressDao:      addressDao,
+		OrganizationDao: organizationDao,
+		EventDao:        eventDao,
+	}, envDuration("EXPORT_TTL", defaultExportTTL), envDuration("EXPORT_INTERVAL", defaultExportInterval))
+	outboxRelay := services.NewOutboxRelay(eventDao, publishers.NewMultiPublisher(outboxPublisher(), webhookDispatcher),
+		envDuration("OUTBOX_RELAY_INTERVAL", defaultOutboxRelayInterval))
+
+	apiDocument := openapi.NewDocument()
+	ctlrs := appControllers{
+		user:           controllers.NewUserController(userService, profileService, userIncludeService),
+		emailChange:    controllers.NewEmailChangeController(emailChangeService),
+		userInvitation: controllers.NewUserInvitationController(userInvitationService),
+		address:        controllers.NewAddressController(addressService),
+		organization:   controllers.NewOrganizationController(organizationService),
+		userSearch:     controllers.NewUserSearchController(userSearchService, userIncludeService),
+		metrics:        controllers.NewMetricsController(userCacheMetrics),
+		webhook:        controllers.NewWebhookController(webhookService),
+		userChange:     controllers.NewUserChangeController(changeFeed),
+		openAPI:        controllers.NewOpenAPIController(apiDocument),
+		statusSchedule: controllers.NewStatusScheduleController(statusScheduleService),
+		export:         controllers.NewExportController(exportService),
+	}
+
+	/// Rate limit buckets are kept in process, a shared IRateLimitStore
+	/// is needed once the api runs on more than one instance
+	rateLimitStore := middlewares.NewMemoryRateLimitStore()
+
+	/// Relays user events written to the outbox, and sends the webhook
+	/// deliveries queued for them, in the background
+	go outboxRelay.Run(nil)
+	go webhookDispatcher.Run(nil)
+	/// Applies scheduled status changes and expiries once due, instances
+	/// skip the changes another one has claimed
+	go statusScheduleService.Run(nil)
+	/// Builds requested data exports and expires their downloads
+	go exportService.Run(nil)
+
+	/// Serves the grpc api for internal services, on the loopback
+	/// interface unless GRPC_ADDRESS names an internal one
+	grpcConfig := grpc_server.Config{
+		Address:      os.Getenv("GRPC_ADDRESS"),
+		CertFile:     os.Getenv("GRPC_TLS_CERT"),
+		KeyFile:      os.Getenv("GRPC_TLS_KEY"),
+		ClientCAFile: os.Getenv("GRPC_TLS_CLIENT_CA"),
+	}
+	if grpcConfig.Address == "" {
+		grpcConfig.Address = defaultGrpcAddress
+	}
+	go func() {
+		logger.Info("starting grpc server...")
+		if err := grpc_server.Start(grpcConfig, grpc_server.NewUserServer(userService)); err != nil {
+			log.Fatalln(err)
+		}
+	}()
 
 	/// Maps urls to controllers
-	mapUrl(userController)
+	/// Requests are validated against the api document before reaching
+	/// the rate limiters and controllers
+	router.Use(middlewares.RequestId, middlewares.ValidateRequest(apiDocument))
+	/// v1 announces its deprecation, and its sunset once API_V1_SUNSET
+	/// is set
+	mapUrl(router, ctlrs, rateLimitStore, middlewares.Deprecation{
+		DeprecatedAt: envDate("API_V1_DEPRECATED_AT", apiV1DeprecatedAt),
+		Sunset:       envDate("API_V1_SUNSET", time.Time{}),
+	})
 
 	/// Starts the server
 	logger.Info("starting server...")
@@ -0,0 +1,77 @@
+package app
+
+import (
+	"github.com/Abacode7/bookstore_users-api/publishers"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+)
+
+/// envInt reads a non negative integer setting, falling back to
+/// defaultValue when it isn't set
+func envInt(name string, defaultValue int) int {
+	value := os.Getenv(name)
+	if value == "" {
+		return defaultValue
+	}
+	number, err := strconv.Atoi(value)
+	if err != nil || number < 0 {
+		log.Fatalln("invalid "+name+":", value)
+	}
+	return number
+}
+
+/// envDuration reads a duration setting such as "90s" or "5m", falling
+/// back to defaultValue when it isn't set
+func envDuration(name string, defaultValue time.Duration) time.Duration {
+	value := os.Getenv(name)
+	if value == "" {
+		return defaultValue
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration < 0 {
+		log.Fatalln("invalid "+name+":", value)
+	}
+	return duration
+}
+
+/// envDate reads a date setting such as "2026-01-31", falling back to
+/// defaultValue when it isn't set
+func envDate(name string, defaultValue time.Time) time.Time {
+	value := os.Getenv(name)
+	if value == "" {
+		return defaultValue
+	}
+	date, err := time.ParseInLocation("2006-01-02", value, time.UTC)
+	if err != nil {
+		log.Fatalln("invalid "+name+":", value)
+	}
+	return date
+}
+
+/// outboxPublisher builds the publisher outbox events are relayed to from
+/// OUTBOX_PUBLISHER: "stdout" (default), "file" writing to OUTBOX_FILE
+/// or "webhook" posting to OUTBOX_WEBHOOK_URL
+func outboxPublisher() publishers.IPublisher {
+	switch kind := os.Getenv("OUTBOX_PUBLISHER"); kind {
+	case "", "stdout":
+		return publishers.NewWriterPublisher(os.Stdout)
+	case "file":
+		file, err := os.OpenFile(os.Getenv("OUTBOX_FILE"), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		return publishers.NewWriterPublisher(file)
+	case "webhook":
+		url := os.Getenv("OUTBOX_WEBHOOK_URL")
+		if url == "" {
+			log.Fatalln("OUTBOX_WEBHOOK_URL is required by the webhook publisher")
+		}
+		return publishers.NewWebhookPublisher(url, &http.Client{Timeout: defaultOutboxWebhookTimeout})
+	default:
+		log.Fatalln("invalid OUTBOX_PUBLISHER:", kind)
+		return nil
+	}
+}
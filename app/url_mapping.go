@@ -2,17 +2,137 @@ package app
 
 import (
 	"github.com/Abacode7/bookstore_users-api/controllers"
+	"github.com/Abacode7/bookstore_users-api/middlewares"
+	"github.com/gin-gonic/gin"
 )
 
-func mapUrl(userCtlr controllers.IUserController) {
-	router.GET("/ping", controllers.Ping)
+var (
+	/// signupRateLimit allows 5 signups an hour per ip address and email
+	signupRateLimit = middlewares.RateLimit{
+		Name:  "signup",
+		Rate:  5.0 / 3600,
+		Burst: 5,
+		Keys:  []middlewares.RateLimitKey{middlewares.ByClientIP, middlewares.ByEmail},
+	}
+	/// loginIPRateLimit and loginEmailRateLimit allow 10 login attempts a
+	/// minute per ip address and 5 a minute per email, slowing down
+	/// password guessing
+	loginIPRateLimit = middlewares.RateLimit{
+		Name:  "login-ip",
+		Rate:  10.0 / 60,
+		Burst: 10,
+		Keys:  []middlewares.RateLimitKey{middlewares.ByClientIP},
+	}
+	loginEmailRateLimit = middlewares.RateLimit{
+		Name:  "login-email",
+		Rate:  5.0 / 60,
+		Burst: 5,
+		Keys:  []middlewares.RateLimitKey{middlewares.ByEmail},
+	}
+	/// exportRateLimit allows 3 export requests an hour per caller, each
+	/// of which builds an archive of a user's data. Internal callers
+	/// aren't throttled.
+	exportRateLimit = middlewares.RateLimit{
+		Name:  "export",
+		Rate:  3.0 / 3600,
+		Burst: 3,
+		Keys:  []middlewares.RateLimitKey{middlewares.ByCaller},
+	}
+)
+
+/// appControllers are the controllers urls are mapped to
+type appControllers struct {
+	user           controllers.IUserController
+	emailChange    controllers.IEmailChangeController
+	userInvitation controllers.IUserInvitationController
+	address        controllers.IAddressController
+	organization   controllers.IOrganizationController
+	userSearch     controllers.IUserSearchController
+	metrics        controllers.IMetricsController
+	webhook        controllers.IWebhookController
+	userChange     controllers.IUserChangeController
+	openAPI        controllers.IOpenAPIController
+	statusSchedule controllers.IStatusScheduleController
+	export         controllers.IExportController
+}
+
+/// mapUrl maps the urls of every api version onto engine
+func mapUrl(engine *gin.Engine, ctlrs appControllers, rateLimitStore middlewares.IRateLimitStore, v1Deprecation middlewares.Deprecation) {
+	engine.GET("/ping", controllers.Ping)
+	engine.GET("/openapi.json", ctlrs.openAPI.GetDocument)
+
+	/// v1 is served unprefixed, as it was before the api was versioned
+	v1Deprecation.SuccessorPrefix = "/v2"
+	mapVersionUrls(engine.Group("", middlewares.ApiVersion(middlewares.ApiV1), middlewares.Deprecated(v1Deprecation)),
+		ctlrs, rateLimitStore)
+	mapVersionUrls(engine.Group("/v2", middlewares.ApiVersion(middlewares.ApiV2)), ctlrs, rateLimitStore)
+}
+
+/// mapVersionUrls maps the urls every api version serves onto group,
+/// the controllers marshall responses for the group's version
+func mapVersionUrls(group *gin.RouterGroup, ctlrs appControllers, rateLimitStore middlewares.IRateLimitStore) {
+	group.POST("/users", middlewares.RateLimiter(rateLimitStore, signupRateLimit), ctlrs.user.CreateUser)
+	group.GET("/users/:user_id", ctlrs.user.GetUser)
+	group.PUT("/users/:user_id", middlewares.Authenticate, ctlrs.user.UpdateUser)
+	group.PATCH("/users/:user_id", middlewares.Authenticate, ctlrs.user.UpdateUser)
+	group.DELETE("/users/:user_id", middlewares.Authenticate, ctlrs.user.DeleteUser)
+	group.POST("/users/batch-get", ctlrs.user.BatchGetUsers)
+	group.POST("/users/login",
+		middlewares.RateLimiter(rateLimitStore, loginIPRateLimit),
+		middlewares.RateLimiter(rateLimitStore, loginEmailRateLimit),
+		ctlrs.user.LoginUser)
+	group.POST("/users/email/confirm", ctlrs.emailChange.ConfirmEmailChange)
+	group.POST("/users/email/cancel", ctlrs.emailChange.CancelEmailChange)
+	group.POST("/users/invitations/accept", ctlrs.userInvitation.AcceptInvitation)
+
+	addressRoutes := group.Group("/users/:user_id/addresses", middlewares.Authenticate)
+	addressRoutes.GET("", ctlrs.address.ListAddresses)
+	addressRoutes.POST("", ctlrs.address.CreateAddress)
+	addressRoutes.GET("/:address_id", ctlrs.address.GetAddress)
+	addressRoutes.PUT("/:address_id", ctlrs.address.UpdateAddress)
+	addressRoutes.PATCH("/:address_id", ctlrs.address.UpdateAddress)
+	addressRoutes.DELETE("/:address_id", ctlrs.address.DeleteAddress)
+
+	exportRoutes := group.Group("/users/:user_id/exports", middlewares.Authenticate)
+	exportRoutes.POST("", middlewares.RateLimiter(rateLimitStore, exportRateLimit), ctlrs.export.RequestExport)
+	exportRoutes.GET("/:export_id", ctlrs.export.GetExport)
+	exportRoutes.GET("/:export_id/download", ctlrs.export.DownloadExport)
+
+	group.GET("/users/:user_id/organizations", middlewares.Authenticate, ctlrs.organization.ListUserOrganizations)
+	organizationRoutes := group.Group("/organizations", middlewares.Authenticate)
+	organizationRoutes.POST("", ctlrs.organization.CreateOrganization)
+	organizationRoutes.POST("/invitations/accept", ctlrs.organization.AcceptInvitation)
+	organizationRoutes.GET("/:org_id", ctlrs.organization.GetOrganization)
+	organizationRoutes.GET("/:org_id/members", ctlrs.organization.ListMembers)
+	organizationRoutes.POST("/:org_id/invitations", ctlrs.organization.InviteMember)
+	organizationRoutes.PUT("/:org_id/members/:user_id", ctlrs.organization.UpdateMember)
+	organizationRoutes.DELETE("/:org_id/members/:user_id", ctlrs.organization.RemoveMember)
 
-	router.POST("/users", userCtlr.CreateUser)
-	router.GET("/users/:user_id", userCtlr.GetUser)
-	router.PUT("/users/:user_id", userCtlr.UpdateUser)
-	router.PATCH("/users/:user_id", userCtlr.UpdateUser)
-	router.DELETE("/users/:user_id", userCtlr.DeleteUser)
-	router.POST("/users/login", userCtlr.LoginUser)
+	group.GET("/internal/users/search", ctlrs.user.SearchUser)
+	group.POST("/internal/users/batch", middlewares.Authenticate, ctlrs.user.ApplyBatch)
+	statusRoutes := group.Group("/internal/users/:user_id", middlewares.Authenticate)
+	statusRoutes.POST("/suspend", ctlrs.user.SuspendUser)
+	statusRoutes.POST("/reactivate", ctlrs.user.ReactivateUser)
+	statusRoutes.GET("/status-history", ctlrs.user.GetStatusHistory)
+	statusRoutes.GET("/scheduled-status-changes", ctlrs.statusSchedule.ListChanges)
+	statusRoutes.POST("/scheduled-status-changes", ctlrs.statusSchedule.ScheduleChange)
+	statusRoutes.DELETE("/scheduled-status-changes/:change_id", ctlrs.statusSchedule.CancelChange)
+	statusRoutes.PUT("/expiry", ctlrs.statusSchedule.SetExpiry)
+	statusRoutes.DELETE("/expiry", ctlrs.statusSchedule.ClearExpiry)
+	group.GET("/internal/users/search/text", ctlrs.userSearch.SearchUsers)
+	group.GET("/internal/users/changes", ctlrs.userChange.StreamChanges)
+	group.GET("/internal/metrics/users/cache", ctlrs.metrics.GetUserCacheMetrics)
+	invitationRoutes := group.Group("/internal/users/invitations", middlewares.Authenticate)
+	invitationRoutes.POST("", ctlrs.userInvitation.InviteUser)
+	invitationRoutes.POST("/:invitation_id/resend", ctlrs.userInvitation.ResendInvitation)
+	invitationRoutes.DELETE("/:invitation_id", ctlrs.userInvitation.RevokeInvitation)
 
-	router.GET("/internal/users/search", userCtlr.SearchUser)
+	webhookRoutes := group.Group("/internal/webhooks", middlewares.Authenticate)
+	webhookRoutes.POST("", ctlrs.webhook.CreateSubscription)
+	webhookRoutes.GET("", ctlrs.webhook.ListSubscriptions)
+	webhookRoutes.GET("/dead-letters", ctlrs.webhook.ListDeadLetters)
+	webhookRoutes.POST("/deliveries/:delivery_id/retry", ctlrs.webhook.RetryDelivery)
+	webhookRoutes.GET("/:subscription_id", ctlrs.webhook.GetSubscription)
+	webhookRoutes.DELETE("/:subscription_id", ctlrs.webhook.DeleteSubscription)
+	webhookRoutes.GET("/:subscription_id/deliveries", ctlrs.webhook.ListDeliveries)
 }
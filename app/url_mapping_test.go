@@ -0,0 +1,221 @@
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"github.com/Abacode7/bookstore_users-api/controllers"
+	"github.com/Abacode7/bookstore_users-api/domain/users"
+	"github.com/Abacode7/bookstore_users-api/middlewares"
+	"github.com/Abacode7/bookstore_users-api/services"
+	"github.com/Abacode7/bookstore_users-api/utils/error_utils"
+	"github.com/Abacode7/bookstore_utils-go/v2/rest_error"
+	"github.com/gin-gonic/gin"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+/// update rewrites the golden files with the current responses. Only
+/// use it for an intended change, the files freeze the v1 contract.
+var update = flag.Bool("update", false, "update the golden files")
+
+var (
+	contractDeprecatedAt = time.Date(2026, time.October, 19, 0, 0, 0, 0, time.UTC)
+	contractSunset       = time.Date(2027, time.April, 19, 0, 0, 0, 0, time.UTC)
+)
+
+/// contractUserService serves two fixed users, 1 and 2
+type contractUserService struct {
+	services.IUserService
+}
+
+func contractUser(userId int64) users.User {
+	return users.User{
+		Id:          userId,
+		FirstName:   "John",
+		LastName:    "Doe",
+		Email:       "john.doe@example.com",
+		DateCreated: "2021-02-03 04:05:06",
+		Status:      users.StatusActive,
+		Password:    "hashed password",
+	}
+}
+
+func (cs *contractUserService) GetUser(userId int64) (*users.User, rest_error.RestErr) {
+	if userId != 1 && userId != 2 {
+		return nil, rest_error.NewNotFoundError("user not found")
+	}
+	user := contractUser(userId)
+	return &user, nil
+}
+
+func (cs *contractUserService) GetUsers(request users.BatchGetRequest) (*users.BatchGetResult, rest_error.RestErr) {
+	result := &users.BatchGetResult{Users: users.Users{}, Missing: []int64{}}
+	for _, userId := range request.Ids {
+		if userId != 1 && userId != 2 {
+			result.Missing = append(result.Missing, userId)
+			continue
+		}
+		result.Users = append(result.Users, contractUser(userId))
+	}
+	return result, nil
+}
+
+func (cs *contractUserService) LoginUser(request users.UserLoginRequest) (*users.User, rest_error.RestErr) {
+	if request.Email != "john.doe@example.com" || request.Password != "secret" {
+		return nil, rest_error.NewNotFoundError("invalid user credentials")
+	}
+	user := contractUser(1)
+	return &user, nil
+}
+
+func (cs *contractUserService) DeleteUser(caller services.Caller, userId int64) rest_error.RestErr {
+	return error_utils.NewConflictError("cannot change status from deleted to deleted")
+}
+
+/// contractProfileService gives every user a profile showing only the
+/// phone number publicly
+type contractProfileService struct {
+	services.IProfileService
+}
+
+func (cs *contractProfileService) GetProfile(userId int64, tenantId int64) (*users.Profile, rest_error.RestErr) {
+	return &users.Profile{
+		Phone:      "+2348012345678",
+		Locale:     "en-NG",
+		Visibility: users.ProfileVisibility{Phone: true},
+	}, nil
+}
+
+func newContractRouter(v1Deprecation middlewares.Deprecation) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	mapUrl(engine, appControllers{
+		user:           controllers.NewUserController(&contractUserService{}, &contractProfileService{}, nil),
+		emailChange:    controllers.NewEmailChangeController(nil),
+		userInvitation: controllers.NewUserInvitationController(nil),
+		address:        controllers.NewAddressController(nil),
+		organization:   controllers.NewOrganizationController(nil),
+		userSearch:     controllers.NewUserSearchController(nil, nil),
+		metrics:        controllers.NewMetricsController(nil),
+		webhook:        controllers.NewWebhookController(nil),
+		userChange:     controllers.NewUserChangeController(nil),
+		openAPI:        controllers.NewOpenAPIController(nil),
+		statusSchedule: controllers.NewStatusScheduleController(nil),
+		export:         controllers.NewExportController(nil),
+	}, middlewares.NewMemoryRateLimitStore(), v1Deprecation)
+	return engine
+}
+
+/// TestV1Contract freezes the bodies the unprefixed v1 routes serve.
+/// Run with -update to rewrite testdata/v1 after an intended change.
+func TestV1Contract(t *testing.T) {
+	engine := newContractRouter(middlewares.Deprecation{DeprecatedAt: contractDeprecatedAt, Sunset: contractSunset})
+	cases := []struct {
+		golden string
+		method string
+		path   string
+		body   string
+		public bool
+		status int
+	}{
+		{"get_user_private", http.MethodGet, "/users/1", "", false, http.StatusOK},
+		{"get_user_public", http.MethodGet, "/users/1", "", true, http.StatusOK},
+		{"batch_get_users_private", http.MethodPost, "/users/batch-get", `{"ids":[2,1,3]}`, false, http.StatusOK},
+		{"batch_get_users_public", http.MethodPost, "/users/batch-get", `{"ids":[2,1,3]}`, true, http.StatusOK},
+		{"login_user_private", http.MethodPost, "/users/login", `{"email":"john.doe@example.com","password":"secret"}`, false, http.StatusOK},
+		{"login_user_public", http.MethodPost, "/users/login", `{"email":"john.doe@example.com","password":"secret"}`, true, http.StatusOK},
+		{"error_bad_request", http.MethodGet, "/users/abc", "", false, http.StatusBadRequest},
+		{"error_not_found", http.MethodGet, "/users/3", "", false, http.StatusNotFound},
+		{"error_conflict", http.MethodDelete, "/users/1", "", false, http.StatusConflict},
+	}
+	for _, c := range cases {
+		t.Run(c.golden, func(t *testing.T) {
+			request := httptest.NewRequest(c.method, c.path, strings.NewReader(c.body))
+			if c.body != "" {
+				request.Header.Set("Content-Type", "application/json")
+			}
+			if c.public {
+				request.Header.Set("X-Public", "true")
+			}
+			response := httptest.NewRecorder()
+			engine.ServeHTTP(response, request)
+
+			if response.Code != c.status {
+				t.Fatalf("got status %d, want %d: %s", response.Code, c.status, response.Body)
+			}
+			if contentType := response.Header().Get("Content-Type"); !strings.HasPrefix(contentType, "application/json") {
+				t.Errorf("got content type %q, want application/json", contentType)
+			}
+			var body bytes.Buffer
+			if err := json.Indent(&body, response.Body.Bytes(), "", "  "); err != nil {
+				t.Fatalf("invalid json body %s: %v", response.Body, err)
+			}
+			body.WriteByte('\n')
+			assertGolden(t, filepath.Join("testdata", "v1", c.golden+".json"), body.Bytes())
+		})
+	}
+}
+
+func assertGolden(t *testing.T, path string, got []byte) {
+	t.Helper()
+	if *update {
+		if err := ioutil.WriteFile(path, got, 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("%s changed:\ngot:\n%s\nwant:\n%s", path, got, want)
+	}
+}
+
+func TestV1DeprecationHeaders(t *testing.T) {
+	engine := newContractRouter(middlewares.Deprecation{DeprecatedAt: contractDeprecatedAt, Sunset: contractSunset})
+
+	response := httptest.NewRecorder()
+	engine.ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/users/1", nil))
+	headers := map[string]string{
+		"Deprecation": "@1792368000",
+		"Link":        `</v2/users/1>; rel="successor-version"`,
+		"Sunset":      "Mon, 19 Apr 2027 00:00:00 GMT",
+	}
+	for name, want := range headers {
+		if got := response.Header().Get(name); got != want {
+			t.Errorf("v1 %s header: got %q, want %q", name, got, want)
+		}
+	}
+
+	response = httptest.NewRecorder()
+	engine.ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/v2/users/1", nil))
+	if response.Code != http.StatusOK {
+		t.Fatalf("v2: got status %d, want %d", response.Code, http.StatusOK)
+	}
+	for name := range headers {
+		if got := response.Header().Get(name); got != "" {
+			t.Errorf("v2 %s header: got %q, want none", name, got)
+		}
+	}
+}
+
+func TestV1SunsetHeaderOnlyOnceDecided(t *testing.T) {
+	engine := newContractRouter(middlewares.Deprecation{DeprecatedAt: contractDeprecatedAt})
+
+	response := httptest.NewRecorder()
+	engine.ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/users/1", nil))
+	if got := response.Header().Get("Sunset"); got != "" {
+		t.Errorf("got Sunset %q, want none before a date is decided", got)
+	}
+	if got := response.Header().Get("Deprecation"); got != "@1792368000" {
+		t.Errorf("got Deprecation %q, want @1792368000", got)
+	}
+}
@@ -0,0 +1,135 @@
+package controllers
+
+import (
+	"github.com/Abacode7/bookstore_users-api/domain/addresses"
+	"github.com/Abacode7/bookstore_users-api/problems"
+	"github.com/Abacode7/bookstore_users-api/services"
+	"github.com/Abacode7/bookstore_utils-go/v2/rest_error"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+)
+
+type IAddressController interface {
+	CreateAddress(c *gin.Context)
+	GetAddress(c *gin.Context)
+	ListAddresses(c *gin.Context)
+	UpdateAddress(c *gin.Context)
+	DeleteAddress(c *gin.Context)
+}
+
+type addressController struct {
+	addressService services.IAddressService
+}
+
+/// NewAddressController is addressController's constructor
+func NewAddressController(as services.IAddressService) *addressController {
+	return &addressController{as}
+}
+
+func (ac *addressController) CreateAddress(c *gin.Context) {
+	userId, restErr := authorizedUserParam(c)
+	if restErr != nil {
+		problems.Respond(c, restErr)
+		return
+	}
+	var address addresses.Address
+	if err := c.ShouldBindJSON(&address); err != nil {
+		jsonErr := rest_error.NewBadRequestError("invalid json body")
+		problems.Respond(c, jsonErr)
+		return
+	}
+	address.UserId = userId
+	result, err := ac.addressService.CreateAddress(address)
+	if err != nil {
+		problems.Respond(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, result)
+}
+
+func (ac *addressController) GetAddress(c *gin.Context) {
+	userId, restErr := authorizedUserParam(c)
+	if restErr != nil {
+		problems.Respond(c, restErr)
+		return
+	}
+	addressId, restErr := addressParam(c)
+	if restErr != nil {
+		problems.Respond(c, restErr)
+		return
+	}
+	result, err := ac.addressService.GetAddress(userId, addressId)
+	if err != nil {
+		problems.Respond(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+func (ac *addressController) ListAddresses(c *gin.Context) {
+	userId, restErr := authorizedUserParam(c)
+	if restErr != nil {
+		problems.Respond(c, restErr)
+		return
+	}
+	result, err := ac.addressService.ListAddresses(userId)
+	if err != nil {
+		problems.Respond(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+func (ac *addressController) UpdateAddress(c *gin.Context) {
+	userId, restErr := authorizedUserParam(c)
+	if restErr != nil {
+		problems.Respond(c, restErr)
+		return
+	}
+	addressId, restErr := addressParam(c)
+	if restErr != nil {
+		problems.Respond(c, restErr)
+		return
+	}
+	var address addresses.Address
+	if err := c.ShouldBindJSON(&address); err != nil {
+		jsonErr := rest_error.NewBadRequestError("invalid json body")
+		problems.Respond(c, jsonErr)
+		return
+	}
+	address.Id = addressId
+	address.UserId = userId
+	result, err := ac.addressService.UpdateAddress(c.Request.Method == http.MethodPut, address)
+	if err != nil {
+		problems.Respond(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+func (ac *addressController) DeleteAddress(c *gin.Context) {
+	userId, restErr := authorizedUserParam(c)
+	if restErr != nil {
+		problems.Respond(c, restErr)
+		return
+	}
+	addressId, restErr := addressParam(c)
+	if restErr != nil {
+		problems.Respond(c, restErr)
+		return
+	}
+	if err := ac.addressService.DeleteAddress(userId, addressId); err != nil {
+		problems.Respond(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, map[string]string{"status": "deleted"})
+}
+
+func addressParam(c *gin.Context) (int64, rest_error.RestErr) {
+	addressId, err := strconv.ParseInt(c.Param("address_id"), 10, 64)
+	if err != nil {
+		return 0, rest_error.NewBadRequestError("invalid request parameter")
+	}
+	return addressId, nil
+}
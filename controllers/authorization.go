@@ -0,0 +1,41 @@
+package controllers
+
+import (
+	"github.com/Abacode7/bookstore_users-api/middlewares"
+	"github.com/Abacode7/bookstore_users-api/services"
+	"github.com/Abacode7/bookstore_users-api/utils/error_utils"
+	"github.com/Abacode7/bookstore_utils-go/v2/rest_error"
+	"github.com/gin-gonic/gin"
+	"strconv"
+)
+
+/// authorizeUser checks that the caller may act on the records of the
+/// user with userId: either it's their own or the caller is privileged
+func authorizeUser(c *gin.Context, userId int64) rest_error.RestErr {
+	if middlewares.IsPrivileged(c) || middlewares.GetCallerId(c) == userId {
+		return nil
+	}
+	return error_utils.NewForbiddenError("not allowed to act on this user")
+}
+
+/// authorizedUserParam parses the user_id path parameter and checks the
+/// caller may act on that user's records
+func authorizedUserParam(c *gin.Context) (int64, rest_error.RestErr) {
+	userId, err := strconv.ParseInt(c.Param("user_id"), 10, 64)
+	if err != nil {
+		return 0, rest_error.NewBadRequestError("invalid request parameter")
+	}
+	if err := authorizeUser(c, userId); err != nil {
+		return 0, err
+	}
+	return userId, nil
+}
+
+/// getCaller describes the authenticated caller to services that
+/// enforce access rules themselves
+func getCaller(c *gin.Context) services.Caller {
+	return services.Caller{
+		UserId:     middlewares.GetCallerId(c),
+		Privileged: middlewares.IsPrivileged(c),
+	}
+}
@@ -0,0 +1,73 @@
+package controllers
+
+import (
+	"github.com/Abacode7/bookstore_oauth-go/oauth"
+	"github.com/Abacode7/bookstore_users-api/problems"
+	"github.com/Abacode7/bookstore_users-api/services"
+	"github.com/Abacode7/bookstore_utils-go/v2/rest_error"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strings"
+)
+
+type IEmailChangeController interface {
+	ConfirmEmailChange(c *gin.Context)
+	CancelEmailChange(c *gin.Context)
+}
+
+type emailChangeController struct {
+	emailChangeService services.IEmailChangeService
+}
+
+type emailChangeTokenRequest struct {
+	Token string `json:"token"`
+}
+
+/// NewEmailChangeController is emailChangeController's constructor
+func NewEmailChangeController(ecs services.IEmailChangeService) *emailChangeController {
+	return &emailChangeController{ecs}
+}
+
+func (ecc *emailChangeController) ConfirmEmailChange(c *gin.Context) {
+	token, restErr := bindEmailChangeToken(c)
+	if restErr != nil {
+		problems.Respond(c, restErr)
+		return
+	}
+	resultUser, err := ecc.emailChangeService.ConfirmChange(token)
+	if err != nil {
+		problems.Respond(c, err)
+		return
+	}
+	result, marshErr := marshallUser(c, resultUser, oauth.IsPublic(c.Request))
+	if marshErr != nil {
+		problems.Respond(c, marshErr)
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+func (ecc *emailChangeController) CancelEmailChange(c *gin.Context) {
+	token, restErr := bindEmailChangeToken(c)
+	if restErr != nil {
+		problems.Respond(c, restErr)
+		return
+	}
+	if err := ecc.emailChangeService.CancelChange(token); err != nil {
+		problems.Respond(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, map[string]string{"status": "cancelled"})
+}
+
+func bindEmailChangeToken(c *gin.Context) (string, rest_error.RestErr) {
+	var request emailChangeTokenRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		return "", rest_error.NewBadRequestError("invalid json body")
+	}
+	token := strings.TrimSpace(request.Token)
+	if token == "" {
+		return "", rest_error.NewBadRequestError("invalid token")
+	}
+	return token, nil
+}
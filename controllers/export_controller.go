@@ -0,0 +1,106 @@
+package controllers
+
+import (
+	"fmt"
+	"github.com/Abacode7/bookstore_users-api/domain/exports"
+	"github.com/Abacode7/bookstore_users-api/middlewares"
+	"github.com/Abacode7/bookstore_users-api/problems"
+	"github.com/Abacode7/bookstore_users-api/services"
+	"github.com/Abacode7/bookstore_utils-go/v2/rest_error"
+	"github.com/gin-gonic/gin"
+	"io"
+	"net/http"
+)
+
+type IExportController interface {
+	RequestExport(c *gin.Context)
+	GetExport(c *gin.Context)
+	DownloadExport(c *gin.Context)
+}
+
+type exportController struct {
+	exportService services.IExportService
+}
+
+/// NewExportController is exportController's constructor
+func NewExportController(es services.IExportService) *exportController {
+	return &exportController{es}
+}
+
+/// RequestExport queues an export and points at its status resource,
+/// an empty body asks for a json archive
+func (ec *exportController) RequestExport(c *gin.Context) {
+	userId, err := int64Param(c, "user_id")
+	if err != nil {
+		problems.Respond(c, err)
+		return
+	}
+	var request exports.ExportRequest
+	if bindErr := c.ShouldBindJSON(&request); bindErr != nil && bindErr != io.EOF {
+		restErr := rest_error.NewBadRequestError("invalid json body")
+		problems.Respond(c, restErr)
+		return
+	}
+	export, err := ec.exportService.RequestExport(getCaller(c), userId, request)
+	if err != nil {
+		problems.Respond(c, err)
+		return
+	}
+	c.Header("Location", exportUrl(c, export))
+	c.JSON(http.StatusAccepted, export)
+}
+
+func (ec *exportController) GetExport(c *gin.Context) {
+	userId, exportId, err := exportParams(c)
+	if err != nil {
+		problems.Respond(c, err)
+		return
+	}
+	export, err := ec.exportService.GetExport(getCaller(c), userId, exportId)
+	if err != nil {
+		problems.Respond(c, err)
+		return
+	}
+	if export.Status == exports.StatusCompleted {
+		export.DownloadUrl = exportUrl(c, export) + "/download"
+	}
+	c.JSON(http.StatusOK, export)
+}
+
+func (ec *exportController) DownloadExport(c *gin.Context) {
+	userId, exportId, err := exportParams(c)
+	if err != nil {
+		problems.Respond(c, err)
+		return
+	}
+	export, archive, err := ec.exportService.DownloadExport(getCaller(c), userId, exportId)
+	if err != nil {
+		problems.Respond(c, err)
+		return
+	}
+	c.Header("Content-Disposition",
+		fmt.Sprintf(`attachment; filename="user-%d-export-%d.%s"`, export.UserId, export.Id, export.Format))
+	c.Data(http.StatusOK, export.ContentType(), archive)
+}
+
+func exportParams(c *gin.Context) (int64, int64, rest_error.RestErr) {
+	userId, err := int64Param(c, "user_id")
+	if err != nil {
+		return 0, 0, err
+	}
+	exportId, err := int64Param(c, "export_id")
+	if err != nil {
+		return 0, 0, err
+	}
+	return userId, exportId, nil
+}
+
+/// exportUrl is the status resource of export, in the api version of
+/// the request
+func exportUrl(c *gin.Context, export *exports.Export) string {
+	prefix := ""
+	if middlewares.GetApiVersion(c) >= middlewares.ApiV2 {
+		prefix = "/v2"
+	}
+	return fmt.Sprintf("%s/users/%d/exports/%d", prefix, export.UserId, export.Id)
+}
@@ -0,0 +1,24 @@
+package controllers
+
+import (
+	"github.com/Abacode7/bookstore_users-api/datasources/cache"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+type IMetricsController interface {
+	GetUserCacheMetrics(c *gin.Context)
+}
+
+type metricsController struct {
+	userCacheMetrics *cache.Metrics
+}
+
+/// NewMetricsController is metricsController's constructor
+func NewMetricsController(userCacheMetrics *cache.Metrics) *metricsController {
+	return &metricsController{userCacheMetrics}
+}
+
+func (mc *metricsController) GetUserCacheMetrics(c *gin.Context) {
+	c.JSON(http.StatusOK, mc.userCacheMetrics.Snapshot())
+}
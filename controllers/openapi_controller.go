@@ -0,0 +1,24 @@
+package controllers
+
+import (
+	"github.com/Abacode7/bookstore_users-api/openapi"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+type IOpenAPIController interface {
+	GetDocument(c *gin.Context)
+}
+
+type openAPIController struct {
+	doc *openapi.Document
+}
+
+/// NewOpenAPIController is openAPIController's constructor
+func NewOpenAPIController(doc *openapi.Document) *openAPIController {
+	return &openAPIController{doc}
+}
+
+func (oc *openAPIController) GetDocument(c *gin.Context) {
+	c.JSON(http.StatusOK, oc.doc)
+}
@@ -0,0 +1,188 @@
+package controllers
+
+import (
+	"github.com/Abacode7/bookstore_users-api/domain/organizations"
+	"github.com/Abacode7/bookstore_users-api/problems"
+	"github.com/Abacode7/bookstore_users-api/services"
+	"github.com/Abacode7/bookstore_utils-go/v2/rest_error"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+type IOrganizationController interface {
+	CreateOrganization(c *gin.Context)
+	GetOrganization(c *gin.Context)
+	ListUserOrganizations(c *gin.Context)
+	ListMembers(c *gin.Context)
+	InviteMember(c *gin.Context)
+	AcceptInvitation(c *gin.Context)
+	UpdateMember(c *gin.Context)
+	RemoveMember(c *gin.Context)
+}
+
+type organizationController struct {
+	organizationService services.IOrganizationService
+}
+
+type inviteMemberRequest struct {
+	Email string `json:"email"`
+	Role  string `json:"role"`
+}
+
+type updateMemberRequest struct {
+	Role string `json:"role"`
+}
+
+type acceptInvitationRequest struct {
+	Token string `json:"token"`
+}
+
+/// NewOrganizationController is organizationController's constructor
+func NewOrganizationController(os services.IOrganizationService) *organizationController {
+	return &organizationController{os}
+}
+
+func (oc *organizationController) CreateOrganization(c *gin.Context) {
+	var org organizations.Organization
+	if err := c.ShouldBindJSON(&org); err != nil {
+		restErr := rest_error.NewBadRequestError("invalid json body")
+		problems.Respond(c, restErr)
+		return
+	}
+	result, err := oc.organizationService.CreateOrganization(getCaller(c), org)
+	if err != nil {
+		problems.Respond(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, result)
+}
+
+func (oc *organizationController) GetOrganization(c *gin.Context) {
+	orgId, restErr := int64Param(c, "org_id")
+	if restErr != nil {
+		problems.Respond(c, restErr)
+		return
+	}
+	result, err := oc.organizationService.GetOrganization(getCaller(c), orgId)
+	if err != nil {
+		problems.Respond(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+func (oc *organizationController) ListUserOrganizations(c *gin.Context) {
+	userId, restErr := int64Param(c, "user_id")
+	if restErr != nil {
+		problems.Respond(c, restErr)
+		return
+	}
+	result, err := oc.organizationService.ListUserOrganizations(getCaller(c), userId)
+	if err != nil {
+		problems.Respond(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+func (oc *organizationController) ListMembers(c *gin.Context) {
+	orgId, restErr := int64Param(c, "org_id")
+	if restErr != nil {
+		problems.Respond(c, restErr)
+		return
+	}
+	result, err := oc.organizationService.ListMembers(getCaller(c), orgId)
+	if err != nil {
+		problems.Respond(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+func (oc *organizationController) InviteMember(c *gin.Context) {
+	orgId, restErr := int64Param(c, "org_id")
+	if restErr != nil {
+		problems.Respond(c, restErr)
+		return
+	}
+	var request inviteMemberRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		jsonErr := rest_error.NewBadRequestError("invalid json body")
+		problems.Respond(c, jsonErr)
+		return
+	}
+	result, err := oc.organizationService.InviteMember(getCaller(c), orgId, request.Email, request.Role)
+	if err != nil {
+		problems.Respond(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, result)
+}
+
+func (oc *organizationController) AcceptInvitation(c *gin.Context) {
+	var request acceptInvitationRequest
+	if err := c.ShouldBindJSON(&request); err != nil || strings.TrimSpace(request.Token) == "" {
+		jsonErr := rest_error.NewBadRequestError("invalid token")
+		problems.Respond(c, jsonErr)
+		return
+	}
+	result, err := oc.organizationService.AcceptInvitation(getCaller(c), strings.TrimSpace(request.Token))
+	if err != nil {
+		problems.Respond(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+func (oc *organizationController) UpdateMember(c *gin.Context) {
+	orgId, restErr := int64Param(c, "org_id")
+	if restErr != nil {
+		problems.Respond(c, restErr)
+		return
+	}
+	userId, restErr := int64Param(c, "user_id")
+	if restErr != nil {
+		problems.Respond(c, restErr)
+		return
+	}
+	var request updateMemberRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		jsonErr := rest_error.NewBadRequestError("invalid json body")
+		problems.Respond(c, jsonErr)
+		return
+	}
+	result, err := oc.organizationService.UpdateMemberRole(getCaller(c), orgId, userId, request.Role)
+	if err != nil {
+		problems.Respond(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+func (oc *organizationController) RemoveMember(c *gin.Context) {
+	orgId, restErr := int64Param(c, "org_id")
+	if restErr != nil {
+		problems.Respond(c, restErr)
+		return
+	}
+	userId, restErr := int64Param(c, "user_id")
+	if restErr != nil {
+		problems.Respond(c, restErr)
+		return
+	}
+	if err := oc.organizationService.RemoveMember(getCaller(c), orgId, userId); err != nil {
+		problems.Respond(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, map[string]string{"status": "removed"})
+}
+
+func int64Param(c *gin.Context, name string) (int64, rest_error.RestErr) {
+	value, err := strconv.ParseInt(c.Param(name), 10, 64)
+	if err != nil {
+		return 0, rest_error.NewBadRequestError("invalid request parameter")
+	}
+	return value, nil
+}
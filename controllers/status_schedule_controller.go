@@ -0,0 +1,112 @@
+package controllers
+
+import (
+	"github.com/Abacode7/bookstore_users-api/domain/users"
+	"github.com/Abacode7/bookstore_users-api/problems"
+	"github.com/Abacode7/bookstore_users-api/services"
+	"github.com/Abacode7/bookstore_utils-go/v2/rest_error"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+type IStatusScheduleController interface {
+	ScheduleChange(c *gin.Context)
+	ListChanges(c *gin.Context)
+	CancelChange(c *gin.Context)
+	SetExpiry(c *gin.Context)
+	ClearExpiry(c *gin.Context)
+}
+
+type statusScheduleController struct {
+	statusScheduleService services.IStatusScheduleService
+}
+
+/// NewStatusScheduleController is statusScheduleController's constructor
+func NewStatusScheduleController(sss services.IStatusScheduleService) *statusScheduleController {
+	return &statusScheduleController{sss}
+}
+
+func (ssc *statusScheduleController) ScheduleChange(c *gin.Context) {
+	userId, err := int64Param(c, "user_id")
+	if err != nil {
+		problems.Respond(c, err)
+		return
+	}
+	var request users.StatusScheduleRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		restErr := rest_error.NewBadRequestError("invalid json body")
+		problems.Respond(c, restErr)
+		return
+	}
+	result, err := ssc.statusScheduleService.ScheduleChange(getCaller(c), userId, request)
+	if err != nil {
+		problems.Respond(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, result)
+}
+
+func (ssc *statusScheduleController) ListChanges(c *gin.Context) {
+	userId, err := int64Param(c, "user_id")
+	if err != nil {
+		problems.Respond(c, err)
+		return
+	}
+	result, err := ssc.statusScheduleService.ListChanges(getCaller(c), userId)
+	if err != nil {
+		problems.Respond(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+func (ssc *statusScheduleController) CancelChange(c *gin.Context) {
+	userId, err := int64Param(c, "user_id")
+	if err != nil {
+		problems.Respond(c, err)
+		return
+	}
+	changeId, err := int64Param(c, "change_id")
+	if err != nil {
+		problems.Respond(c, err)
+		return
+	}
+	if err := ssc.statusScheduleService.CancelChange(getCaller(c), userId, changeId); err != nil {
+		problems.Respond(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, map[string]string{"status": "cancelled"})
+}
+
+func (ssc *statusScheduleController) SetExpiry(c *gin.Context) {
+	userId, err := int64Param(c, "user_id")
+	if err != nil {
+		problems.Respond(c, err)
+		return
+	}
+	var request users.ExpiryRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		restErr := rest_error.NewBadRequestError("invalid json body")
+		problems.Respond(c, restErr)
+		return
+	}
+	result, err := ssc.statusScheduleService.SetExpiry(getCaller(c), userId, request)
+	if err != nil {
+		problems.Respond(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+func (ssc *statusScheduleController) ClearExpiry(c *gin.Context) {
+	userId, err := int64Param(c, "user_id")
+	if err != nil {
+		problems.Respond(c, err)
+		return
+	}
+	if err := ssc.statusScheduleService.ClearExpiry(getCaller(c), userId); err != nil {
+		problems.Respond(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, map[string]string{"status": "cancelled"})
+}
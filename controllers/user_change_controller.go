@@ -0,0 +1,111 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/Abacode7/bookstore_oauth-go/oauth"
+	"github.com/Abacode7/bookstore_users-api/domain/users"
+	"github.com/Abacode7/bookstore_users-api/problems"
+	"github.com/Abacode7/bookstore_users-api/services"
+	"github.com/Abacode7/bookstore_users-api/utils/error_utils"
+	"github.com/Abacode7/bookstore_utils-go/v2/logger"
+	"github.com/gin-gonic/gin"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+/// heartbeatInterval keeps idle streams from being closed by proxies
+const heartbeatInterval = 15 * time.Second
+
+type IUserChangeController interface {
+	StreamChanges(c *gin.Context)
+}
+
+type userChangeController struct {
+	changeFeed services.IUserChangeFeed
+}
+
+/// NewUserChangeController is userChangeController's constructor
+func NewUserChangeController(changeFeed services.IUserChangeFeed) *userChangeController {
+	return &userChangeController{changeFeed}
+}
+
+/// StreamChanges streams user changes as server sent events, filtered by
+/// the type and status query parameters. A client reconnecting with
+/// Last-Event-ID first gets the changes it missed, or a resync event when
+/// they are no longer logged or the id was issued by another instance.
+func (ucc *userChangeController) StreamChanges(c *gin.Context) {
+	if oauth.IsPublic(c.Request) {
+		restErr := error_utils.NewForbiddenError("user changes are only available internally")
+		problems.Respond(c, restErr)
+		return
+	}
+	filter := users.UserChangeFilter{Types: listQuery(c, "type"), Statuses: listQuery(c, "status")}
+	if err := filter.Validate(); err != nil {
+		problems.Respond(c, err)
+		return
+	}
+	subscription, unsubscribe := ucc.changeFeed.Subscribe(c.GetHeader("Last-Event-ID"))
+	defer unsubscribe()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	if subscription.Resync {
+		fmt.Fprint(c.Writer, "event: resync\ndata: {}\n\n")
+	}
+	for _, change := range subscription.Backlog {
+		writeChange(c.Writer, filter, subscription, change)
+	}
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case change, ok := <-subscription.Changes:
+			/// A subscriber falling behind is dropped, it catches up from
+			/// the log when it reconnects
+			if !ok {
+				return
+			}
+			writeChange(c.Writer, filter, subscription, change)
+		case <-heartbeat.C:
+			fmt.Fprint(c.Writer, ": heartbeat\n\n")
+		}
+		c.Writer.Flush()
+	}
+}
+
+func writeChange(w io.Writer, filter users.UserChangeFilter, subscription *services.UserChangeSubscription, change users.UserChange) {
+	if !filter.Matches(change) {
+		return
+	}
+	data, err := json.Marshal(change)
+	if err != nil {
+		logger.Error("error marshalling user change", err)
+		return
+	}
+	fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", subscription.EventId(change), change.Type, data)
+}
+
+/// listQuery gets the values of a query parameter that may be repeated
+/// or comma separated
+func listQuery(c *gin.Context, name string) []string {
+	values := make([]string, 0)
+	for _, value := range c.QueryArray(name) {
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				values = append(values, item)
+			}
+		}
+	}
+	return values
+}
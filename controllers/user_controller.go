@@ -3,7 +3,10 @@ package controllers
 import (
 	"github.com/Abacode7/bookstore_oauth-go/oauth"
 	"github.com/Abacode7/bookstore_users-api/domain/users"
+	"github.com/Abacode7/bookstore_users-api/middlewares"
+	"github.com/Abacode7/bookstore_users-api/problems"
 	"github.com/Abacode7/bookstore_users-api/services"
+	"github.com/Abacode7/bookstore_users-api/utils/error_utils"
 	"github.com/Abacode7/bookstore_utils-go/v2/rest_error"
 	"github.com/gin-gonic/gin"
 	"net/http"
@@ -14,36 +17,43 @@ import (
 type IUserController interface {
 	CreateUser(c *gin.Context)
 	GetUser(c *gin.Context)
+	BatchGetUsers(c *gin.Context)
 	SearchUser(c *gin.Context)
 	UpdateUser(c *gin.Context)
 	DeleteUser(c *gin.Context)
 	LoginUser(c *gin.Context)
+	ApplyBatch(c *gin.Context)
+	SuspendUser(c *gin.Context)
+	ReactivateUser(c *gin.Context)
+	GetStatusHistory(c *gin.Context)
 }
 
 type userController struct {
-	userService services.IUserService
+	userService    services.IUserService
+	profileService services.IProfileService
+	includeService services.IUserIncludeService
 }
 
 /// NewUserController is userController's constructor
-func NewUserController(us services.IUserService) *userController {
-	return &userController{us}
+func NewUserController(us services.IUserService, ps services.IProfileService, uis services.IUserIncludeService) *userController {
+	return &userController{us, ps, uis}
 }
 
 func (uc *userController) CreateUser(c *gin.Context) {
 	var user users.User
 	if err := c.ShouldBindJSON(&user); err != nil {
 		restErr := rest_error.NewBadRequestError("invalid json body")
-		c.JSON(restErr.Status(), restErr)
+		problems.Respond(c, restErr)
 		return
 	}
 	resultUser, serviceErr := uc.userService.CreateUser(user)
 	if serviceErr != nil {
-		c.JSON(serviceErr.Status(), serviceErr)
+		problems.Respond(c, serviceErr)
 		return
 	}
-	result, marshErr := resultUser.Marshall(oauth.IsPublic(c.Request))
+	result, marshErr := marshallUser(c, resultUser, oauth.IsPublic(c.Request))
 	if marshErr != nil {
-		c.JSON(marshErr.Status(), marshErr)
+		problems.Respond(c, marshErr)
 		return
 	}
 	c.JSON(http.StatusOK, result)
@@ -51,73 +61,143 @@ func (uc *userController) CreateUser(c *gin.Context) {
 
 func (uc *userController) GetUser(c *gin.Context) {
 	if err := oauth.Authenticate(c.Request); err != nil {
-		c.JSON(err.Status, err)
+		problems.Respond(c, error_utils.NewRestErr(err.Message, err.Status))
 		return
 	}
 	id := c.Param("user_id")
 	userID, err := strconv.ParseInt(id, 10, 64)
 	if err != nil {
 		restErr := rest_error.NewBadRequestError("invalid parameter")
-		c.JSON(restErr.Status(), restErr)
+		problems.Respond(c, restErr)
+		return
+	}
+	view, viewErr := parseUserView(c, oauth.IsPublic(c.Request))
+	if viewErr != nil {
+		problems.Respond(c, viewErr)
 		return
 	}
 	resultUser, serviceErr := uc.userService.GetUser(userID)
 	if serviceErr != nil {
-		c.JSON(serviceErr.Status(), serviceErr)
+		problems.Respond(c, serviceErr)
+		return
+	}
+	resultUser.Profile, serviceErr = uc.profileService.GetProfile(userID, oauth.GetClientId(c.Request))
+	if serviceErr != nil {
+		problems.Respond(c, serviceErr)
 		return
 	}
-	result, marshErr := resultUser.Marshall(oauth.IsPublic(c.Request))
+	result, marshErr := renderUser(c, uc.includeService, view, resultUser, oauth.IsPublic(c.Request))
 	if marshErr != nil {
-		c.JSON(marshErr.Status(), marshErr)
+		problems.Respond(c, marshErr)
 		return
 	}
 	c.JSON(http.StatusOK, result)
 }
 
+/// BatchGetUsers gets up to users.MaxBatchGetIds users at once, listing
+/// the ids without a user under missing
+func (uc *userController) BatchGetUsers(c *gin.Context) {
+	if err := oauth.Authenticate(c.Request); err != nil {
+		problems.Respond(c, error_utils.NewRestErr(err.Message, err.Status))
+		return
+	}
+	var request users.BatchGetRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		restErr := rest_error.NewBadRequestError("invalid json body")
+		problems.Respond(c, restErr)
+		return
+	}
+	view, viewErr := parseUserView(c, oauth.IsPublic(c.Request))
+	if viewErr != nil {
+		problems.Respond(c, viewErr)
+		return
+	}
+	batch, serviceErr := uc.userService.GetUsers(request)
+	if serviceErr != nil {
+		problems.Respond(c, serviceErr)
+		return
+	}
+	result, marshErr := renderUsers(c, uc.includeService, view, batch.Users, oauth.IsPublic(c.Request))
+	if marshErr != nil {
+		problems.Respond(c, marshErr)
+		return
+	}
+	c.JSON(http.StatusOK, map[string]interface{}{"users": result, "missing": batch.Missing})
+}
+
 func (uc *userController) SearchUser(c *gin.Context) {
+	view, viewErr := parseUserView(c, oauth.IsPublic(c.Request))
+	if viewErr != nil {
+		problems.Respond(c, viewErr)
+		return
+	}
 	value := strings.TrimSpace(c.Query("status"))
 	users, err := uc.userService.SearchUser(value)
 	if err != nil {
-		c.JSON(err.Status(), err)
+		problems.Respond(c, err)
 		return
 	}
-	result, marshErr := users.Marshall(oauth.IsPublic(c.Request))
+	result, marshErr := renderUsers(c, uc.includeService, view, users, oauth.IsPublic(c.Request))
 	if marshErr != nil {
-		c.JSON(marshErr.Status(), marshErr)
+		problems.Respond(c, marshErr)
 		return
 	}
 	c.JSON(http.StatusOK, result)
 }
 
 func (uc *userController) UpdateUser(c *gin.Context) {
-	var user users.User
-	if err := c.ShouldBindJSON(&user); err != nil {
-		jsonErr := rest_error.NewBadRequestError("invalid json body")
-		c.JSON(jsonErr.Status(), jsonErr)
-		return
-	}
 	userId, err := strconv.ParseInt(c.Param("user_id"), 10, 64)
 	if err != nil {
 		paramErr := rest_error.NewBadRequestError("invalid request parameter")
-		c.JSON(paramErr.Status(), paramErr)
+		problems.Respond(c, paramErr)
+		return
+	}
+	if authErr := authorizeUser(c, userId); authErr != nil {
+		problems.Respond(c, authErr)
 		return
 	}
-	user.Id = userId
 
-	var isTotalUpdate bool
-	if c.Request.Method == "PUT" {
-		isTotalUpdate = true
+	var resultUser *users.User
+	var sevErr rest_error.RestErr
+	contentType := c.ContentType()
+	if c.Request.Method == http.MethodPatch &&
+		(contentType == users.MergePatchContentType || contentType == users.JSONPatchContentType) {
+		body, readErr := c.GetRawData()
+		if readErr != nil {
+			bodyErr := rest_error.NewBadRequestError("invalid request body")
+			problems.Respond(c, bodyErr)
+			return
+		}
+		resultUser, sevErr = uc.userService.PatchUser(userId, users.UserPatch{ContentType: contentType, Body: body})
 	} else {
-		isTotalUpdate = false
+		var user users.User
+		if err := c.ShouldBindJSON(&user); err != nil {
+			jsonErr := rest_error.NewBadRequestError("invalid json body")
+			problems.Respond(c, jsonErr)
+			return
+		}
+		user.Id = userId
+		// The profile, when given, is replaced as a whole. It's validated
+		// first so an invalid profile doesn't leave the user updated.
+		if user.Profile != nil {
+			if err := uc.profileService.ValidateProfile(middlewares.GetClientId(c), user.Profile); err != nil {
+				problems.Respond(c, err)
+				return
+			}
+		}
+		isTotalUpdate := c.Request.Method == http.MethodPut
+		resultUser, sevErr = uc.userService.UpdateUser(isTotalUpdate, user)
+		if sevErr == nil && user.Profile != nil {
+			resultUser.Profile, sevErr = uc.profileService.UpdateProfile(userId, middlewares.GetClientId(c), *user.Profile)
+		}
 	}
-	resultUser, sevErr := uc.userService.UpdateUser(isTotalUpdate, user)
 	if sevErr != nil {
-		c.JSON(sevErr.Status(), sevErr)
+		problems.Respond(c, sevErr)
 		return
 	}
-	result, marshErr := resultUser.Marshall(true)
+	result, marshErr := marshallUser(c, resultUser, oauth.IsPublic(c.Request) && middlewares.GetCallerId(c) != resultUser.Id)
 	if marshErr != nil {
-		c.JSON(marshErr.Status(), marshErr)
+		problems.Respond(c, marshErr)
 		return
 	}
 	c.JSON(http.StatusOK, result)
@@ -127,11 +207,15 @@ func (uc *userController) DeleteUser(c *gin.Context) {
 	userId, strErr := strconv.ParseInt(c.Param("user_id"), 10, 64)
 	if strErr != nil {
 		err := rest_error.NewBadRequestError("invalid request parameter")
-		c.JSON(err.Status(), err)
+		problems.Respond(c, err)
 		return
 	}
-	if err := uc.userService.DeleteUser(userId); err != nil {
-		c.JSON(err.Status(), err)
+	if authErr := authorizeUser(c, userId); authErr != nil {
+		problems.Respond(c, authErr)
+		return
+	}
+	if err := uc.userService.DeleteUser(getCaller(c), userId); err != nil {
+		problems.Respond(c, err)
 		return
 	}
 	c.JSON(http.StatusOK, map[string]string{"status": "deleted"})
@@ -141,18 +225,112 @@ func (uc *userController) LoginUser(c *gin.Context) {
 	var ulr users.UserLoginRequest
 	if err := c.ShouldBindJSON(&ulr); err != nil {
 		restErr := rest_error.NewBadRequestError("invalid requests body")
-		c.JSON(restErr.Status(), restErr)
+		problems.Respond(c, restErr)
 		return
 	}
 	resultUser, err := uc.userService.LoginUser(ulr)
 	if err != nil {
-		c.JSON(err.Status(), err)
+		problems.Respond(c, err)
 		return
 	}
-	result, marshErr := resultUser.Marshall(oauth.IsPublic(c.Request))
+	result, marshErr := marshallUser(c, resultUser, oauth.IsPublic(c.Request))
 	if marshErr != nil {
-		c.JSON(marshErr.Status(), marshErr)
+		problems.Respond(c, marshErr)
 		return
 	}
 	c.JSON(http.StatusOK, result)
 }
+
+/// ApplyBatch applies a batch of user writes for admin tooling. The
+/// response has a status for every operation, and the error of those
+/// that failed.
+func (uc *userController) ApplyBatch(c *gin.Context) {
+	var request users.BatchRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		restErr := rest_error.NewBadRequestError("invalid json body")
+		problems.Respond(c, restErr)
+		return
+	}
+	results, serviceErr := uc.userService.ApplyBatch(getCaller(c), request)
+	if serviceErr != nil {
+		problems.Respond(c, serviceErr)
+		return
+	}
+
+	items := make([]map[string]interface{}, len(results))
+	failed := 0
+	for i, result := range results {
+		item := map[string]interface{}{"index": result.Index, "op": result.Op, "status": http.StatusOK}
+		if result.Err != nil {
+			failed++
+			item["status"] = result.Err.Status()
+			item["error"] = problems.Body(c, result.Err)
+		} else if result.User != nil {
+			user, marshErr := marshallUser(c, result.User, false)
+			if marshErr != nil {
+				problems.Respond(c, marshErr)
+				return
+			}
+			item["user"] = user
+		}
+		items[i] = item
+	}
+	c.JSON(http.StatusOK, map[string]interface{}{
+		"results":   items,
+		"succeeded": len(results) - failed,
+		"failed":    failed,
+	})
+}
+
+func (uc *userController) SuspendUser(c *gin.Context) {
+	uc.changeStatus(c, uc.userService.SuspendUser)
+}
+
+func (uc *userController) ReactivateUser(c *gin.Context) {
+	uc.changeStatus(c, uc.userService.ReactivateUser)
+}
+
+/// changeStatus runs a status change endpoint, change being the service
+/// method making it
+func (uc *userController) changeStatus(c *gin.Context,
+	change func(services.Caller, int64, users.StatusChangeRequest) (*users.User, rest_error.RestErr)) {
+	userId, err := strconv.ParseInt(c.Param("user_id"), 10, 64)
+	if err != nil {
+		paramErr := rest_error.NewBadRequestError("invalid request parameter")
+		problems.Respond(c, paramErr)
+		return
+	}
+	var request users.StatusChangeRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		jsonErr := rest_error.NewBadRequestError("invalid json body")
+		problems.Respond(c, jsonErr)
+		return
+	}
+	resultUser, serviceErr := change(getCaller(c), userId, request)
+	if serviceErr != nil {
+		problems.Respond(c, serviceErr)
+		return
+	}
+	result, marshErr := marshallUser(c, resultUser, false)
+	if marshErr != nil {
+		problems.Respond(c, marshErr)
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+/// GetStatusHistory lists the status changes of a user, latest first
+func (uc *userController) GetStatusHistory(c *gin.Context) {
+	userId, err := strconv.ParseInt(c.Param("user_id"), 10, 64)
+	if err != nil {
+		paramErr := rest_error.NewBadRequestError("invalid request parameter")
+		problems.Respond(c, paramErr)
+		return
+	}
+	history, serviceErr := uc.userService.GetStatusHistory(getCaller(c), userId)
+	if serviceErr != nil {
+		problems.Respond(c, serviceErr)
+		return
+	}
+	c.JSON(http.StatusOK, history)
+}
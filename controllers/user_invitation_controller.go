@@ -0,0 +1,89 @@
+package controllers
+
+import (
+	"github.com/Abacode7/bookstore_oauth-go/oauth"
+	"github.com/Abacode7/bookstore_users-api/domain/users"
+	"github.com/Abacode7/bookstore_users-api/problems"
+	"github.com/Abacode7/bookstore_users-api/services"
+	"github.com/Abacode7/bookstore_utils-go/v2/rest_error"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+type IUserInvitationController interface {
+	InviteUser(c *gin.Context)
+	AcceptInvitation(c *gin.Context)
+	RevokeInvitation(c *gin.Context)
+	ResendInvitation(c *gin.Context)
+}
+
+type userInvitationController struct {
+	invitationService services.IUserInvitationService
+}
+
+/// NewUserInvitationController is userInvitationController's constructor
+func NewUserInvitationController(uis services.IUserInvitationService) *userInvitationController {
+	return &userInvitationController{uis}
+}
+
+func (uic *userInvitationController) InviteUser(c *gin.Context) {
+	var request users.UserInvitationRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		restErr := rest_error.NewBadRequestError("invalid json body")
+		problems.Respond(c, restErr)
+		return
+	}
+	result, err := uic.invitationService.InviteUser(getCaller(c), request)
+	if err != nil {
+		problems.Respond(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, result)
+}
+
+func (uic *userInvitationController) AcceptInvitation(c *gin.Context) {
+	var request users.AcceptInvitationRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		restErr := rest_error.NewBadRequestError("invalid json body")
+		problems.Respond(c, restErr)
+		return
+	}
+	resultUser, err := uic.invitationService.AcceptInvitation(request)
+	if err != nil {
+		problems.Respond(c, err)
+		return
+	}
+	result, marshErr := marshallUser(c, resultUser, oauth.IsPublic(c.Request))
+	if marshErr != nil {
+		problems.Respond(c, marshErr)
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+func (uic *userInvitationController) RevokeInvitation(c *gin.Context) {
+	invitationId, restErr := int64Param(c, "invitation_id")
+	if restErr != nil {
+		problems.Respond(c, restErr)
+		return
+	}
+	if err := uic.invitationService.RevokeInvitation(getCaller(c), invitationId); err != nil {
+		problems.Respond(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, map[string]string{"status": "revoked"})
+}
+
+func (uic *userInvitationController) ResendInvitation(c *gin.Context) {
+	invitationId, restErr := int64Param(c, "invitation_id")
+	if restErr != nil {
+		problems.Respond(c, restErr)
+		return
+	}
+	result, err := uic.invitationService.ResendInvitation(getCaller(c), invitationId)
+	if err != nil {
+		problems.Respond(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
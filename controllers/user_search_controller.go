@@ -0,0 +1,66 @@
+package controllers
+
+import (
+	"github.com/Abacode7/bookstore_oauth-go/oauth"
+	"github.com/Abacode7/bookstore_users-api/domain/users"
+	"github.com/Abacode7/bookstore_users-api/problems"
+	"github.com/Abacode7/bookstore_users-api/services"
+	"github.com/Abacode7/bookstore_utils-go/v2/rest_error"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+)
+
+type IUserSearchController interface {
+	SearchUsers(c *gin.Context)
+}
+
+type userSearchController struct {
+	searchService  services.IUserSearchService
+	includeService services.IUserIncludeService
+}
+
+/// NewUserSearchController is userSearchController's constructor
+func NewUserSearchController(uss services.IUserSearchService, uis services.IUserIncludeService) *userSearchController {
+	return &userSearchController{uss, uis}
+}
+
+func (usc *userSearchController) SearchUsers(c *gin.Context) {
+	isPublic := oauth.IsPublic(c.Request)
+	view, viewErr := parseUserView(c, isPublic)
+	if viewErr != nil {
+		problems.Respond(c, viewErr)
+		return
+	}
+	query := users.SearchQuery{Text: c.Query("q")}
+	if limit := c.Query("limit"); limit != "" {
+		var err error
+		if query.Limit, err = strconv.Atoi(limit); err != nil {
+			restErr := rest_error.NewBadRequestError("invalid limit")
+			problems.Respond(c, restErr)
+			return
+		}
+	}
+	results, err := usc.searchService.SearchUsers(query)
+	if err != nil {
+		problems.Respond(c, err)
+		return
+	}
+	userList := make(users.Users, 0, len(results))
+	for _, result := range results {
+		userList = append(userList, result.User)
+	}
+	embedded, includeErr := loadUsersIncludes(c, usc.includeService, view, userList)
+	if includeErr != nil {
+		problems.Respond(c, includeErr)
+		return
+	}
+	result, marshErr := results.MarshallWith(isPublic, func(user *users.User, isPublic bool) (interface{}, rest_error.RestErr) {
+		return applyUserView(c, view, user, isPublic, embedded[user.Id])
+	})
+	if marshErr != nil {
+		problems.Respond(c, marshErr)
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
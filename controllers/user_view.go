@@ -0,0 +1,80 @@
+package controllers
+
+import (
+	"github.com/Abacode7/bookstore_users-api/domain/users"
+	"github.com/Abacode7/bookstore_users-api/services"
+	"github.com/Abacode7/bookstore_utils-go/v2/rest_error"
+	"github.com/gin-gonic/gin"
+)
+
+/// parseUserView reads the fields and include parameters of a user read
+func parseUserView(c *gin.Context, isPublic bool) (*users.UserView, rest_error.RestErr) {
+	view := users.NewUserView(c.Query("fields"), c.Query("include"))
+	publicFields, privateFields := userFields(c)
+	if err := view.Validate(isPublic, publicFields, privateFields); err != nil {
+		return nil, err
+	}
+	return &view, nil
+}
+
+/// renderUser marshalls user for the request's api version and applies
+/// view to it
+func renderUser(c *gin.Context, includeService services.IUserIncludeService, view *users.UserView,
+	user *users.User, isPublic bool) (interface{}, rest_error.RestErr) {
+	marshalled, err := marshallUser(c, user, isPublic)
+	if err != nil || view.IsDefault() {
+		return marshalled, err
+	}
+	embedded, err := includeService.LoadIncludes(getCaller(c), user.Id, view.Includes)
+	if err != nil {
+		return nil, err
+	}
+	return view.Apply(marshalled, embedded)
+}
+
+/// renderUsers is renderUser for a list of users. The includes of all
+/// the users are loaded at once.
+func renderUsers(c *gin.Context, includeService services.IUserIncludeService, view *users.UserView,
+	userList users.Users, isPublic bool) (interface{}, rest_error.RestErr) {
+	if view.IsDefault() {
+		return marshallUsers(c, userList, isPublic)
+	}
+	embedded, err := loadUsersIncludes(c, includeService, view, userList)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]interface{}, 0, len(userList))
+	for i := range userList {
+		user, err := applyUserView(c, view, &userList[i], isPublic, embedded[userList[i].Id])
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, user)
+	}
+	return result, nil
+}
+
+/// loadUsersIncludes loads the includes view asks for of every user in
+/// userList, keyed by user id
+func loadUsersIncludes(c *gin.Context, includeService services.IUserIncludeService, view *users.UserView,
+	userList users.Users) (map[int64]map[string]interface{}, rest_error.RestErr) {
+	if len(view.Includes) == 0 {
+		return nil, nil
+	}
+	userIds := make([]int64, 0, len(userList))
+	for _, user := range userList {
+		userIds = append(userIds, user.Id)
+	}
+	return includeService.LoadUsersIncludes(getCaller(c), userIds, view.Includes)
+}
+
+/// applyUserView marshalls user for the request's api version and
+/// applies view to it, with the includes already loaded
+func applyUserView(c *gin.Context, view *users.UserView, user *users.User, isPublic bool,
+	embedded map[string]interface{}) (interface{}, rest_error.RestErr) {
+	marshalled, err := marshallUser(c, user, isPublic)
+	if err != nil || view.IsDefault() {
+		return marshalled, err
+	}
+	return view.Apply(marshalled, embedded)
+}
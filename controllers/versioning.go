@@ -0,0 +1,33 @@
+package controllers
+
+import (
+	"github.com/Abacode7/bookstore_users-api/domain/users"
+	"github.com/Abacode7/bookstore_users-api/middlewares"
+	"github.com/Abacode7/bookstore_utils-go/v2/rest_error"
+	"github.com/gin-gonic/gin"
+)
+
+/// marshallUser marshalls user in the shape of the request's api version
+func marshallUser(c *gin.Context, user *users.User, isPublic bool) (interface{}, rest_error.RestErr) {
+	if middlewares.GetApiVersion(c) >= middlewares.ApiV2 {
+		return user.MarshallV2(isPublic)
+	}
+	return user.Marshall(isPublic)
+}
+
+/// marshallUsers marshalls userList in the shape of the request's api version
+func marshallUsers(c *gin.Context, userList users.Users, isPublic bool) (interface{}, rest_error.RestErr) {
+	if middlewares.GetApiVersion(c) >= middlewares.ApiV2 {
+		return userList.MarshallV2(isPublic)
+	}
+	return userList.Marshall(isPublic)
+}
+
+/// userFields are the fields of the public and private user shapes of
+/// the request's api version
+func userFields(c *gin.Context) ([]string, []string) {
+	if middlewares.GetApiVersion(c) >= middlewares.ApiV2 {
+		return users.PublicUserV2Fields, users.PrivateUserV2Fields
+	}
+	return users.PublicUserFields, users.PrivateUserFields
+}
@@ -0,0 +1,140 @@
+package controllers
+
+import (
+	"github.com/Abacode7/bookstore_users-api/domain/webhooks"
+	"github.com/Abacode7/bookstore_users-api/problems"
+	"github.com/Abacode7/bookstore_users-api/services"
+	"github.com/Abacode7/bookstore_utils-go/v2/rest_error"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+)
+
+type IWebhookController interface {
+	CreateSubscription(c *gin.Context)
+	GetSubscription(c *gin.Context)
+	ListSubscriptions(c *gin.Context)
+	DeleteSubscription(c *gin.Context)
+	ListDeliveries(c *gin.Context)
+	ListDeadLetters(c *gin.Context)
+	RetryDelivery(c *gin.Context)
+}
+
+type webhookController struct {
+	webhookService services.IWebhookService
+}
+
+/// NewWebhookController is webhookController's constructor
+func NewWebhookController(ws services.IWebhookService) *webhookController {
+	return &webhookController{ws}
+}
+
+func (wc *webhookController) CreateSubscription(c *gin.Context) {
+	var subscription webhooks.Subscription
+	if err := c.ShouldBindJSON(&subscription); err != nil {
+		restErr := rest_error.NewBadRequestError("invalid json body")
+		problems.Respond(c, restErr)
+		return
+	}
+	result, err := wc.webhookService.CreateSubscription(getCaller(c), subscription)
+	if err != nil {
+		problems.Respond(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, result)
+}
+
+func (wc *webhookController) GetSubscription(c *gin.Context) {
+	subscriptionId, err := int64Param(c, "subscription_id")
+	if err != nil {
+		problems.Respond(c, err)
+		return
+	}
+	result, err := wc.webhookService.GetSubscription(getCaller(c), subscriptionId)
+	if err != nil {
+		problems.Respond(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+func (wc *webhookController) ListSubscriptions(c *gin.Context) {
+	result, err := wc.webhookService.ListSubscriptions(getCaller(c))
+	if err != nil {
+		problems.Respond(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+func (wc *webhookController) DeleteSubscription(c *gin.Context) {
+	subscriptionId, err := int64Param(c, "subscription_id")
+	if err != nil {
+		problems.Respond(c, err)
+		return
+	}
+	if err := wc.webhookService.DeleteSubscription(getCaller(c), subscriptionId); err != nil {
+		problems.Respond(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, map[string]string{"status": "deleted"})
+}
+
+func (wc *webhookController) ListDeliveries(c *gin.Context) {
+	subscriptionId, err := int64Param(c, "subscription_id")
+	if err != nil {
+		problems.Respond(c, err)
+		return
+	}
+	limit, err := limitQuery(c)
+	if err != nil {
+		problems.Respond(c, err)
+		return
+	}
+	result, err := wc.webhookService.ListDeliveries(getCaller(c), subscriptionId, c.Query("status"), limit)
+	if err != nil {
+		problems.Respond(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+func (wc *webhookController) ListDeadLetters(c *gin.Context) {
+	limit, err := limitQuery(c)
+	if err != nil {
+		problems.Respond(c, err)
+		return
+	}
+	result, err := wc.webhookService.ListDeadLetters(getCaller(c), limit)
+	if err != nil {
+		problems.Respond(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+func (wc *webhookController) RetryDelivery(c *gin.Context) {
+	deliveryId, err := int64Param(c, "delivery_id")
+	if err != nil {
+		problems.Respond(c, err)
+		return
+	}
+	if err := wc.webhookService.RetryDelivery(getCaller(c), deliveryId); err != nil {
+		problems.Respond(c, err)
+		return
+	}
+	c.JSON(http.StatusAccepted, map[string]string{"status": webhooks.DeliveryPending})
+}
+
+/// limitQuery parses the optional limit query parameter, 0 when absent
+func limitQuery(c *gin.Context) (int, rest_error.RestErr) {
+	value := c.Query("limit")
+	if value == "" {
+		return 0, nil
+	}
+	limit, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, rest_error.NewBadRequestError("invalid limit")
+	}
+	return limit, nil
+}
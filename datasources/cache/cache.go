@@ -0,0 +1,55 @@
+package cache
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+/// ICache stores serialized values by key. An in-process LRU serves a
+/// single instance, a shared implementation (e.g. redis) is needed to
+/// keep replicas consistent.
+type ICache interface {
+	/// Get returns the value at key and whether it was found
+	Get(key string) ([]byte, bool, error)
+	Set(key string, value []byte, ttl time.Duration) error
+	Delete(key string) error
+}
+
+/// Metrics counts the outcomes of cache lookups
+type Metrics struct {
+	hits   int64
+	misses int64
+	errors int64
+}
+
+/// MetricsSnapshot is the state of Metrics at one point in time
+type MetricsSnapshot struct {
+	Hits    int64   `json:"hits"`
+	Misses  int64   `json:"misses"`
+	Errors  int64   `json:"errors"`
+	HitRate float64 `json:"hit_rate"`
+}
+
+func (m *Metrics) Hit() {
+	atomic.AddInt64(&m.hits, 1)
+}
+
+func (m *Metrics) Miss() {
+	atomic.AddInt64(&m.misses, 1)
+}
+
+func (m *Metrics) Error() {
+	atomic.AddInt64(&m.errors, 1)
+}
+
+func (m *Metrics) Snapshot() MetricsSnapshot {
+	snapshot := MetricsSnapshot{
+		Hits:   atomic.LoadInt64(&m.hits),
+		Misses: atomic.LoadInt64(&m.misses),
+		Errors: atomic.LoadInt64(&m.errors),
+	}
+	if lookups := snapshot.Hits + snapshot.Misses; lookups > 0 {
+		snapshot.HitRate = float64(snapshot.Hits) / float64(lookups)
+	}
+	return snapshot
+}
@@ -0,0 +1,85 @@
+package cache
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+type lruEntry struct {
+	key       string
+	value     []byte
+	expiresAt time.Time
+}
+
+/// lruCache is an in-process ICache evicting the least recently used
+/// entry once full, and entries past their ttl on access
+type lruCache struct {
+	mu       sync.Mutex
+	capacity int
+	entries  map[string]*list.Element
+	order    *list.List
+	now      func() time.Time
+}
+
+/// NewLRUCache is a constructor for an in-process ICache holding at most
+/// capacity entries
+func NewLRUCache(capacity int) ICache {
+	return &lruCache{
+		capacity: capacity,
+		entries:  make(map[string]*list.Element),
+		order:    list.New(),
+		now:      time.Now,
+	}
+}
+
+func (lc *lruCache) Get(key string) ([]byte, bool, error) {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+
+	element, ok := lc.entries[key]
+	if !ok {
+		return nil, false, nil
+	}
+	entry := element.Value.(*lruEntry)
+	if lc.now().After(entry.expiresAt) {
+		lc.removeElement(element)
+		return nil, false, nil
+	}
+	lc.order.MoveToFront(element)
+	return entry.value, true, nil
+}
+
+func (lc *lruCache) Set(key string, value []byte, ttl time.Duration) error {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+
+	expiresAt := lc.now().Add(ttl)
+	if element, ok := lc.entries[key]; ok {
+		entry := element.Value.(*lruEntry)
+		entry.value = value
+		entry.expiresAt = expiresAt
+		lc.order.MoveToFront(element)
+		return nil
+	}
+	lc.entries[key] = lc.order.PushFront(&lruEntry{key: key, value: value, expiresAt: expiresAt})
+	for lc.order.Len() > lc.capacity {
+		lc.removeElement(lc.order.Back())
+	}
+	return nil
+}
+
+func (lc *lruCache) Delete(key string) error {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+
+	if element, ok := lc.entries[key]; ok {
+		lc.removeElement(element)
+	}
+	return nil
+}
+
+func (lc *lruCache) removeElement(element *list.Element) {
+	lc.order.Remove(element)
+	delete(lc.entries, element.Value.(*lruEntry).key)
+}
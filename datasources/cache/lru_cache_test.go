@@ -0,0 +1,88 @@
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+func newTestLRUCache(capacity int, now *time.Time) *lruCache {
+	cache := NewLRUCache(capacity).(*lruCache)
+	cache.now = func() time.Time { return *now }
+	return cache
+}
+
+func assertCached(t *testing.T, cache ICache, key string, want string) {
+	t.Helper()
+	value, found, err := cache.Get(key)
+	if err != nil {
+		t.Fatalf("get %s: %v", key, err)
+	}
+	if want == "" {
+		if found {
+			t.Errorf("get %s = %q, want a miss", key, value)
+		}
+		return
+	}
+	if !found || string(value) != want {
+		t.Errorf("get %s = %q, %v, want %q", key, value, found, want)
+	}
+}
+
+func TestLRUCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	now := time.Now()
+	cache := newTestLRUCache(2, &now)
+	cache.Set("a", []byte("1"), time.Minute)
+	cache.Set("b", []byte("2"), time.Minute)
+	/// Reading a makes b the least recently used
+	assertCached(t, cache, "a", "1")
+	cache.Set("c", []byte("3"), time.Minute)
+
+	assertCached(t, cache, "b", "")
+	assertCached(t, cache, "a", "1")
+	assertCached(t, cache, "c", "3")
+}
+
+func TestLRUCacheOverwriteDoesNotEvict(t *testing.T) {
+	now := time.Now()
+	cache := newTestLRUCache(2, &now)
+	cache.Set("a", []byte("1"), time.Minute)
+	cache.Set("b", []byte("2"), time.Minute)
+	cache.Set("a", []byte("3"), time.Minute)
+
+	assertCached(t, cache, "a", "3")
+	assertCached(t, cache, "b", "2")
+}
+
+func TestLRUCacheExpiresEntries(t *testing.T) {
+	now := time.Now()
+	cache := newTestLRUCache(2, &now)
+	cache.Set("a", []byte("1"), time.Minute)
+	cache.Set("b", []byte("2"), time.Hour)
+
+	now = now.Add(time.Minute)
+	assertCached(t, cache, "a", "1")
+	now = now.Add(time.Second)
+	assertCached(t, cache, "a", "")
+	assertCached(t, cache, "b", "2")
+	if cache.order.Len() != 1 {
+		t.Errorf("cache holds %d entries, want the expired one removed", cache.order.Len())
+	}
+
+	/// Setting again restarts the ttl
+	cache.Set("b", []byte("3"), time.Minute)
+	now = now.Add(2 * time.Minute)
+	assertCached(t, cache, "b", "")
+}
+
+func TestLRUCacheDelete(t *testing.T) {
+	now := time.Now()
+	cache := newTestLRUCache(2, &now)
+	cache.Set("a", []byte("1"), time.Minute)
+	if err := cache.Delete("a"); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if err := cache.Delete("missing"); err != nil {
+		t.Fatalf("delete missing key: %v", err)
+	}
+	assertCached(t, cache, "a", "")
+}
@@ -0,0 +1,57 @@
+package cache
+
+import "sync"
+
+type call struct {
+	wg    sync.WaitGroup
+	value interface{}
+	/// waiters counts the callers that joined the load
+	waiters int
+}
+
+/// SingleFlight collapses concurrent loads of the same key into one, so
+/// that an expired hot entry doesn't send every waiting request to the
+/// database at once
+type SingleFlight struct {
+	mu    sync.Mutex
+	calls map[string]*call
+}
+
+/// Do runs load once for all concurrent callers with the same key and
+/// hands each of them its result
+func (sf *SingleFlight) Do(key string, load func() interface{}) interface{} {
+	sf.mu.Lock()
+	if sf.calls == nil {
+		sf.calls = make(map[string]*call)
+	}
+	if c, ok := sf.calls[key]; ok {
+		c.waiters++
+		sf.mu.Unlock()
+		c.wg.Wait()
+		return c.value
+	}
+	c := &call{}
+	c.wg.Add(1)
+	sf.calls[key] = c
+	sf.mu.Unlock()
+
+	defer func() {
+		sf.mu.Lock()
+		delete(sf.calls, key)
+		sf.mu.Unlock()
+		c.wg.Done()
+	}()
+	c.value = load()
+	return c.value
+}
+
+/// Waiters reports how many callers joined the load of key in flight,
+/// 0 if there is none
+func (sf *SingleFlight) Waiters(key string) int {
+	sf.mu.Lock()
+	defer sf.mu.Unlock()
+	if c, ok := sf.calls[key]; ok {
+		return c.waiters
+	}
+	return 0
+}
@@ -0,0 +1,86 @@
+package cache
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestSingleFlightSharesConcurrentLoads(t *testing.T) {
+	var flight SingleFlight
+	var loads int32
+	started := make(chan struct{})
+	release := make(chan struct{})
+	load := func() interface{} {
+		if atomic.AddInt32(&loads, 1) == 1 {
+			close(started)
+		}
+		<-release
+		return "value"
+	}
+
+	const callers = 10
+	results := make([]interface{}, callers)
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		results[0] = flight.Do("key", load)
+	}()
+	<-started
+	for i := 1; i < callers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i] = flight.Do("key", load)
+		}(i)
+	}
+	for flight.Waiters("key") < callers-1 {
+		runtime.Gosched()
+	}
+	close(release)
+	wg.Wait()
+
+	if loads != 1 {
+		t.Errorf("%d loads for %d concurrent callers, want 1", loads, callers)
+	}
+	for i, result := range results {
+		if result != "value" {
+			t.Errorf("caller %d got %v, want value", i, result)
+		}
+	}
+}
+
+func TestSingleFlightLoadsAgainOnceDone(t *testing.T) {
+	var flight SingleFlight
+	loads := 0
+	for i := 0; i < 3; i++ {
+		flight.Do("key", func() interface{} {
+			loads++
+			return loads
+		})
+	}
+	if loads != 3 {
+		t.Errorf("%d loads for 3 sequential calls, want 3", loads)
+	}
+}
+
+func TestSingleFlightKeysAreIndependent(t *testing.T) {
+	var flight SingleFlight
+	release := make(chan struct{})
+	done := make(chan interface{})
+	go func() {
+		done <- flight.Do("a", func() interface{} {
+			<-release
+			return "a"
+		})
+	}()
+	if result := flight.Do("b", func() interface{} { return "b" }); result != "b" {
+		t.Errorf("load of b = %v while a is in flight, want b", result)
+	}
+	close(release)
+	if result := <-done; result != "a" {
+		t.Errorf("load of a = %v, want a", result)
+	}
+}
@@ -3,13 +3,27 @@ package mysql
 import (
 	"database/sql"
 	_ "github.com/go-sql-driver/mysql"
+	"time"
 )
 
-func Init(dataSource string) (*sql.DB, error) {
+/// PoolConfig sizes the connection pool, zero values keep the
+/// database/sql defaults
+type PoolConfig struct {
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+}
+
+func Init(dataSource string, pool PoolConfig) (*sql.DB, error) {
 	db, err := sql.Open("mysql", dataSource)
 	if err != nil {
 		return nil, err
 	}
+	db.SetMaxOpenConns(pool.MaxOpenConns)
+	if pool.MaxIdleConns > 0 {
+		db.SetMaxIdleConns(pool.MaxIdleConns)
+	}
+	db.SetConnMaxLifetime(pool.ConnMaxLifetime)
 	if err := db.Ping(); err != nil {
 		return nil, err
 	}
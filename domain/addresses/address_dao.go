@@ -0,0 +1,286 @@
+package addresses
+
+import (
+	"database/sql"
+	"fmt"
+	"github.com/Abacode7/bookstore_utils-go/v2/logger"
+	"github.com/Abacode7/bookstore_utils-go/v2/rest_error"
+	"strings"
+)
+
+const (
+	addressColumns            = `id, user_id, recipient, line1, line2, city, region, postal_code, country, default_shipping, default_billing, date_created`
+	insertAddressQuery        = `INSERT INTO addresses (user_id, recipient, line1, line2, city, region, postal_code, country, default_shipping, default_billing, date_created) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?);`
+	getAddressQuery           = `SELECT ` + addressColumns + ` FROM addresses WHERE id=? AND user_id=?;`
+	findByUserQuery           = `SELECT ` + addressColumns + ` FROM addresses WHERE user_id=? ORDER BY id;`
+	findDefaultsQuery         = `SELECT ` + addressColumns + ` FROM addresses WHERE user_id=? AND (default_shipping OR default_billing);`
+	findByUsersQuery          = `SELECT ` + addressColumns + ` FROM addresses WHERE user_id IN (%s) ORDER BY user_id, id;`
+	findDefaultsByUsersQuery  = `SELECT ` + addressColumns + ` FROM addresses WHERE user_id IN (%s) AND (default_shipping OR default_billing);`
+	updateAddressQuery        = `UPDATE addresses SET recipient=?, line1=?, line2=?, city=?, region=?, postal_code=?, country=?, default_shipping=?, default_billing=? WHERE id=? AND user_id=?;`
+	deleteAddressQuery        = `DELETE FROM addresses WHERE id=? AND user_id=?;`
+	clearDefaultShippingQuery = `UPDATE addresses SET default_shipping=FALSE WHERE user_id=? AND id<>?;`
+	clearDefaultBillingQuery  = `UPDATE addresses SET default_billing=FALSE WHERE user_id=? AND id<>?;`
+)
+
+type IAddressDao interface {
+	Save(Address) (*Address, rest_error.RestErr)
+	Get(int64, int64) (*Address, rest_error.RestErr)
+	FindByUser(int64) (Addresses, rest_error.RestErr)
+	FindDefaults(int64) (*DefaultAddresses, rest_error.RestErr)
+	FindByUsers([]int64) (map[int64]Addresses, rest_error.RestErr)
+	FindDefaultsByUsers([]int64) (map[int64]*DefaultAddresses, rest_error.RestErr)
+	Update(Address) (*Address, rest_error.RestErr)
+	Delete(int64, int64) rest_error.RestErr
+}
+
+type addressDao struct {
+	client *sql.DB
+}
+
+/// NewAddressDao is a constructor for addressDao
+func NewAddressDao(db *sql.DB) IAddressDao {
+	return &addressDao{db}
+}
+
+/// Save stores the address, taking the default flags off the user's
+/// other addresses if it is a default one
+func (ad *addressDao) Save(address Address) (*Address, rest_error.RestErr) {
+	tx, err := ad.client.Begin()
+	if err != nil {
+		logger.Error("error starting save address transaction", err)
+		return nil, rest_error.NewInternalServerError("database error")
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(insertAddressQuery, address.UserId, address.Recipient, address.Line1, address.Line2, address.City,
+		address.Region, address.PostalCode, address.Country, address.DefaultShipping, address.DefaultBilling, address.DateCreated)
+	if err != nil {
+		logger.Error("error executing insert address query", err)
+		return nil, rest_error.NewInternalServerError("database error")
+	}
+	address.Id, err = result.LastInsertId()
+	if err != nil {
+		logger.Error("error retrieving last insert id", err)
+		return nil, rest_error.NewInternalServerError("database error")
+	}
+	if err := clearOtherDefaults(tx, address); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		logger.Error("error committing save address transaction", err)
+		return nil, rest_error.NewInternalServerError("database error")
+	}
+	return &address, nil
+}
+
+/// Get gets the address with addressId of user with userId
+func (ad *addressDao) Get(userId int64, addressId int64) (*Address, rest_error.RestErr) {
+	stmt, err := ad.client.Prepare(getAddressQuery)
+	if err != nil {
+		logger.Error("error preparing get address query", err)
+		return nil, rest_error.NewInternalServerError("database error")
+	}
+	defer stmt.Close()
+
+	address, err := scanAddress(stmt.QueryRow(addressId, userId))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, rest_error.NewNotFoundError("invalid address id: address not found")
+		}
+		logger.Error("error scanning address", err)
+		return nil, rest_error.NewInternalServerError("database error")
+	}
+	return address, nil
+}
+
+/// FindByUser gets all addresses of user with userId
+func (ad *addressDao) FindByUser(userId int64) (Addresses, rest_error.RestErr) {
+	return ad.find(findByUserQuery, userId)
+}
+
+/// FindDefaults gets the default shipping and billing addresses of user with userId
+func (ad *addressDao) FindDefaults(userId int64) (*DefaultAddresses, rest_error.RestErr) {
+	addresses, err := ad.find(findDefaultsQuery, userId)
+	if err != nil {
+		return nil, err
+	}
+	var defaults DefaultAddresses
+	for i := range addresses {
+		defaults.add(&addresses[i])
+	}
+	return &defaults, nil
+}
+
+/// FindByUsers gets the addresses of the users with the given ids in a
+/// single query, keyed by user id. Users without addresses are left out.
+func (ad *addressDao) FindByUsers(userIds []int64) (map[int64]Addresses, rest_error.RestErr) {
+	addresses, err := ad.findMany(findByUsersQuery, userIds)
+	if err != nil {
+		return nil, err
+	}
+	byUser := make(map[int64]Addresses)
+	for _, address := range addresses {
+		byUser[address.UserId] = append(byUser[address.UserId], address)
+	}
+	return byUser, nil
+}
+
+/// FindDefaultsByUsers is FindDefaults for the users with the given ids,
+/// in a single query, keyed by user id
+func (ad *addressDao) FindDefaultsByUsers(userIds []int64) (map[int64]*DefaultAddresses, rest_error.RestErr) {
+	addresses, err := ad.findMany(findDefaultsByUsersQuery, userIds)
+	if err != nil {
+		return nil, err
+	}
+	byUser := make(map[int64]*DefaultAddresses, len(userIds))
+	for _, userId := range userIds {
+		byUser[userId] = &DefaultAddresses{}
+	}
+	for i := range addresses {
+		byUser[addresses[i].UserId].add(&addresses[i])
+	}
+	return byUser, nil
+}
+
+func (ad *addressDao) find(query string, userId int64) (Addresses, rest_error.RestErr) {
+	stmt, err := ad.client.Prepare(query)
+	if err != nil {
+		logger.Error("error preparing find addresses query", err)
+		return nil, rest_error.NewInternalServerError("database error")
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.Query(userId)
+	if err != nil {
+		logger.Error("error executing find addresses query", err)
+		return nil, rest_error.NewInternalServerError("database error")
+	}
+	defer rows.Close()
+
+	addresses := make(Addresses, 0)
+	for rows.Next() {
+		address, err := scanAddress(rows)
+		if err != nil {
+			logger.Error("error scanning address", err)
+			return nil, rest_error.NewInternalServerError("database error")
+		}
+		addresses = append(addresses, *address)
+	}
+	return addresses, nil
+}
+
+/// findMany runs query, with an IN list in place of its %s, for userIds
+func (ad *addressDao) findMany(query string, userIds []int64) (Addresses, rest_error.RestErr) {
+	addresses := make(Addresses, 0)
+	if len(userIds) == 0 {
+		return addresses, nil
+	}
+	args := make([]interface{}, len(userIds))
+	for i, userId := range userIds {
+		args[i] = userId
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(userIds)), ",")
+	rows, err := ad.client.Query(fmt.Sprintf(query, placeholders), args...)
+	if err != nil {
+		logger.Error("error executing find addresses by users query", err)
+		return nil, rest_error.NewInternalServerError("database error")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		address, err := scanAddress(rows)
+		if err != nil {
+			logger.Error("error scanning address", err)
+			return nil, rest_error.NewInternalServerError("database error")
+		}
+		addresses = append(addresses, *address)
+	}
+	if err := rows.Err(); err != nil {
+		logger.Error("error iterating addresses", err)
+		return nil, rest_error.NewInternalServerError("database error")
+	}
+	return addresses, nil
+}
+
+/// Update modifies the address, taking the default flags off the
+/// user's other addresses if it is a default one
+func (ad *addressDao) Update(address Address) (*Address, rest_error.RestErr) {
+	tx, err := ad.client.Begin()
+	if err != nil {
+		logger.Error("error starting update address transaction", err)
+		return nil, rest_error.NewInternalServerError("database error")
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(updateAddressQuery, address.Recipient, address.Line1, address.Line2, address.City, address.Region,
+		address.PostalCode, address.Country, address.DefaultShipping, address.DefaultBilling, address.Id, address.UserId)
+	if err != nil {
+		logger.Error("error executing update address query", err)
+		return nil, rest_error.NewInternalServerError("database error")
+	}
+	if err := clearOtherDefaults(tx, address); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		logger.Error("error committing update address transaction", err)
+		return nil, rest_error.NewInternalServerError("database error")
+	}
+	return &address, nil
+}
+
+/// Delete removes the address with addressId of user with userId
+func (ad *addressDao) Delete(userId int64, addressId int64) rest_error.RestErr {
+	stmt, err := ad.client.Prepare(deleteAddressQuery)
+	if err != nil {
+		logger.Error("error preparing delete address query", err)
+		return rest_error.NewInternalServerError("database error")
+	}
+	defer stmt.Close()
+
+	result, err := stmt.Exec(addressId, userId)
+	if err != nil {
+		logger.Error("error executing delete address query", err)
+		return rest_error.NewInternalServerError("database error")
+	}
+	rowsAff, err := result.RowsAffected()
+	if err != nil {
+		logger.Error("error retrieving rows affected", err)
+		return rest_error.NewInternalServerError("database error")
+	}
+	if rowsAff < 1 {
+		return rest_error.NewNotFoundError("invalid address id: address not found")
+	}
+	return nil
+}
+
+/// clearOtherDefaults makes address the user's only default shipping
+/// and/or billing address
+func clearOtherDefaults(tx *sql.Tx, address Address) rest_error.RestErr {
+	if address.DefaultShipping {
+		if _, err := tx.Exec(clearDefaultShippingQuery, address.UserId, address.Id); err != nil {
+			logger.Error("error clearing default shipping address", err)
+			return rest_error.NewInternalServerError("database error")
+		}
+	}
+	if address.DefaultBilling {
+		if _, err := tx.Exec(clearDefaultBillingQuery, address.UserId, address.Id); err != nil {
+			logger.Error("error clearing default billing address", err)
+			return rest_error.NewInternalServerError("database error")
+		}
+	}
+	return nil
+}
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanAddress(row scanner) (*Address, error) {
+	var address Address
+	err := row.Scan(&address.Id, &address.UserId, &address.Recipient, &address.Line1, &address.Line2, &address.City,
+		&address.Region, &address.PostalCode, &address.Country, &address.DefaultShipping, &address.DefaultBilling, &address.DateCreated)
+	if err != nil {
+		return nil, err
+	}
+	return &address, nil
+}
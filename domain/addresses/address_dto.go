@@ -0,0 +1,106 @@
+package addresses
+
+import (
+	"github.com/Abacode7/bookstore_users-api/utils/error_utils"
+	"github.com/Abacode7/bookstore_utils-go/v2/rest_error"
+	"regexp"
+	"strings"
+)
+
+var (
+	/// postalCodeFormats are the postal code formats of the countries we
+	/// ship to most, keyed by ISO 3166-1 alpha-2 code
+	postalCodeFormats = map[string]*regexp.Regexp{
+		"AU": regexp.MustCompile(`^[0-9]{4}$`),
+		"BR": regexp.MustCompile(`^[0-9]{5}-?[0-9]{3}$`),
+		"CA": regexp.MustCompile(`^[A-Z][0-9][A-Z] ?[0-9][A-Z][0-9]$`),
+		"DE": regexp.MustCompile(`^[0-9]{5}$`),
+		"ES": regexp.MustCompile(`^[0-9]{5}$`),
+		"FR": regexp.MustCompile(`^[0-9]{5}$`),
+		"GB": regexp.MustCompile(`^[A-Z]{1,2}[0-9][A-Z0-9]? ?[0-9][A-Z]{2}$`),
+		"IN": regexp.MustCompile(`^[1-9][0-9]{5}$`),
+		"IT": regexp.MustCompile(`^[0-9]{5}$`),
+		"JP": regexp.MustCompile(`^[0-9]{3}-?[0-9]{4}$`),
+		"NG": regexp.MustCompile(`^[0-9]{6}$`),
+		"NL": regexp.MustCompile(`^[1-9][0-9]{3} ?[A-Z]{2}$`),
+		"US": regexp.MustCompile(`^[0-9]{5}(-[0-9]{4})?$`),
+	}
+	/// countriesWithoutPostalCodes don't use postal codes at all
+	countriesWithoutPostalCodes = map[string]bool{"AE": true, "HK": true, "QA": true}
+	/// genericPostalCode is checked for countries without a known format
+	genericPostalCode = regexp.MustCompile(`^[A-Z0-9][A-Z0-9 -]{1,9}$`)
+	countryCode       = regexp.MustCompile(`^[A-Z]{2}$`)
+)
+
+type Addresses []Address
+
+type Address struct {
+	Id              int64  `json:"id"`
+	UserId          int64  `json:"user_id"`
+	Recipient       string `json:"recipient"`
+	Line1           string `json:"line1"`
+	Line2           string `json:"line2"`
+	City            string `json:"city"`
+	Region          string `json:"region"`
+	PostalCode      string `json:"postal_code"`
+	Country         string `json:"country"`
+	DefaultShipping bool   `json:"default_shipping"`
+	DefaultBilling  bool   `json:"default_billing"`
+	DateCreated     string `json:"date_created"`
+}
+
+/// DefaultAddresses are a user's default shipping and billing addresses
+type DefaultAddresses struct {
+	Shipping *Address `json:"shipping,omitempty"`
+	Billing  *Address `json:"billing,omitempty"`
+}
+
+/// add makes address the shipping and billing default it's flagged as
+func (defaults *DefaultAddresses) add(address *Address) {
+	if address.DefaultShipping {
+		defaults.Shipping = address
+	}
+	if address.DefaultBilling {
+		defaults.Billing = address
+	}
+}
+
+/// Validate normalizes the address and checks it, including the postal
+/// code against the format of its country
+func (address *Address) Validate() rest_error.RestErr {
+	address.Recipient = strings.TrimSpace(address.Recipient)
+	address.Line1 = strings.TrimSpace(address.Line1)
+	address.Line2 = strings.TrimSpace(address.Line2)
+	address.City = strings.TrimSpace(address.City)
+	address.Region = strings.TrimSpace(address.Region)
+	address.Country = strings.ToUpper(strings.TrimSpace(address.Country))
+	address.PostalCode = strings.ToUpper(strings.TrimSpace(address.PostalCode))
+
+	if address.Line1 == "" {
+		return error_utils.NewFieldError("line1", "invalid address line")
+	}
+	if address.City == "" {
+		return error_utils.NewFieldError("city", "invalid city")
+	}
+	if !countryCode.MatchString(address.Country) {
+		return error_utils.NewFieldError("country", "invalid country: must be an ISO 3166-1 alpha-2 code")
+	}
+	return address.validatePostalCode()
+}
+
+func (address *Address) validatePostalCode() rest_error.RestErr {
+	if countriesWithoutPostalCodes[address.Country] {
+		if address.PostalCode != "" {
+			return error_utils.NewFieldError("postal_code", "invalid postal code: country has no postal codes")
+		}
+		return nil
+	}
+	format, ok := postalCodeFormats[address.Country]
+	if !ok {
+		format = genericPostalCode
+	}
+	if !format.MatchString(address.PostalCode) {
+		return error_utils.NewFieldError("postal_code", "invalid postal code for country "+address.Country)
+	}
+	return nil
+}
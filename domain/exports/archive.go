@@ -0,0 +1,100 @@
+package exports
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"github.com/Abacode7/bookstore_users-api/domain/addresses"
+	"github.com/Abacode7/bookstore_users-api/domain/organizations"
+	"github.com/Abacode7/bookstore_users-api/domain/users"
+	"github.com/Abacode7/bookstore_utils-go/v2/logger"
+	"github.com/Abacode7/bookstore_utils-go/v2/rest_error"
+)
+
+/// Archive is the personal data held about a user. A json archive is a
+/// single document, a zip archive has a json file per section.
+type Archive struct {
+	GeneratedAt            string                           `json:"generated_at"`
+	User                   interface{}                      `json:"user"`
+	Profile                *users.Profile                   `json:"profile"`
+	ProfileAttributes      map[int64]map[string]interface{} `json:"profile_attributes"`
+	Addresses              addresses.Addresses              `json:"addresses"`
+	Organizations          organizations.Memberships        `json:"organizations"`
+	EmailChanges           []EmailChangeRecord              `json:"email_changes"`
+	SignIns                []SignIn                         `json:"sign_ins"`
+	Activity               []ActivityEntry                  `json:"activity"`
+	StatusHistory          []users.StatusChange             `json:"status_history"`
+	ScheduledStatusChanges []users.ScheduledStatusChange    `json:"scheduled_status_changes"`
+}
+
+/// EmailChangeRecord is an email change without its tokens
+type EmailChangeRecord struct {
+	OldEmail    string `json:"old_email"`
+	NewEmail    string `json:"new_email"`
+	Status      string `json:"status"`
+	DateCreated string `json:"date_created"`
+}
+
+/// SignIn is a successful login of the user
+type SignIn struct {
+	DateCreated string `json:"date_created"`
+}
+
+/// ActivityEntry is a change made to the user, as recorded in the outbox
+type ActivityEntry struct {
+	Type        string          `json:"type"`
+	DateCreated string          `json:"date_created"`
+	Payload     json.RawMessage `json:"payload"`
+}
+
+/// Encode writes the archive in format
+func (archive *Archive) Encode(format string) ([]byte, rest_error.RestErr) {
+	if format != FormatZip {
+		return encodeSection(archive)
+	}
+
+	var buffer bytes.Buffer
+	writer := zip.NewWriter(&buffer)
+	sections := []struct {
+		name    string
+		content interface{}
+	}{
+		{"user", archive.User},
+		{"profile", map[string]interface{}{"profile": archive.Profile, "attributes": archive.ProfileAttributes}},
+		{"addresses", archive.Addresses},
+		{"organizations", archive.Organizations},
+		{"email_changes", archive.EmailChanges},
+		{"sign_ins", archive.SignIns},
+		{"activity", archive.Activity},
+		{"status_history", archive.StatusHistory},
+		{"scheduled_status_changes", archive.ScheduledStatusChanges},
+	}
+	for _, section := range sections {
+		content, err := encodeSection(section.content)
+		if err != nil {
+			return nil, err
+		}
+		file, zipErr := writer.Create(section.name + ".json")
+		if zipErr == nil {
+			_, zipErr = file.Write(content)
+		}
+		if zipErr != nil {
+			logger.Error("error writing export archive", zipErr)
+			return nil, rest_error.NewInternalServerError("error writing export archive")
+		}
+	}
+	if err := writer.Close(); err != nil {
+		logger.Error("error closing export archive", err)
+		return nil, rest_error.NewInternalServerError("error writing export archive")
+	}
+	return buffer.Bytes(), nil
+}
+
+func encodeSection(content interface{}) ([]byte, rest_error.RestErr) {
+	encoded, err := json.MarshalIndent(content, "", "  ")
+	if err != nil {
+		logger.Error("error encoding export archive", err)
+		return nil, rest_error.NewInternalServerError("error encoding export archive")
+	}
+	return encoded, nil
+}
@@ -0,0 +1,232 @@
+package exports
+
+import (
+	"database/sql"
+	"fmt"
+	"github.com/Abacode7/bookstore_users-api/utils/date_utils"
+	"github.com/Abacode7/bookstore_users-api/utils/error_utils"
+	"github.com/Abacode7/bookstore_utils-go/v2/logger"
+	"github.com/Abacode7/bookstore_utils-go/v2/rest_error"
+	"github.com/go-sql-driver/mysql"
+	"time"
+)
+
+const (
+	exportColumns        = `id, user_id, requested_by, format, status, size, date_created, date_completed, expires_at, last_error, attempts`
+	insertExportQuery    = `INSERT INTO users_exports (user_id, requested_by, format, status, size, date_created) VALUES (?, ?, ?, ?, 0, ?);`
+	getExportQuery       = `SELECT ` + exportColumns + ` FROM users_exports WHERE id=? AND user_id=?;`
+	getArchiveQuery      = `SELECT archive FROM users_exports WHERE id=? AND user_id=? AND status=?;`
+	claimPendingQuery    = `SELECT ` + exportColumns + ` FROM users_exports WHERE status=? AND (next_attempt_at IS NULL OR next_attempt_at <= ?) ORDER BY id LIMIT ? FOR UPDATE SKIP LOCKED;`
+	markCompletedQuery   = `UPDATE users_exports SET status=?, archive=?, size=?, date_completed=?, expires_at=? WHERE id=?;`
+	markBuildErrorQuery  = `UPDATE users_exports SET status=?, attempts=?, next_attempt_at=?, date_completed=?, last_error=? WHERE id=?;`
+	expireCompletedQuery = `UPDATE users_exports SET status=?, archive=NULL WHERE status=? AND expires_at <= ?;`
+	maxLastErrorLength   = 255
+	mysqlDuplicateEntry  = 1062
+)
+
+type IExportDao interface {
+	Save(Export) (*Export, rest_error.RestErr)
+	Get(userId int64, exportId int64) (*Export, rest_error.RestErr)
+	GetArchive(userId int64, exportId int64) ([]byte, rest_error.RestErr)
+	ProcessPending(limit int, build func(Export) ([]byte, rest_error.RestErr), ttl time.Duration) (int, rest_error.RestErr)
+	ExpireCompleted() rest_error.RestErr
+}
+
+type exportDao struct {
+	client *sql.DB
+}
+
+/// NewExportDao is a constructor for exportDao
+func NewExportDao(db *sql.DB) IExportDao {
+	return &exportDao{db}
+}
+
+/// Save stores a pending export. The pending_user_id unique key allows a
+/// single pending export per user, a second one is a conflict.
+func (ed *exportDao) Save(export Export) (*Export, rest_error.RestErr) {
+	export.Status = StatusPending
+	export.DateCreated = date_utils.GetDbFormattedTime()
+	result, err := ed.client.Exec(insertExportQuery, export.UserId, export.RequestedBy, export.Format, export.Status,
+		export.DateCreated)
+	if isDuplicateEntry(err) {
+		return nil, error_utils.NewConflictError("an export of this user is already in progress")
+	}
+	if err != nil {
+		logger.Error("error executing insert export query", err)
+		return nil, rest_error.NewInternalServerError("database error")
+	}
+	if export.Id, err = result.LastInsertId(); err != nil {
+		logger.Error("error retrieving last insert id", err)
+		return nil, rest_error.NewInternalServerError("database error")
+	}
+	return &export, nil
+}
+
+/// Get gets the export with exportId of the user with userId
+func (ed *exportDao) Get(userId int64, exportId int64) (*Export, rest_error.RestErr) {
+	export, err := scanExport(ed.client.QueryRow(getExportQuery, exportId, userId))
+	if err == sql.ErrNoRows {
+		return nil, rest_error.NewNotFoundError("export not found")
+	}
+	if err != nil {
+		logger.Error("error scanning export", err)
+		return nil, rest_error.NewInternalServerError("database error")
+	}
+	return export, nil
+}
+
+/// GetArchive gets the archive of a completed export
+func (ed *exportDao) GetArchive(userId int64, exportId int64) ([]byte, rest_error.RestErr) {
+	var archive []byte
+	err := ed.client.QueryRow(getArchiveQuery, exportId, userId, StatusCompleted).Scan(&archive)
+	if err == sql.ErrNoRows {
+		return nil, rest_error.NewNotFoundError("export archive not found")
+	}
+	if err != nil {
+		logger.Error("error scanning export archive", err)
+		return nil, rest_error.NewInternalServerError("database error")
+	}
+	return archive, nil
+}
+
+/// ProcessPending locks up to limit pending exports and builds their
+/// archives in order. Built archives are kept for ttl. Exports that
+/// failed to build are marked failed and have to be requested again,
+/// unless it was a server error: those stay pending and are retried with
+/// backoff, up to exportMaxAttempts builds. Locked rows are skipped, so
+/// workers on several instances don't build the same export. It returns
+/// how many exports were marked completed or failed, the ones left
+/// pending aren't counted.
+func (ed *exportDao) ProcessPending(limit int, build func(Export) ([]byte, rest_error.RestErr), ttl time.Duration) (int, rest_error.RestErr) {
+	tx, err := ed.client.Begin()
+	if err != nil {
+		logger.Error("error starting process exports transaction", err)
+		return 0, rest_error.NewInternalServerError("database error")
+	}
+	defer tx.Rollback()
+
+	exports, restErr := claimPending(tx, limit)
+	if restErr != nil {
+		return 0, restErr
+	}
+	marked := 0
+	for _, export := range exports {
+		now := date_utils.GetTime()
+		archive, buildErr := build(export)
+		if buildErr == nil {
+			buildErr = markCompleted(tx, export, archive, now, ttl)
+		}
+		if buildErr != nil {
+			logger.Error(fmt.Sprintf("error building export %d", export.Id), buildErr)
+			export.RecordBuildError(buildErr, now)
+			if err := markBuildError(tx, export); err != nil {
+				return 0, err
+			}
+			if export.Status == StatusFailed {
+				marked++
+			}
+			continue
+		}
+		marked++
+	}
+	if err := tx.Commit(); err != nil {
+		logger.Error("error committing process exports transaction", err)
+		return 0, rest_error.NewInternalServerError("database error")
+	}
+	return marked, nil
+}
+
+/// ExpireCompleted removes the archives of exports past their expiry
+func (ed *exportDao) ExpireCompleted() rest_error.RestErr {
+	if _, err := ed.client.Exec(expireCompletedQuery, StatusExpired, StatusCompleted, date_utils.GetDbFormattedTime()); err != nil {
+		logger.Error("error executing expire exports query", err)
+		return rest_error.NewInternalServerError("database error")
+	}
+	return nil
+}
+
+func claimPending(tx *sql.Tx, limit int) ([]Export, rest_error.RestErr) {
+	rows, err := tx.Query(claimPendingQuery, StatusPending, date_utils.GetDbFormattedTime(), limit)
+	if err != nil {
+		logger.Error("error executing claim exports query", err)
+		return nil, rest_error.NewInternalServerError("database error")
+	}
+	defer rows.Close()
+
+	exports := make([]Export, 0)
+	for rows.Next() {
+		export, err := scanExport(rows)
+		if err != nil {
+			logger.Error("error scanning export", err)
+			return nil, rest_error.NewInternalServerError("database error")
+		}
+		exports = append(exports, *export)
+	}
+	if err := rows.Err(); err != nil {
+		logger.Error("error iterating exports", err)
+		return nil, rest_error.NewInternalServerError("database error")
+	}
+	return exports, nil
+}
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanExport(row scanner) (*Export, error) {
+	var export Export
+	var dateCompleted, expiresAt, lastError sql.NullString
+	err := row.Scan(&export.Id, &export.UserId, &export.RequestedBy, &export.Format, &export.Status, &export.Size,
+		&export.DateCreated, &dateCompleted, &expiresAt, &lastError, &export.Attempts)
+	if err != nil {
+		return nil, err
+	}
+	export.DateCompleted = dateCompleted.String
+	export.ExpiresAt = expiresAt.String
+	export.LastError = lastError.String
+	return &export, nil
+}
+
+/// markCompleted stores the archive of export, built at now and kept for
+/// ttl. Failing to store it is a build error like any other, so an
+/// archive the database won't take doesn't hold up the exports after it.
+func markCompleted(tx *sql.Tx, export Export, archive []byte, now time.Time, ttl time.Duration) rest_error.RestErr {
+	_, err := tx.Exec(markCompletedQuery, StatusCompleted, archive, len(archive), date_utils.FormatDbTime(now),
+		date_utils.FormatDbTime(now.Add(ttl)), export.Id)
+	if err != nil {
+		logger.Error("error executing mark export completed query", err)
+		return rest_error.NewInternalServerError("database error")
+	}
+	return nil
+}
+
+/// markBuildError stores the outcome RecordBuildError gave export
+func markBuildError(tx *sql.Tx, export Export) rest_error.RestErr {
+	var nextAttemptAt, dateCompleted sql.NullString
+	if export.NextAttemptAt != "" {
+		nextAttemptAt = sql.NullString{String: export.NextAttemptAt, Valid: true}
+	}
+	if export.DateCompleted != "" {
+		dateCompleted = sql.NullString{String: export.DateCompleted, Valid: true}
+	}
+	_, err := tx.Exec(markBuildErrorQuery, export.Status, export.Attempts, nextAttemptAt, dateCompleted,
+		truncate(export.LastError, maxLastErrorLength), export.Id)
+	if err != nil {
+		logger.Error("error executing mark export build error query", err)
+		return rest_error.NewInternalServerError("database error")
+	}
+	return nil
+}
+
+/// isDuplicateEntry reports whether err is a unique index violation
+func isDuplicateEntry(err error) bool {
+	mysqlErr, ok := err.(*mysql.MySQLError)
+	return ok && mysqlErr.Number == mysqlDuplicateEntry
+}
+
+func truncate(value string, length int) string {
+	if len(value) <= length {
+		return value
+	}
+	return value[:length]
+}
@@ -0,0 +1,96 @@
+package exports
+
+import (
+	"github.com/Abacode7/bookstore_users-api/utils/date_utils"
+	"github.com/Abacode7/bookstore_users-api/utils/error_utils"
+	"github.com/Abacode7/bookstore_utils-go/v2/rest_error"
+	"net/http"
+	"strings"
+	"time"
+)
+
+/// Export job statuses
+const (
+	StatusPending   = "pending"
+	StatusCompleted = "completed"
+	StatusFailed    = "failed"
+	/// StatusExpired exports had their archive removed
+	StatusExpired = "expired"
+)
+
+const (
+	/// exportMaxAttempts is how many builds failing with a server error
+	/// an export gets before it is marked failed
+	exportMaxAttempts = 5
+	/// exportRetryDelay is the wait before retrying a build that failed
+	/// with a server error, doubled after every attempt
+	exportRetryDelay = time.Minute
+)
+
+/// Archive formats
+const (
+	FormatJSON = "json"
+	FormatZip  = "zip"
+)
+
+/// Export is a job assembling the personal data held about a user into
+/// an archive, downloadable until ExpiresAt once completed
+type Export struct {
+	Id            int64  `json:"id"`
+	UserId        int64  `json:"user_id"`
+	RequestedBy   int64  `json:"requested_by"`
+	Format        string `json:"format"`
+	Status        string `json:"status"`
+	Size          int    `json:"size,omitempty"`
+	DateCreated   string `json:"date_created"`
+	DateCompleted string `json:"date_completed,omitempty"`
+	ExpiresAt     string `json:"expires_at,omitempty"`
+	LastError     string `json:"last_error,omitempty"`
+	/// Attempts counts the builds that failed, NextAttemptAt is when a
+	/// pending export is built again after a server error
+	Attempts      int    `json:"-"`
+	NextAttemptAt string `json:"-"`
+	/// DownloadUrl is set by the api on completed exports
+	DownloadUrl string `json:"download_url,omitempty"`
+}
+
+/// ExportRequest asks for an export of a user in Format, json by default
+type ExportRequest struct {
+	Format string `json:"format"`
+}
+
+func (request *ExportRequest) Validate() rest_error.RestErr {
+	request.Format = strings.ToLower(strings.TrimSpace(request.Format))
+	if request.Format == "" {
+		request.Format = FormatJSON
+	}
+	if request.Format != FormatJSON && request.Format != FormatZip {
+		return error_utils.NewFieldError("format", "format must be json or zip")
+	}
+	return nil
+}
+
+/// ContentType is the media type of the export's archive
+func (export *Export) ContentType() string {
+	if export.Format == FormatZip {
+		return "application/zip"
+	}
+	return "application/json"
+}
+
+/// RecordBuildError records that building the export failed at now with
+/// buildErr. A server error, such as the database being unavailable, is
+/// retried with backoff until exportMaxAttempts builds have failed. Any
+/// other error fails the export.
+func (export *Export) RecordBuildError(buildErr rest_error.RestErr, now time.Time) {
+	export.Attempts++
+	export.LastError = buildErr.Message()
+	if buildErr.Status() >= http.StatusInternalServerError && export.Attempts < exportMaxAttempts {
+		export.Status = StatusPending
+		export.NextAttemptAt = date_utils.FormatDbTime(now.Add(exportRetryDelay << uint(export.Attempts-1)))
+		return
+	}
+	export.Status = StatusFailed
+	export.NextAttemptAt = ""
+	export.DateCompleted = date_utils.FormatDbTime(now)
+}
@@ -0,0 +1,52 @@
+package exports
+
+import (
+	"github.com/Abacode7/bookstore_users-api/utils/date_utils"
+	"github.com/Abacode7/bookstore_utils-go/v2/rest_error"
+	"github.com/go-sql-driver/mysql"
+	"testing"
+	"time"
+)
+
+func TestRecordBuildErrorRetriesServerErrors(t *testing.T) {
+	now := time.Date(2026, time.October, 19, 12, 0, 0, 0, time.UTC)
+	export := Export{Status: StatusPending}
+	wantDelays := []time.Duration{time.Minute, 2 * time.Minute, 4 * time.Minute, 8 * time.Minute}
+	for i, delay := range wantDelays {
+		export.RecordBuildError(rest_error.NewInternalServerError("database error"), now)
+		if export.Status != StatusPending || export.Attempts != i+1 {
+			t.Fatalf("attempt %d: got %s after %d attempts, want pending", i+1, export.Status, export.Attempts)
+		}
+		if want := date_utils.FormatDbTime(now.Add(delay)); export.NextAttemptAt != want {
+			t.Errorf("attempt %d: got next attempt at %s, want %s", i+1, export.NextAttemptAt, want)
+		}
+		if export.LastError != "database error" || export.DateCompleted != "" {
+			t.Errorf("attempt %d: got last error %q and completion %q", i+1, export.LastError, export.DateCompleted)
+		}
+	}
+
+	export.RecordBuildError(rest_error.NewInternalServerError("database error"), now)
+	if export.Status != StatusFailed || export.Attempts != exportMaxAttempts || export.NextAttemptAt != "" {
+		t.Errorf("got %+v, want failed after %d attempts", export, exportMaxAttempts)
+	}
+	if export.DateCompleted != date_utils.FormatDbTime(now) {
+		t.Errorf("got completion %q, want %q", export.DateCompleted, date_utils.FormatDbTime(now))
+	}
+}
+
+func TestRecordBuildErrorFailsOtherErrors(t *testing.T) {
+	export := Export{Status: StatusPending}
+	export.RecordBuildError(rest_error.NewNotFoundError("user not found"), time.Now())
+	if export.Status != StatusFailed || export.Attempts != 1 || export.LastError != "user not found" {
+		t.Errorf("got %+v, want failed on the first attempt", export)
+	}
+}
+
+func TestIsDuplicateEntry(t *testing.T) {
+	if !isDuplicateEntry(&mysql.MySQLError{Number: mysqlDuplicateEntry, Message: "Duplicate entry '7' for key 'pending_user_id_UNIQUE'"}) {
+		t.Error("a duplicate entry error wasn't recognised")
+	}
+	if isDuplicateEntry(&mysql.MySQLError{Number: 1452}) || isDuplicateEntry(nil) {
+		t.Error("another error was taken for a duplicate entry")
+	}
+}
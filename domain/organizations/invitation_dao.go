@@ -0,0 +1,89 @@
+package organizations
+
+import (
+	"database/sql"
+	"github.com/Abacode7/bookstore_utils-go/v2/logger"
+	"github.com/Abacode7/bookstore_utils-go/v2/rest_error"
+)
+
+const (
+	insertInvitationQuery       = `INSERT INTO organization_invitations (organization_id, email, role, invited_by, token_hash, status, date_created, expires_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?);`
+	findInvitationByTokenQuery  = `SELECT id, organization_id, email, role, invited_by, token_hash, status, date_created, expires_at FROM organization_invitations WHERE token_hash=?;`
+	updateInvitationStatusQuery = `UPDATE organization_invitations SET status=? WHERE id=?;`
+)
+
+type IInvitationDao interface {
+	Save(Invitation) (*Invitation, rest_error.RestErr)
+	FindByToken(string) (*Invitation, rest_error.RestErr)
+	UpdateStatus(int64, string) rest_error.RestErr
+}
+
+type invitationDao struct {
+	client *sql.DB
+}
+
+/// NewInvitationDao is a constructor for invitationDao
+func NewInvitationDao(db *sql.DB) IInvitationDao {
+	return &invitationDao{db}
+}
+
+/// Save stores an organization invitation
+func (id *invitationDao) Save(invitation Invitation) (*Invitation, rest_error.RestErr) {
+	stmt, err := id.client.Prepare(insertInvitationQuery)
+	if err != nil {
+		logger.Error("error preparing insert invitation query", err)
+		return nil, rest_error.NewInternalServerError("database error")
+	}
+	defer stmt.Close()
+
+	result, err := stmt.Exec(invitation.OrganizationId, invitation.Email, invitation.Role, invitation.InvitedBy,
+		invitation.TokenHash, invitation.Status, invitation.DateCreated, invitation.ExpiresAt)
+	if err != nil {
+		logger.Error("error executing insert invitation query", err)
+		return nil, rest_error.NewInternalServerError("database error")
+	}
+	invitation.Id, err = result.LastInsertId()
+	if err != nil {
+		logger.Error("error retrieving last insert id", err)
+		return nil, rest_error.NewInternalServerError("database error")
+	}
+	return &invitation, nil
+}
+
+/// FindByToken gets the invitation with the given token hash
+func (id *invitationDao) FindByToken(tokenHash string) (*Invitation, rest_error.RestErr) {
+	stmt, err := id.client.Prepare(findInvitationByTokenQuery)
+	if err != nil {
+		logger.Error("error preparing find invitation query", err)
+		return nil, rest_error.NewInternalServerError("database error")
+	}
+	defer stmt.Close()
+
+	var invitation Invitation
+	err = stmt.QueryRow(tokenHash).Scan(&invitation.Id, &invitation.OrganizationId, &invitation.Email, &invitation.Role,
+		&invitation.InvitedBy, &invitation.TokenHash, &invitation.Status, &invitation.DateCreated, &invitation.ExpiresAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, rest_error.NewNotFoundError("invalid token: invitation not found")
+		}
+		logger.Error("error scanning invitation", err)
+		return nil, rest_error.NewInternalServerError("database error")
+	}
+	return &invitation, nil
+}
+
+/// UpdateStatus sets the status of the invitation with invitationId
+func (id *invitationDao) UpdateStatus(invitationId int64, status string) rest_error.RestErr {
+	stmt, err := id.client.Prepare(updateInvitationStatusQuery)
+	if err != nil {
+		logger.Error("error preparing update invitation query", err)
+		return rest_error.NewInternalServerError("database error")
+	}
+	defer stmt.Close()
+
+	if _, err := stmt.Exec(status, invitationId); err != nil {
+		logger.Error("error executing update invitation query", err)
+		return rest_error.NewInternalServerError("database error")
+	}
+	return nil
+}
@@ -0,0 +1,263 @@
+package organizations
+
+import (
+	"database/sql"
+	"fmt"
+	"github.com/Abacode7/bookstore_utils-go/v2/logger"
+	"github.com/Abacode7/bookstore_utils-go/v2/rest_error"
+	"strings"
+)
+
+const (
+	insertOrganizationQuery = `INSERT INTO organizations (name, date_created) VALUES (?, ?);`
+	getOrganizationQuery    = `SELECT id, name, date_created FROM organizations WHERE id=?;`
+	findByUserQuery         = `SELECT o.id, o.name, o.date_created, m.role FROM organizations o JOIN organization_members m ON m.organization_id=o.id WHERE m.user_id=? ORDER BY o.id;`
+	findByUsersQuery        = `SELECT m.user_id, o.id, o.name, o.date_created, m.role FROM organizations o JOIN organization_members m ON m.organization_id=o.id WHERE m.user_id IN (%s) ORDER BY m.user_id, o.id;`
+	getMemberQuery          = `SELECT organization_id, user_id, role, date_created FROM organization_members WHERE organization_id=? AND user_id=?;`
+	findMembersQuery        = `SELECT organization_id, user_id, role, date_created FROM organization_members WHERE organization_id=? ORDER BY date_created, user_id;`
+	saveMemberQuery         = `INSERT INTO organization_members (organization_id, user_id, role, date_created) VALUES (?, ?, ?, ?) ON DUPLICATE KEY UPDATE role=VALUES(role);`
+	deleteMemberQuery       = `DELETE FROM organization_members WHERE organization_id=? AND user_id=?;`
+	countOwnersQuery        = `SELECT COUNT(*) FROM organization_members WHERE organization_id=? AND role=?;`
+)
+
+type IOrganizationDao interface {
+	Save(Organization, Member) (*Organization, rest_error.RestErr)
+	Get(int64) (*Organization, rest_error.RestErr)
+	FindByUser(int64) (Memberships, rest_error.RestErr)
+	FindByUsers([]int64) (map[int64]Memberships, rest_error.RestErr)
+	GetMember(int64, int64) (*Member, rest_error.RestErr)
+	FindMembers(int64) (Members, rest_error.RestErr)
+	SaveMember(Member) rest_error.RestErr
+	DeleteMember(int64, int64) rest_error.RestErr
+	CountOwners(int64) (int, rest_error.RestErr)
+}
+
+type organizationDao struct {
+	client *sql.DB
+}
+
+/// NewOrganizationDao is a constructor for organizationDao
+func NewOrganizationDao(db *sql.DB) IOrganizationDao {
+	return &organizationDao{db}
+}
+
+/// Save stores the organization together with its first member
+func (od *organizationDao) Save(org Organization, owner Member) (*Organization, rest_error.RestErr) {
+	tx, err := od.client.Begin()
+	if err != nil {
+		logger.Error("error starting save organization transaction", err)
+		return nil, rest_error.NewInternalServerError("database error")
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(insertOrganizationQuery, org.Name, org.DateCreated)
+	if err != nil {
+		logger.Error("error executing insert organization query", err)
+		return nil, rest_error.NewInternalServerError("database error")
+	}
+	org.Id, err = result.LastInsertId()
+	if err != nil {
+		logger.Error("error retrieving last insert id", err)
+		return nil, rest_error.NewInternalServerError("database error")
+	}
+	if _, err := tx.Exec(saveMemberQuery, org.Id, owner.UserId, owner.Role, owner.DateCreated); err != nil {
+		logger.Error("error executing save member query", err)
+		return nil, rest_error.NewInternalServerError("database error")
+	}
+	if err := tx.Commit(); err != nil {
+		logger.Error("error committing save organization transaction", err)
+		return nil, rest_error.NewInternalServerError("database error")
+	}
+	return &org, nil
+}
+
+/// Get gets the organization with orgId
+func (od *organizationDao) Get(orgId int64) (*Organization, rest_error.RestErr) {
+	stmt, err := od.client.Prepare(getOrganizationQuery)
+	if err != nil {
+		logger.Error("error preparing get organization query", err)
+		return nil, rest_error.NewInternalServerError("database error")
+	}
+	defer stmt.Close()
+
+	var org Organization
+	if err := stmt.QueryRow(orgId).Scan(&org.Id, &org.Name, &org.DateCreated); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, rest_error.NewNotFoundError("invalid organization id: organization not found")
+		}
+		logger.Error("error scanning organization", err)
+		return nil, rest_error.NewInternalServerError("database error")
+	}
+	return &org, nil
+}
+
+/// FindByUser gets the organizations user with userId is a member of
+func (od *organizationDao) FindByUser(userId int64) (Memberships, rest_error.RestErr) {
+	stmt, err := od.client.Prepare(findByUserQuery)
+	if err != nil {
+		logger.Error("error preparing find organizations by user query", err)
+		return nil, rest_error.NewInternalServerError("database error")
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.Query(userId)
+	if err != nil {
+		logger.Error("error executing find organizations by user query", err)
+		return nil, rest_error.NewInternalServerError("database error")
+	}
+	defer rows.Close()
+
+	memberships := make(Memberships, 0)
+	for rows.Next() {
+		var membership Membership
+		if err := rows.Scan(&membership.Id, &membership.Name, &membership.DateCreated, &membership.Role); err != nil {
+			logger.Error("error scanning membership", err)
+			return nil, rest_error.NewInternalServerError("database error")
+		}
+		memberships = append(memberships, membership)
+	}
+	return memberships, nil
+}
+
+/// FindByUsers gets the memberships of the users with the given ids in a
+/// single query, keyed by user id. Users without any are left out.
+func (od *organizationDao) FindByUsers(userIds []int64) (map[int64]Memberships, rest_error.RestErr) {
+	byUser := make(map[int64]Memberships)
+	if len(userIds) == 0 {
+		return byUser, nil
+	}
+	args := make([]interface{}, len(userIds))
+	for i, userId := range userIds {
+		args[i] = userId
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(userIds)), ",")
+	rows, err := od.client.Query(fmt.Sprintf(findByUsersQuery, placeholders), args...)
+	if err != nil {
+		logger.Error("error executing find organizations by users query", err)
+		return nil, rest_error.NewInternalServerError("database error")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var userId int64
+		var membership Membership
+		if err := rows.Scan(&userId, &membership.Id, &membership.Name, &membership.DateCreated, &membership.Role); err != nil {
+			logger.Error("error scanning membership", err)
+			return nil, rest_error.NewInternalServerError("database error")
+		}
+		byUser[userId] = append(byUser[userId], membership)
+	}
+	if err := rows.Err(); err != nil {
+		logger.Error("error iterating memberships", err)
+		return nil, rest_error.NewInternalServerError("database error")
+	}
+	return byUser, nil
+}
+
+/// GetMember gets the membership of user with userId in organization with orgId
+func (od *organizationDao) GetMember(orgId int64, userId int64) (*Member, rest_error.RestErr) {
+	stmt, err := od.client.Prepare(getMemberQuery)
+	if err != nil {
+		logger.Error("error preparing get member query", err)
+		return nil, rest_error.NewInternalServerError("database error")
+	}
+	defer stmt.Close()
+
+	var member Member
+	err = stmt.QueryRow(orgId, userId).Scan(&member.OrganizationId, &member.UserId, &member.Role, &member.DateCreated)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, rest_error.NewNotFoundError("invalid user id: member not found")
+		}
+		logger.Error("error scanning member", err)
+		return nil, rest_error.NewInternalServerError("database error")
+	}
+	return &member, nil
+}
+
+/// FindMembers gets all members of organization with orgId
+func (od *organizationDao) FindMembers(orgId int64) (Members, rest_error.RestErr) {
+	stmt, err := od.client.Prepare(findMembersQuery)
+	if err != nil {
+		logger.Error("error preparing find members query", err)
+		return nil, rest_error.NewInternalServerError("database error")
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.Query(orgId)
+	if err != nil {
+		logger.Error("error executing find members query", err)
+		return nil, rest_error.NewInternalServerError("database error")
+	}
+	defer rows.Close()
+
+	members := make(Members, 0)
+	for rows.Next() {
+		var member Member
+		if err := rows.Scan(&member.OrganizationId, &member.UserId, &member.Role, &member.DateCreated); err != nil {
+			logger.Error("error scanning member", err)
+			return nil, rest_error.NewInternalServerError("database error")
+		}
+		members = append(members, member)
+	}
+	return members, nil
+}
+
+/// SaveMember adds a member to an organization, or changes the role of
+/// an existing one
+func (od *organizationDao) SaveMember(member Member) rest_error.RestErr {
+	stmt, err := od.client.Prepare(saveMemberQuery)
+	if err != nil {
+		logger.Error("error preparing save member query", err)
+		return rest_error.NewInternalServerError("database error")
+	}
+	defer stmt.Close()
+
+	if _, err := stmt.Exec(member.OrganizationId, member.UserId, member.Role, member.DateCreated); err != nil {
+		logger.Error("error executing save member query", err)
+		return rest_error.NewInternalServerError("database error")
+	}
+	return nil
+}
+
+/// DeleteMember removes user with userId from organization with orgId
+func (od *organizationDao) DeleteMember(orgId int64, userId int64) rest_error.RestErr {
+	stmt, err := od.client.Prepare(deleteMemberQuery)
+	if err != nil {
+		logger.Error("error preparing delete member query", err)
+		return rest_error.NewInternalServerError("database error")
+	}
+	defer stmt.Close()
+
+	result, err := stmt.Exec(orgId, userId)
+	if err != nil {
+		logger.Error("error executing delete member query", err)
+		return rest_error.NewInternalServerError("database error")
+	}
+	rowsAff, err := result.RowsAffected()
+	if err != nil {
+		logger.Error("error retrieving rows affected", err)
+		return rest_error.NewInternalServerError("database error")
+	}
+	if rowsAff < 1 {
+		return rest_error.NewNotFoundError("invalid user id: member not found")
+	}
+	return nil
+}
+
+/// CountOwners counts the owners of organization with orgId
+func (od *organizationDao) CountOwners(orgId int64) (int, rest_error.RestErr) {
+	stmt, err := od.client.Prepare(countOwnersQuery)
+	if err != nil {
+		logger.Error("error preparing count owners query", err)
+		return 0, rest_error.NewInternalServerError("database error")
+	}
+	defer stmt.Close()
+
+	var count int
+	if err := stmt.QueryRow(orgId, RoleOwner).Scan(&count); err != nil {
+		logger.Error("error scanning owner count", err)
+		return 0, rest_error.NewInternalServerError("database error")
+	}
+	return count, nil
+}
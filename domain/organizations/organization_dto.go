@@ -0,0 +1,104 @@
+package organizations
+
+import (
+	"github.com/Abacode7/bookstore_users-api/utils/error_utils"
+	"github.com/Abacode7/bookstore_utils-go/v2/rest_error"
+	"strings"
+)
+
+const (
+	RoleOwner  = "owner"
+	RoleAdmin  = "admin"
+	RoleMember = "member"
+
+	InvitationPending  = "pending"
+	InvitationAccepted = "accepted"
+	InvitationRevoked  = "revoked"
+)
+
+type Organization struct {
+	Id          int64  `json:"id"`
+	Name        string `json:"name"`
+	DateCreated string `json:"date_created"`
+}
+
+/// Membership is an organization along with a user's role in it
+type Membership struct {
+	Organization
+	Role string `json:"role"`
+}
+
+type Memberships []Membership
+
+/// OrganizationRole is a user's role in an organization
+type OrganizationRole struct {
+	OrganizationId int64  `json:"organization_id"`
+	Role           string `json:"role"`
+}
+
+type Member struct {
+	OrganizationId int64  `json:"organization_id"`
+	UserId         int64  `json:"user_id"`
+	Role           string `json:"role"`
+	DateCreated    string `json:"date_created"`
+}
+
+type Members []Member
+
+/// Invitation invites the owner of an email address into an organization
+type Invitation struct {
+	Id             int64  `json:"id"`
+	OrganizationId int64  `json:"organization_id"`
+	Email          string `json:"email"`
+	Role           string `json:"role"`
+	InvitedBy      int64  `json:"invited_by"`
+	TokenHash      string `json:"-"`
+	Status         string `json:"status"`
+	DateCreated    string `json:"date_created"`
+	ExpiresAt      string `json:"expires_at"`
+}
+
+func (org *Organization) Validate() rest_error.RestErr {
+	org.Name = strings.TrimSpace(org.Name)
+	if org.Name == "" {
+		return error_utils.NewFieldError("name", "invalid organization name")
+	}
+	return nil
+}
+
+/// ValidRole reports whether role is one of the membership roles
+func ValidRole(role string) bool {
+	return role == RoleOwner || role == RoleAdmin || role == RoleMember
+}
+
+/// CanManage reports whether a member with role can manage the
+/// membership of a member with targetRole. Owners manage everyone,
+/// admins only plain members.
+func CanManage(role string, targetRole string) bool {
+	switch role {
+	case RoleOwner:
+		return true
+	case RoleAdmin:
+		return targetRole == RoleMember
+	default:
+		return false
+	}
+}
+
+/// Organizations are the organizations of the memberships
+func (memberships Memberships) Organizations() []Organization {
+	result := make([]Organization, 0, len(memberships))
+	for _, membership := range memberships {
+		result = append(result, membership.Organization)
+	}
+	return result
+}
+
+/// Roles are the roles the memberships grant
+func (memberships Memberships) Roles() []OrganizationRole {
+	result := make([]OrganizationRole, 0, len(memberships))
+	for _, membership := range memberships {
+		result = append(result, OrganizationRole{OrganizationId: membership.Id, Role: membership.Role})
+	}
+	return result
+}
@@ -0,0 +1,180 @@
+package outbox
+
+import (
+	"database/sql"
+	"fmt"
+	"github.com/Abacode7/bookstore_users-api/utils/date_utils"
+	"github.com/Abacode7/bookstore_utils-go/v2/logger"
+	"github.com/Abacode7/bookstore_utils-go/v2/rest_error"
+	"strings"
+	"time"
+)
+
+const (
+	insertEventQuery     = `INSERT INTO users_outbox (event_type, aggregate_id, payload, date_created, next_attempt_at) VALUES (?, ?, ?, ?, ?);`
+	claimDueQuery        = `SELECT id, event_type, aggregate_id, payload, date_created, attempts FROM users_outbox WHERE date_delivered IS NULL AND next_attempt_at <= ? AND NOT EXISTS (SELECT 1 FROM users_outbox earlier WHERE earlier.aggregate_id=users_outbox.aggregate_id AND earlier.id < users_outbox.id AND earlier.date_delivered IS NULL AND earlier.next_attempt_at > ?) ORDER BY id LIMIT ? FOR UPDATE SKIP LOCKED;`
+	leaseEventsQuery     = `UPDATE users_outbox SET next_attempt_at=? WHERE id IN (%s);`
+	markDeliveredQuery   = `UPDATE users_outbox SET attempts=attempts+1, date_delivered=?, last_error=NULL WHERE id=?;`
+	markFailedQuery      = `UPDATE users_outbox SET attempts=attempts+1, next_attempt_at=?, last_error=? WHERE id=?;`
+	postponeEventQuery   = `UPDATE users_outbox SET next_attempt_at=? WHERE id=?;`
+	findByAggregateQuery = `SELECT id, event_type, aggregate_id, payload, date_created, attempts FROM users_outbox WHERE aggregate_id=? ORDER BY id DESC LIMIT ?;`
+
+	maxLastErrorLength = 255
+	/// claimLease is how long claimed events are held by the relay that
+	/// claimed them, it outlasts the delivery of a batch
+	claimLease = 15 * time.Minute
+)
+
+/// Execer is satisfied by both *sql.DB and *sql.Tx, letting an event be
+/// written in the transaction of the change it describes
+type Execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+type IEventDao interface {
+	Append(Execer, Event) rest_error.RestErr
+	ProcessDue(limit int, deliver func(Event) error, retryDelay func(attempts int) time.Duration) (int, rest_error.RestErr)
+	FindByAggregate(aggregateId int64, limit int) ([]Event, rest_error.RestErr)
+}
+
+type eventDao struct {
+	client *sql.DB
+}
+
+/// NewEventDao is a constructor for eventDao
+func NewEventDao(db *sql.DB) IEventDao {
+	return &eventDao{db}
+}
+
+/// Append writes event to the outbox through execer, it is due at once
+func (ed *eventDao) Append(execer Execer, event Event) rest_error.RestErr {
+	_, err := execer.Exec(insertEventQuery, event.Type, event.AggregateId, string(event.Payload), event.DateCreated, event.DateCreated)
+	if err != nil {
+		logger.Error("error executing insert outbox event query", err)
+		return rest_error.NewInternalServerError("database error")
+	}
+	return nil
+}
+
+/// ProcessDue claims up to limit undelivered events that are due and
+/// hands them to deliver in order. Delivered events are marked as such,
+/// failed ones are retried after retryDelay of their attempts. The events
+/// of a user are delivered in order: after a failure, the user's later
+/// events are postponed to the retry, and they aren't claimed while an
+/// earlier one waits for it. Claimed events are leased to this relay, so
+/// relays on several instances don't deliver the same event concurrently,
+/// and delivery happens outside of any transaction. It returns how many
+/// events were processed.
+func (ed *eventDao) ProcessDue(limit int, deliver func(Event) error, retryDelay func(attempts int) time.Duration) (int, rest_error.RestErr) {
+	events, restErr := ed.claimDue(limit)
+	if restErr != nil {
+		return 0, restErr
+	}
+	/// retries holds the next attempt of the users whose delivery failed
+	retries := make(map[int64]string)
+	for _, event := range events {
+		if nextAttemptAt, failed := retries[event.AggregateId]; failed {
+			ed.mark(postponeEventQuery, nextAttemptAt, event.Id)
+			continue
+		}
+		if deliverErr := deliver(event); deliverErr != nil {
+			logger.Error(fmt.Sprintf("error delivering outbox event %d", event.Id), deliverErr)
+			nextAttemptAt := date_utils.FormatDbTime(date_utils.GetTime().Add(retryDelay(event.Attempts + 1)))
+			retries[event.AggregateId] = nextAttemptAt
+			ed.mark(markFailedQuery, nextAttemptAt, truncate(deliverErr.Error(), maxLastErrorLength), event.Id)
+			continue
+		}
+		ed.mark(markDeliveredQuery, date_utils.GetDbFormattedTime(), event.Id)
+	}
+	return len(events), nil
+}
+
+/// mark records what became of a claimed event. A failure is only logged
+/// so the rest of the batch is still marked, the event stays leased and
+/// is delivered again once its lease ends.
+func (ed *eventDao) mark(query string, args ...interface{}) {
+	if _, err := ed.client.Exec(query, args...); err != nil {
+		logger.Error("error executing mark outbox event query", err)
+	}
+}
+
+/// FindByAggregate gets the latest limit events about the entity with
+/// aggregateId, delivered or not
+func (ed *eventDao) FindByAggregate(aggregateId int64, limit int) ([]Event, rest_error.RestErr) {
+	rows, err := ed.client.Query(findByAggregateQuery, aggregateId, limit)
+	if err != nil {
+		logger.Error("error executing find outbox events query", err)
+		return nil, rest_error.NewInternalServerError("database error")
+	}
+	defer rows.Close()
+	return scanEvents(rows)
+}
+
+/// claimDue locks up to limit due events and leases them for claimLease
+/// by moving their next attempt past it. Other relays skip them until a
+/// delivery result is marked, or the lease ends if this relay dies.
+func (ed *eventDao) claimDue(limit int) ([]Event, rest_error.RestErr) {
+	tx, err := ed.client.Begin()
+	if err != nil {
+		logger.Error("error starting claim outbox transaction", err)
+		return nil, rest_error.NewInternalServerError("database error")
+	}
+	defer tx.Rollback()
+
+	now := date_utils.GetDbFormattedTime()
+	events, restErr := queryEvents(tx, claimDueQuery, now, now, limit)
+	if restErr != nil || len(events) == 0 {
+		return events, restErr
+	}
+	args := []interface{}{date_utils.FormatDbTime(date_utils.GetTime().Add(claimLease))}
+	placeholders := make([]string, 0, len(events))
+	for _, event := range events {
+		args = append(args, event.Id)
+		placeholders = append(placeholders, "?")
+	}
+	if _, err := tx.Exec(fmt.Sprintf(leaseEventsQuery, strings.Join(placeholders, ", ")), args...); err != nil {
+		logger.Error("error executing lease outbox events query", err)
+		return nil, rest_error.NewInternalServerError("database error")
+	}
+	if err := tx.Commit(); err != nil {
+		logger.Error("error committing claim outbox transaction", err)
+		return nil, rest_error.NewInternalServerError("database error")
+	}
+	return events, nil
+}
+
+func queryEvents(tx *sql.Tx, query string, args ...interface{}) ([]Event, rest_error.RestErr) {
+	rows, err := tx.Query(query, args...)
+	if err != nil {
+		logger.Error("error executing claim outbox events query", err)
+		return nil, rest_error.NewInternalServerError("database error")
+	}
+	defer rows.Close()
+	return scanEvents(rows)
+}
+
+func scanEvents(rows *sql.Rows) ([]Event, rest_error.RestErr) {
+	events := make([]Event, 0)
+	for rows.Next() {
+		var event Event
+		var payload string
+		if err := rows.Scan(&event.Id, &event.Type, &event.AggregateId, &payload, &event.DateCreated, &event.Attempts); err != nil {
+			logger.Error("error scanning outbox event", err)
+			return nil, rest_error.NewInternalServerError("database error")
+		}
+		event.Payload = []byte(payload)
+		events = append(events, event)
+	}
+	if err := rows.Err(); err != nil {
+		logger.Error("error iterating outbox events", err)
+		return nil, rest_error.NewInternalServerError("database error")
+	}
+	return events, nil
+}
+
+func truncate(value string, length int) string {
+	if len(value) <= length {
+		return value
+	}
+	return value[:length]
+}
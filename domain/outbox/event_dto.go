@@ -0,0 +1,31 @@
+package outbox
+
+import (
+	"encoding/json"
+	"github.com/Abacode7/bookstore_users-api/utils/date_utils"
+)
+
+/// Event is a domain event waiting in, or relayed from, the outbox
+type Event struct {
+	Id          int64           `json:"id"`
+	Type        string          `json:"type"`
+	AggregateId int64           `json:"aggregate_id"`
+	Payload     json.RawMessage `json:"payload"`
+	DateCreated string          `json:"date_created"`
+	Attempts    int             `json:"-"`
+}
+
+/// NewEvent is a constructor for an Event of eventType about the entity
+/// with aggregateId, carrying payload encoded as json
+func NewEvent(eventType string, aggregateId int64, payload interface{}) (*Event, error) {
+	rawPayload, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	return &Event{
+		Type:        eventType,
+		AggregateId: aggregateId,
+		Payload:     rawPayload,
+		DateCreated: date_utils.GetDbFormattedTime(),
+	}, nil
+}
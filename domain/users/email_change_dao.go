@@ -0,0 +1,162 @@
+package users
+
+import (
+	"database/sql"
+	"github.com/Abacode7/bookstore_utils-go/v2/logger"
+	"github.com/Abacode7/bookstore_utils-go/v2/rest_error"
+)
+
+const (
+	emailChangeColumns             = `id, user_id, old_email, new_email, confirm_token_hash, cancel_token_hash, status, date_created, confirm_expires_at, cancel_expires_at`
+	insertEmailChangeQuery         = `INSERT INTO users_email_changes (user_id, old_email, new_email, confirm_token_hash, cancel_token_hash, status, date_created, confirm_expires_at, cancel_expires_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?);`
+	findEmailChangeByConfirmQuery  = `SELECT ` + emailChangeColumns + ` FROM users_email_changes WHERE confirm_token_hash=?;`
+	findEmailChangeByCancelQuery   = `SELECT ` + emailChangeColumns + ` FROM users_email_changes WHERE cancel_token_hash=?;`
+	findEmailChangesByUserQuery    = `SELECT ` + emailChangeColumns + ` FROM users_email_changes WHERE user_id=? ORDER BY id DESC;`
+	updateEmailChangeStatusQuery   = `UPDATE users_email_changes SET status=? WHERE id=?;`
+	cancelPendingEmailChangesQuery = `UPDATE users_email_changes SET status=? WHERE user_id=? AND status=?;`
+)
+
+type IEmailChangeDao interface {
+	Save(EmailChange) (*EmailChange, rest_error.RestErr)
+	FindByConfirmToken(string) (*EmailChange, rest_error.RestErr)
+	FindByCancelToken(string) (*EmailChange, rest_error.RestErr)
+	UpdateStatus(int64, string) rest_error.RestErr
+	CancelPending(int64) rest_error.RestErr
+	FindByUser(int64) ([]EmailChange, rest_error.RestErr)
+}
+
+type emailChangeDao struct {
+	client *sql.DB
+}
+
+/// NewEmailChangeDao is a constructor for emailChangeDao
+func NewEmailChangeDao(db *sql.DB) IEmailChangeDao {
+	return &emailChangeDao{db}
+}
+
+/// Save stores an email change request
+func (ecd *emailChangeDao) Save(change EmailChange) (*EmailChange, rest_error.RestErr) {
+	stmt, err := ecd.client.Prepare(insertEmailChangeQuery)
+	if err != nil {
+		logger.Error("error preparing insert email change query", err)
+		return nil, rest_error.NewInternalServerError("database error")
+	}
+	defer stmt.Close()
+
+	result, err := stmt.Exec(change.UserId, change.OldEmail, change.NewEmail, change.ConfirmTokenHash,
+		change.CancelTokenHash, change.Status, change.DateCreated, change.ConfirmExpiresAt, change.CancelExpiresAt)
+	if err != nil {
+		logger.Error("error executing insert email change query", err)
+		return nil, rest_error.NewInternalServerError("database error")
+	}
+	change.Id, err = result.LastInsertId()
+	if err != nil {
+		logger.Error("error retrieving last insert id", err)
+		return nil, rest_error.NewInternalServerError("database error")
+	}
+	return &change, nil
+}
+
+/// FindByConfirmToken gets the email change with the given confirmation token hash
+func (ecd *emailChangeDao) FindByConfirmToken(tokenHash string) (*EmailChange, rest_error.RestErr) {
+	return ecd.findOne(findEmailChangeByConfirmQuery, tokenHash)
+}
+
+/// FindByCancelToken gets the email change with the given cancellation token hash
+func (ecd *emailChangeDao) FindByCancelToken(tokenHash string) (*EmailChange, rest_error.RestErr) {
+	return ecd.findOne(findEmailChangeByCancelQuery, tokenHash)
+}
+
+/// FindByUser gets the email changes of a user, latest first
+func (ecd *emailChangeDao) FindByUser(userId int64) ([]EmailChange, rest_error.RestErr) {
+	stmt, err := ecd.client.Prepare(findEmailChangesByUserQuery)
+	if err != nil {
+		logger.Error("error preparing find email changes query", err)
+		return nil, rest_error.NewInternalServerError("database error")
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.Query(userId)
+	if err != nil {
+		logger.Error("error executing find email changes query", err)
+		return nil, rest_error.NewInternalServerError("database error")
+	}
+	defer rows.Close()
+
+	changes := make([]EmailChange, 0)
+	for rows.Next() {
+		change, err := scanEmailChange(rows)
+		if err != nil {
+			logger.Error("error scanning email change", err)
+			return nil, rest_error.NewInternalServerError("database error")
+		}
+		changes = append(changes, *change)
+	}
+	return changes, nil
+}
+
+func (ecd *emailChangeDao) findOne(query string, args ...interface{}) (*EmailChange, rest_error.RestErr) {
+	stmt, err := ecd.client.Prepare(query)
+	if err != nil {
+		logger.Error("error preparing find email change query", err)
+		return nil, rest_error.NewInternalServerError("database error")
+	}
+	defer stmt.Close()
+
+	change, err := scanEmailChange(stmt.QueryRow(args...))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, rest_error.NewNotFoundError("invalid token: email change not found")
+		}
+		logger.Error("error scanning email change", err)
+		return nil, rest_error.NewInternalServerError("database error")
+	}
+	return change, nil
+}
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanEmailChange(row scanner) (*EmailChange, error) {
+	var change EmailChange
+	err := row.Scan(&change.Id, &change.UserId, &change.OldEmail, &change.NewEmail,
+		&change.ConfirmTokenHash, &change.CancelTokenHash, &change.Status, &change.DateCreated,
+		&change.ConfirmExpiresAt, &change.CancelExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+	return &change, nil
+}
+
+/// UpdateStatus sets the status of the email change with id
+func (ecd *emailChangeDao) UpdateStatus(id int64, status string) rest_error.RestErr {
+	stmt, err := ecd.client.Prepare(updateEmailChangeStatusQuery)
+	if err != nil {
+		logger.Error("error preparing update email change query", err)
+		return rest_error.NewInternalServerError("database error")
+	}
+	defer stmt.Close()
+
+	if _, err := stmt.Exec(status, id); err != nil {
+		logger.Error("error executing update email change query", err)
+		return rest_error.NewInternalServerError("database error")
+	}
+	return nil
+}
+
+/// CancelPending cancels every pending email change of the user with userId
+func (ecd *emailChangeDao) CancelPending(userId int64) rest_error.RestErr {
+	stmt, err := ecd.client.Prepare(cancelPendingEmailChangesQuery)
+	if err != nil {
+		logger.Error("error preparing cancel email changes query", err)
+		return rest_error.NewInternalServerError("database error")
+	}
+	defer stmt.Close()
+
+	if _, err := stmt.Exec(EmailChangeCancelled, userId, EmailChangePending); err != nil {
+		logger.Error("error executing cancel email changes query", err)
+		return rest_error.NewInternalServerError("database error")
+	}
+	return nil
+}
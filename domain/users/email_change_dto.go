@@ -0,0 +1,24 @@
+package users
+
+const (
+	EmailChangePending   = "pending"
+	EmailChangeConfirmed = "confirmed"
+	EmailChangeCancelled = "cancelled"
+)
+
+/// EmailChange is a request to change a user's email address. The new
+/// address only replaces the old one once confirmed, and the old address
+/// can cancel the change, reverting it if already confirmed, until
+/// CancelExpiresAt.
+type EmailChange struct {
+	Id               int64
+	UserId           int64
+	OldEmail         string
+	NewEmail         string
+	ConfirmTokenHash string
+	CancelTokenHash  string
+	Status           string
+	DateCreated      string
+	ConfirmExpiresAt string
+	CancelExpiresAt  string
+}
@@ -0,0 +1,89 @@
+package users
+
+import (
+	"database/sql"
+	"github.com/Abacode7/bookstore_users-api/utils/date_utils"
+	"github.com/Abacode7/bookstore_utils-go/v2/logger"
+	"github.com/Abacode7/bookstore_utils-go/v2/rest_error"
+)
+
+const (
+	insertPasswordHistoryQuery = `INSERT INTO users_password_history (user_id, password, date_created) VALUES (?, ?, ?);`
+	getPasswordHistoryQuery    = `SELECT password FROM users_password_history WHERE user_id=? ORDER BY id DESC LIMIT ?;`
+	prunePasswordHistoryQuery  = `DELETE FROM users_password_history WHERE user_id=? AND id NOT IN (SELECT id FROM (SELECT id FROM users_password_history WHERE user_id=? ORDER BY id DESC LIMIT ?) AS recent);`
+)
+
+type IPasswordHistoryDao interface {
+	Save(int64, string) rest_error.RestErr
+	GetRecent(int64, int) ([]string, rest_error.RestErr)
+	Prune(int64, int) rest_error.RestErr
+}
+
+type passwordHistoryDao struct {
+	client *sql.DB
+}
+
+/// NewPasswordHistoryDao is a constructor for passwordHistoryDao
+func NewPasswordHistoryDao(db *sql.DB) IPasswordHistoryDao {
+	return &passwordHistoryDao{db}
+}
+
+/// Save records a password hash in the user's password history
+func (phd *passwordHistoryDao) Save(userId int64, passwordHash string) rest_error.RestErr {
+	stmt, err := phd.client.Prepare(insertPasswordHistoryQuery)
+	if err != nil {
+		logger.Error("error preparing insert password history query", err)
+		return rest_error.NewInternalServerError("database error")
+	}
+	defer stmt.Close()
+
+	if _, err := stmt.Exec(userId, passwordHash, date_utils.GetDbFormattedTime()); err != nil {
+		logger.Error("error executing insert password history query", err)
+		return rest_error.NewInternalServerError("database error")
+	}
+	return nil
+}
+
+/// GetRecent gets the latest limit password hashes of a user, newest first
+func (phd *passwordHistoryDao) GetRecent(userId int64, limit int) ([]string, rest_error.RestErr) {
+	stmt, err := phd.client.Prepare(getPasswordHistoryQuery)
+	if err != nil {
+		logger.Error("error preparing get password history query", err)
+		return nil, rest_error.NewInternalServerError("database error")
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.Query(userId, limit)
+	if err != nil {
+		logger.Error("error executing get password history query", err)
+		return nil, rest_error.NewInternalServerError("database error")
+	}
+	defer rows.Close()
+
+	hashes := make([]string, 0, limit)
+	for rows.Next() {
+		var hash string
+		if err := rows.Scan(&hash); err != nil {
+			logger.Error("error scanning password history", err)
+			return nil, rest_error.NewInternalServerError("database error")
+		}
+		hashes = append(hashes, hash)
+	}
+	return hashes, nil
+}
+
+/// Prune removes all but the latest keep password hashes of a user
+func (phd *passwordHistoryDao) Prune(userId int64, keep int) rest_error.RestErr {
+	stmt, err := phd.client.Prepare(prunePasswordHistoryQuery)
+	if err != nil {
+		logger.Error("error preparing prune password history query", err)
+		return rest_error.NewInternalServerError("database error")
+	}
+	defer stmt.Close()
+
+	if _, err := stmt.Exec(userId, userId, keep); err != nil {
+		logger.Error("error executing prune password history query", err)
+		return rest_error.NewInternalServerError("database error")
+	}
+	return nil
+}
@@ -0,0 +1,197 @@
+package users
+
+import (
+	"database/sql"
+	"encoding/json"
+	"github.com/Abacode7/bookstore_utils-go/v2/logger"
+	"github.com/Abacode7/bookstore_utils-go/v2/rest_error"
+)
+
+const (
+	getProfileQuery  = `SELECT phone, date_of_birth, locale, timezone, phone_public, date_of_birth_public, locale_public, timezone_public FROM users_profiles WHERE user_id=?;`
+	saveProfileQuery = `INSERT INTO users_profiles (user_id, phone, date_of_birth, locale, timezone, phone_public, date_of_birth_public, locale_public, timezone_public) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE phone=VALUES(phone), date_of_birth=VALUES(date_of_birth), locale=VALUES(locale), timezone=VALUES(timezone),
+		phone_public=VALUES(phone_public), date_of_birth_public=VALUES(date_of_birth_public), locale_public=VALUES(locale_public), timezone_public=VALUES(timezone_public);`
+	getAttributesQuery           = `SELECT attr_key, value FROM users_profile_attributes WHERE user_id=? AND tenant_id=?;`
+	getAllAttributesQuery        = `SELECT tenant_id, attr_key, value FROM users_profile_attributes WHERE user_id=?;`
+	deleteAttributesQuery        = `DELETE FROM users_profile_attributes WHERE user_id=? AND tenant_id=?;`
+	insertAttributeQuery         = `INSERT INTO users_profile_attributes (user_id, tenant_id, attr_key, value) VALUES (?, ?, ?, ?);`
+	getAttributeDefinitionsQuery = `SELECT tenant_id, attr_key, type, required, public, max_length FROM profile_attribute_definitions WHERE tenant_id=?;`
+)
+
+type IProfileDao interface {
+	Get(int64, int64) (*Profile, rest_error.RestErr)
+	Save(int64, int64, Profile) rest_error.RestErr
+	GetAttributeDefinitions(int64) (AttributeDefinitions, rest_error.RestErr)
+	GetAllAttributes(int64) (map[int64]map[string]interface{}, rest_error.RestErr)
+}
+
+type profileDao struct {
+	client *sql.DB
+}
+
+/// NewProfileDao is a constructor for profileDao
+func NewProfileDao(db *sql.DB) IProfileDao {
+	return &profileDao{db}
+}
+
+/// Get gets the profile of user with userId along with the attributes
+/// of tenant tenantId. Users without a profile get an empty one.
+func (pd *profileDao) Get(userId int64, tenantId int64) (*Profile, rest_error.RestErr) {
+	stmt, err := pd.client.Prepare(getProfileQuery)
+	if err != nil {
+		logger.Error("error preparing get profile query", err)
+		return nil, rest_error.NewInternalServerError("database error")
+	}
+	defer stmt.Close()
+
+	var profile Profile
+	var dateOfBirth sql.NullString
+	err = stmt.QueryRow(userId).Scan(&profile.Phone, &dateOfBirth, &profile.Locale, &profile.Timezone,
+		&profile.Visibility.Phone, &profile.Visibility.DateOfBirth, &profile.Visibility.Locale, &profile.Visibility.Timezone)
+	if err != nil && err != sql.ErrNoRows {
+		logger.Error("error scanning profile", err)
+		return nil, rest_error.NewInternalServerError("database error")
+	}
+	profile.DateOfBirth = dateOfBirth.String
+
+	attrStmt, err := pd.client.Prepare(getAttributesQuery)
+	if err != nil {
+		logger.Error("error preparing get attributes query", err)
+		return nil, rest_error.NewInternalServerError("database error")
+	}
+	defer attrStmt.Close()
+
+	rows, err := attrStmt.Query(userId, tenantId)
+	if err != nil {
+		logger.Error("error executing get attributes query", err)
+		return nil, rest_error.NewInternalServerError("database error")
+	}
+	defer rows.Close()
+
+	profile.Attributes = make(map[string]interface{})
+	for rows.Next() {
+		var key, rawValue string
+		if err := rows.Scan(&key, &rawValue); err != nil {
+			logger.Error("error scanning attribute", err)
+			return nil, rest_error.NewInternalServerError("database error")
+		}
+		var value interface{}
+		if err := json.Unmarshal([]byte(rawValue), &value); err != nil {
+			logger.Error("error decoding attribute value", err)
+			return nil, rest_error.NewInternalServerError("database error")
+		}
+		profile.Attributes[key] = value
+	}
+	return &profile, nil
+}
+
+/// GetAllAttributes gets the profile attributes of user with userId for
+/// every tenant, by tenant id
+func (pd *profileDao) GetAllAttributes(userId int64) (map[int64]map[string]interface{}, rest_error.RestErr) {
+	stmt, err := pd.client.Prepare(getAllAttributesQuery)
+	if err != nil {
+		logger.Error("error preparing get all attributes query", err)
+		return nil, rest_error.NewInternalServerError("database error")
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.Query(userId)
+	if err != nil {
+		logger.Error("error executing get all attributes query", err)
+		return nil, rest_error.NewInternalServerError("database error")
+	}
+	defer rows.Close()
+
+	attributes := make(map[int64]map[string]interface{})
+	for rows.Next() {
+		var tenantId int64
+		var key, rawValue string
+		if err := rows.Scan(&tenantId, &key, &rawValue); err != nil {
+			logger.Error("error scanning attribute", err)
+			return nil, rest_error.NewInternalServerError("database error")
+		}
+		var value interface{}
+		if err := json.Unmarshal([]byte(rawValue), &value); err != nil {
+			logger.Error("error decoding attribute value", err)
+			return nil, rest_error.NewInternalServerError("database error")
+		}
+		if attributes[tenantId] == nil {
+			attributes[tenantId] = make(map[string]interface{})
+		}
+		attributes[tenantId][key] = value
+	}
+	return attributes, nil
+}
+
+/// Save stores the profile of user with userId, replacing the user's
+/// attributes of tenant tenantId
+func (pd *profileDao) Save(userId int64, tenantId int64, profile Profile) rest_error.RestErr {
+	tx, err := pd.client.Begin()
+	if err != nil {
+		logger.Error("error starting save profile transaction", err)
+		return rest_error.NewInternalServerError("database error")
+	}
+	defer tx.Rollback()
+
+	var dateOfBirth sql.NullString
+	if profile.DateOfBirth != "" {
+		dateOfBirth = sql.NullString{String: profile.DateOfBirth, Valid: true}
+	}
+	_, err = tx.Exec(saveProfileQuery, userId, profile.Phone, dateOfBirth, profile.Locale, profile.Timezone,
+		profile.Visibility.Phone, profile.Visibility.DateOfBirth, profile.Visibility.Locale, profile.Visibility.Timezone)
+	if err != nil {
+		logger.Error("error executing save profile query", err)
+		return rest_error.NewInternalServerError("database error")
+	}
+	if _, err := tx.Exec(deleteAttributesQuery, userId, tenantId); err != nil {
+		logger.Error("error executing delete attributes query", err)
+		return rest_error.NewInternalServerError("database error")
+	}
+	for key, value := range profile.Attributes {
+		rawValue, err := json.Marshal(value)
+		if err != nil {
+			logger.Error("error encoding attribute value", err)
+			return rest_error.NewInternalServerError("database error")
+		}
+		if _, err := tx.Exec(insertAttributeQuery, userId, tenantId, key, string(rawValue)); err != nil {
+			logger.Error("error executing insert attribute query", err)
+			return rest_error.NewInternalServerError("database error")
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		logger.Error("error committing save profile transaction", err)
+		return rest_error.NewInternalServerError("database error")
+	}
+	return nil
+}
+
+/// GetAttributeDefinitions gets the custom attribute schema of tenant tenantId
+func (pd *profileDao) GetAttributeDefinitions(tenantId int64) (AttributeDefinitions, rest_error.RestErr) {
+	stmt, err := pd.client.Prepare(getAttributeDefinitionsQuery)
+	if err != nil {
+		logger.Error("error preparing get attribute definitions query", err)
+		return nil, rest_error.NewInternalServerError("database error")
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.Query(tenantId)
+	if err != nil {
+		logger.Error("error executing get attribute definitions query", err)
+		return nil, rest_error.NewInternalServerError("database error")
+	}
+	defer rows.Close()
+
+	definitions := make(AttributeDefinitions, 0)
+	for rows.Next() {
+		var definition AttributeDefinition
+		err := rows.Scan(&definition.TenantId, &definition.Key, &definition.Type,
+			&definition.Required, &definition.Public, &definition.MaxLength)
+		if err != nil {
+			logger.Error("error scanning attribute definition", err)
+			return nil, rest_error.NewInternalServerError("database error")
+		}
+		definitions = append(definitions, definition)
+	}
+	return definitions, nil
+}
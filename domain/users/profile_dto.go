@@ -0,0 +1,175 @@
+package users
+
+import (
+	"github.com/Abacode7/bookstore_users-api/utils/error_utils"
+	"github.com/Abacode7/bookstore_utils-go/v2/rest_error"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	AttributeTypeString  = "string"
+	AttributeTypeNumber  = "number"
+	AttributeTypeBoolean = "boolean"
+	AttributeTypeDate    = "date"
+
+	dateLayout = "2006-01-02"
+)
+
+var (
+	phoneRegexp  = regexp.MustCompile(`^\+[1-9][0-9]{6,14}$`)
+	localeRegexp = regexp.MustCompile(`^[a-z]{2,3}(-[A-Z]{2})?$`)
+)
+
+/// Profile holds a user's optional personal details and the custom
+/// attributes defined by the tenant (oauth client) the user belongs to
+type Profile struct {
+	Phone       string                 `json:"phone"`
+	DateOfBirth string                 `json:"date_of_birth"`
+	Locale      string                 `json:"locale"`
+	Timezone    string                 `json:"timezone"`
+	Attributes  map[string]interface{} `json:"attributes"`
+	Visibility  ProfileVisibility      `json:"visibility"`
+
+	/// publicAttributes are the keys of Attributes whose definition
+	/// allows them in a PublicUser, set when the profile is loaded
+	publicAttributes map[string]bool
+}
+
+/// ProfileVisibility flags which core profile fields are public
+type ProfileVisibility struct {
+	Phone       bool `json:"phone"`
+	DateOfBirth bool `json:"date_of_birth"`
+	Locale      bool `json:"locale"`
+	Timezone    bool `json:"timezone"`
+}
+
+/// PublicProfile is the part of a Profile anyone may see
+type PublicProfile struct {
+	Phone       string                 `json:"phone,omitempty"`
+	DateOfBirth string                 `json:"date_of_birth,omitempty"`
+	Locale      string                 `json:"locale,omitempty"`
+	Timezone    string                 `json:"timezone,omitempty"`
+	Attributes  map[string]interface{} `json:"attributes,omitempty"`
+}
+
+/// AttributeDefinition is the schema of a tenant's custom profile attribute
+type AttributeDefinition struct {
+	TenantId  int64
+	Key       string
+	Type      string
+	Required  bool
+	Public    bool
+	MaxLength int
+}
+
+type AttributeDefinitions []AttributeDefinition
+
+/// Validate checks the core fields of the profile and its attributes
+/// against the tenant's definitions
+func (profile *Profile) Validate(definitions AttributeDefinitions) rest_error.RestErr {
+	profile.Phone = strings.TrimSpace(profile.Phone)
+	if profile.Phone != "" && !phoneRegexp.MatchString(profile.Phone) {
+		return error_utils.NewFieldError("phone", "invalid phone number: must be in E.164 format")
+	}
+	if profile.DateOfBirth != "" {
+		dateOfBirth, err := time.Parse(dateLayout, profile.DateOfBirth)
+		if err != nil || dateOfBirth.After(time.Now()) {
+			return error_utils.NewFieldError("date_of_birth", "invalid date of birth")
+		}
+	}
+	if profile.Locale != "" && !localeRegexp.MatchString(profile.Locale) {
+		return error_utils.NewFieldError("locale", "invalid locale")
+	}
+	if profile.Timezone != "" {
+		if _, err := time.LoadLocation(profile.Timezone); err != nil {
+			return error_utils.NewFieldError("timezone", "invalid timezone")
+		}
+	}
+	return definitions.Validate(profile.Attributes)
+}
+
+/// Public returns the fields and attributes of the profile marked public
+func (profile *Profile) Public() *PublicProfile {
+	var public PublicProfile
+	if profile.Visibility.Phone {
+		public.Phone = profile.Phone
+	}
+	if profile.Visibility.DateOfBirth {
+		public.DateOfBirth = profile.DateOfBirth
+	}
+	if profile.Visibility.Locale {
+		public.Locale = profile.Locale
+	}
+	if profile.Visibility.Timezone {
+		public.Timezone = profile.Timezone
+	}
+	for key, value := range profile.Attributes {
+		if !profile.publicAttributes[key] {
+			continue
+		}
+		if public.Attributes == nil {
+			public.Attributes = make(map[string]interface{})
+		}
+		public.Attributes[key] = value
+	}
+	return &public
+}
+
+/// SetAttributeVisibility records which attributes are public
+/// according to the definitions
+func (profile *Profile) SetAttributeVisibility(definitions AttributeDefinitions) {
+	profile.publicAttributes = make(map[string]bool)
+	for _, definition := range definitions {
+		if definition.Public {
+			profile.publicAttributes[definition.Key] = true
+		}
+	}
+}
+
+/// Validate checks attributes against the definitions: every attribute
+/// must be defined and of the defined type, and required ones present
+func (definitions AttributeDefinitions) Validate(attributes map[string]interface{}) rest_error.RestErr {
+	byKey := make(map[string]AttributeDefinition, len(definitions))
+	for _, definition := range definitions {
+		byKey[definition.Key] = definition
+		if _, ok := attributes[definition.Key]; definition.Required && !ok {
+			return rest_error.NewBadRequestError("missing required attribute " + definition.Key)
+		}
+	}
+	for key, value := range attributes {
+		definition, ok := byKey[key]
+		if !ok {
+			return error_utils.NewFieldError("attributes."+key, "unknown attribute "+key)
+		}
+		if !definition.accepts(value) {
+			return error_utils.NewFieldError("attributes."+key, "invalid value for attribute "+key)
+		}
+	}
+	return nil
+}
+
+func (definition AttributeDefinition) accepts(value interface{}) bool {
+	switch definition.Type {
+	case AttributeTypeString:
+		s, ok := value.(string)
+		return ok && (definition.MaxLength <= 0 || utf8.RuneCountInString(s) <= definition.MaxLength)
+	case AttributeTypeNumber:
+		_, ok := value.(float64)
+		return ok
+	case AttributeTypeBoolean:
+		_, ok := value.(bool)
+		return ok
+	case AttributeTypeDate:
+		s, ok := value.(string)
+		if !ok {
+			return false
+		}
+		_, err := time.Parse(dateLayout, s)
+		return err == nil
+	default:
+		return false
+	}
+}
@@ -0,0 +1,104 @@
+package users
+
+import (
+	"github.com/Abacode7/bookstore_utils-go/v2/rest_error"
+	"sort"
+	"sync"
+)
+
+/// memorySearchIndex is an in-process inverted index from the words of
+/// users' names and emails to the users, for tests and single instances
+type memorySearchIndex struct {
+	mu       sync.RWMutex
+	users    map[int64]User
+	postings map[string]map[int64]bool
+}
+
+/// NewMemorySearchIndex is a constructor for an in-process ISearchIndex
+func NewMemorySearchIndex() ISearchIndex {
+	return &memorySearchIndex{
+		users:    make(map[int64]User),
+		postings: make(map[string]map[int64]bool),
+	}
+}
+
+func (msi *memorySearchIndex) Search(query SearchQuery) (SearchResults, rest_error.RestErr) {
+	if err := query.Validate(); err != nil {
+		return nil, err
+	}
+	terms := Tokenize(query.Text)
+
+	msi.mu.RLock()
+	defer msi.mu.RUnlock()
+
+	candidates := make(map[int64]bool)
+	for word, userIds := range msi.postings {
+		for _, term := range terms {
+			if matchTerm(word, term) > 0 {
+				for userId := range userIds {
+					candidates[userId] = true
+				}
+				break
+			}
+		}
+	}
+
+	results := make(SearchResults, 0, len(candidates))
+	for userId := range candidates {
+		user := msi.users[userId]
+		score, highlights := scoreUser(user, terms)
+		results = append(results, SearchResult{User: user, Score: score, Highlights: highlights})
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].User.Id < results[j].User.Id
+	})
+	if len(results) > query.Limit {
+		results = results[:query.Limit]
+	}
+	return results, nil
+}
+
+func (msi *memorySearchIndex) Index(user User) rest_error.RestErr {
+	msi.mu.Lock()
+	defer msi.mu.Unlock()
+
+	msi.remove(user.Id)
+	user.Password = ""
+	msi.users[user.Id] = user
+	for _, value := range []string{user.FirstName, user.LastName, user.Email} {
+		for _, word := range Tokenize(value) {
+			if msi.postings[word] == nil {
+				msi.postings[word] = make(map[int64]bool)
+			}
+			msi.postings[word][user.Id] = true
+		}
+	}
+	return nil
+}
+
+func (msi *memorySearchIndex) Remove(userId int64) rest_error.RestErr {
+	msi.mu.Lock()
+	defer msi.mu.Unlock()
+
+	msi.remove(userId)
+	return nil
+}
+
+func (msi *memorySearchIndex) remove(userId int64) {
+	user, ok := msi.users[userId]
+	if !ok {
+		return
+	}
+	for _, value := range []string{user.FirstName, user.LastName, user.Email} {
+		for _, word := range Tokenize(value) {
+			delete(msi.postings[word], userId)
+			if len(msi.postings[word]) == 0 {
+				delete(msi.postings, word)
+			}
+		}
+	}
+	delete(msi.users, userId)
+}
@@ -0,0 +1,156 @@
+package users
+
+import (
+	"net/http"
+	"testing"
+)
+
+func newTestSearchIndex(t *testing.T, users ...User) ISearchIndex {
+	index := NewMemorySearchIndex()
+	for _, user := range users {
+		if err := index.Index(user); err != nil {
+			t.Fatalf("indexing user %d: %v", user.Id, err)
+		}
+	}
+	return index
+}
+
+func searchIds(t *testing.T, index ISearchIndex, text string, limit int) []int64 {
+	results, err := index.Search(SearchQuery{Text: text, Limit: limit})
+	if err != nil {
+		t.Fatalf("searching %q: %v", text, err)
+	}
+	ids := make([]int64, 0, len(results))
+	for _, result := range results {
+		ids = append(ids, result.User.Id)
+	}
+	return ids
+}
+
+func equalIds(a []int64, b []int64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestMemorySearchIndexRanksExactPrefixAndFuzzyMatches(t *testing.T) {
+	index := newTestSearchIndex(t,
+		User{Id: 1, FirstName: "Johnathan", LastName: "Smith", Email: "jsmith@mail.com"},
+		User{Id: 2, FirstName: "John", LastName: "Doe", Email: "jdoe@mail.com"},
+		User{Id: 3, FirstName: "Jon", LastName: "Snow", Email: "jon@mail.com"},
+		User{Id: 4, FirstName: "Jane", LastName: "Roe", Email: "jroe@mail.com"},
+	)
+
+	tests := []struct {
+		text string
+		want []int64
+	}{
+		{"john", []int64{2, 1, 3}},
+		{"JOHN doe", []int64{2, 1, 3}},
+		{"snow", []int64{3}},
+		{"smiht", nil},
+		{"smth", []int64{1}},
+		{"nobody", nil},
+	}
+	for _, test := range tests {
+		if got := searchIds(t, index, test.text, 0); !equalIds(got, test.want) {
+			t.Errorf("search %q = %v, want %v", test.text, got, test.want)
+		}
+	}
+}
+
+func TestMemorySearchIndexLimitsResults(t *testing.T) {
+	index := newTestSearchIndex(t,
+		User{Id: 1, FirstName: "Ann", Email: "ann1@mail.com"},
+		User{Id: 2, FirstName: "Ann", Email: "ann2@mail.com"},
+		User{Id: 3, FirstName: "Ann", Email: "ann3@mail.com"},
+	)
+	if got := searchIds(t, index, "ann", 2); !equalIds(got, []int64{1, 2}) {
+		t.Errorf("search with limit 2 = %v, want [1 2]", got)
+	}
+}
+
+func TestMemorySearchIndexReindexAndRemove(t *testing.T) {
+	index := newTestSearchIndex(t, User{Id: 1, FirstName: "Alice", LastName: "Walker", Email: "alice@mail.com"})
+
+	if err := index.Index(User{Id: 1, FirstName: "Alicia", LastName: "Keys", Email: "alicia@mail.com"}); err != nil {
+		t.Fatalf("reindexing user: %v", err)
+	}
+	if got := searchIds(t, index, "walker", 0); len(got) != 0 {
+		t.Errorf("search for a replaced name = %v, want none", got)
+	}
+	if got := searchIds(t, index, "keys", 0); !equalIds(got, []int64{1}) {
+		t.Errorf("search for the new name = %v, want [1]", got)
+	}
+
+	if err := index.Remove(1); err != nil {
+		t.Fatalf("removing user: %v", err)
+	}
+	if got := searchIds(t, index, "alicia", 0); len(got) != 0 {
+		t.Errorf("search after remove = %v, want none", got)
+	}
+	if err := index.Remove(1); err != nil {
+		t.Errorf("removing a missing user: %v", err)
+	}
+}
+
+func TestMemorySearchIndexDoesNotKeepPasswords(t *testing.T) {
+	index := newTestSearchIndex(t, User{Id: 1, FirstName: "Bob", Password: "hash"})
+	results, err := index.Search(SearchQuery{Text: "bob"})
+	if err != nil {
+		t.Fatalf("searching: %v", err)
+	}
+	if len(results) != 1 || results[0].User.Password != "" {
+		t.Errorf("results = %+v, want one user without password", results)
+	}
+}
+
+func TestMemorySearchIndexRejectsEmptyQueries(t *testing.T) {
+	index := newTestSearchIndex(t)
+	for _, text := range []string{"", "   ", "@.-"} {
+		_, err := index.Search(SearchQuery{Text: text})
+		if err == nil || err.Status() != http.StatusBadRequest {
+			t.Errorf("search %q error = %v, want 400", text, err)
+		}
+	}
+}
+
+func TestMemorySearchIndexHighlightsMatches(t *testing.T) {
+	index := newTestSearchIndex(t, User{Id: 1, FirstName: "Johnny", LastName: "Doe", Email: "johnny.doe@mail.com"})
+	results, err := index.Search(SearchQuery{Text: "john doe"})
+	if err != nil || len(results) != 1 {
+		t.Fatalf("search = %v, %v, want one result", results, err)
+	}
+	want := map[string]string{
+		"first_name": "<em>John</em>ny",
+		"last_name":  "<em>Doe</em>",
+		"email":      "<em>john</em>ny.<em>doe</em>@mail.com",
+	}
+	for field, highlighted := range want {
+		if got := results[0].Highlights[field]; got != highlighted {
+			t.Errorf("highlight of %s = %q, want %q", field, got, highlighted)
+		}
+	}
+}
+
+func TestHighlightEscapesUserText(t *testing.T) {
+	tests := []struct {
+		value string
+		terms []string
+		want  string
+	}{
+		{"<script>alert(1)</script>", []string{"script"}, "&lt;<em>script</em>&gt;alert(1)&lt;/<em>script</em>&gt;"},
+		{`Tom "O'Neil" & co`, []string{"nomatch"}, "Tom &#34;O&#39;Neil&#34; &amp; co"},
+	}
+	for _, test := range tests {
+		if got, _ := highlight(test.value, test.terms); got != test.want {
+			t.Errorf("highlight(%q) = %q, want %q", test.value, got, test.want)
+		}
+	}
+}
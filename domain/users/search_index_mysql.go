@@ -0,0 +1,90 @@
+package users
+
+import (
+	"database/sql"
+	"fmt"
+	"github.com/Abacode7/bookstore_utils-go/v2/logger"
+	"github.com/Abacode7/bookstore_utils-go/v2/rest_error"
+	"strings"
+)
+
+/// fullTextSearchQuery matches the words of the query as prefixes through
+/// the FULLTEXT index. It takes a termLikeCondition per word in place of
+/// its %s, as a fallback for the words the index leaves out, shorter
+/// than its minimum token size or stopwords. Deleted users are kept as
+/// tombstones, the index still holds them, so they are filtered out.
+const fullTextSearchQuery = `SELECT id, first_name, last_name, email, date_created, status,
+		MATCH(first_name, last_name, email) AGAINST(? IN BOOLEAN MODE) AS score
+	FROM users
+	WHERE status <> ? AND (MATCH(first_name, last_name, email) AGAINST(? IN BOOLEAN MODE)%s)
+	ORDER BY score DESC, id
+	LIMIT ?;`
+
+/// termLikeCondition matches a word of the query as a prefix of a name
+/// or email
+const termLikeCondition = `
+		OR first_name LIKE ? OR last_name LIKE ? OR email LIKE ?`
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+type mysqlSearchIndex struct {
+	client *sql.DB
+}
+
+/// NewMySQLSearchIndex is a constructor for an ISearchIndex backed by the
+/// FULLTEXT index of the users table
+func NewMySQLSearchIndex(db *sql.DB) ISearchIndex {
+	return &mysqlSearchIndex{db}
+}
+
+func (msi *mysqlSearchIndex) Search(query SearchQuery) (SearchResults, rest_error.RestErr) {
+	if err := query.Validate(); err != nil {
+		return nil, err
+	}
+	terms := Tokenize(query.Text)
+	booleanQuery := strings.Join(terms, "* ") + "*"
+	args := []interface{}{booleanQuery, StatusDeleted, booleanQuery}
+	for _, term := range terms {
+		likePrefix := likeEscaper.Replace(term) + "%"
+		args = append(args, likePrefix, likePrefix, likePrefix)
+	}
+	args = append(args, query.Limit)
+
+	stmt, err := msi.client.Prepare(fmt.Sprintf(fullTextSearchQuery, strings.Repeat(termLikeCondition, len(terms))))
+	if err != nil {
+		logger.Error("error preparing full text search query", err)
+		return nil, rest_error.NewInternalServerError("database error")
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.Query(args...)
+	if err != nil {
+		logger.Error("error executing full text search query", err)
+		return nil, rest_error.NewInternalServerError("database error")
+	}
+	defer rows.Close()
+
+	results := make(SearchResults, 0)
+	for rows.Next() {
+		var result SearchResult
+		user := &result.User
+		if err := rows.Scan(&user.Id, &user.FirstName, &user.LastName, &user.Email, &user.DateCreated, &user.Status, &result.Score); err != nil {
+			logger.Error("error scanning full text search result", err)
+			return nil, rest_error.NewInternalServerError("database error")
+		}
+		_, result.Highlights = scoreUser(result.User, terms)
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+/// Index is a no-op, MySQL maintains the FULLTEXT index itself
+func (msi *mysqlSearchIndex) Index(User) rest_error.RestErr {
+	return nil
+}
+
+/// Remove is a no-op, MySQL maintains the FULLTEXT index itself. Search
+/// leaves out deleted users, whose rows are kept.
+func (msi *mysqlSearchIndex) Remove(int64) rest_error.RestErr {
+	return nil
+}
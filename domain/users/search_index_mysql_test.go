@@ -0,0 +1,150 @@
+package users
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+/// searchDriver is a database/sql driver serving users as the rows of
+/// the full text search query. It records the query and its arguments,
+/// and applies the query's status filter to the rows.
+type searchDriver struct {
+	users []User
+	query string
+	args  []driver.Value
+}
+
+func (sd *searchDriver) Connect(context.Context) (driver.Conn, error) {
+	return &searchConn{sd}, nil
+}
+
+func (sd *searchDriver) Driver() driver.Driver {
+	return sd
+}
+
+func (sd *searchDriver) Open(string) (driver.Conn, error) {
+	return &searchConn{sd}, nil
+}
+
+type searchConn struct {
+	driver *searchDriver
+}
+
+func (sc *searchConn) Prepare(query string) (driver.Stmt, error) {
+	return &searchStmt{driver: sc.driver, query: query}, nil
+}
+
+func (sc *searchConn) Close() error {
+	return nil
+}
+
+func (sc *searchConn) Begin() (driver.Tx, error) {
+	return nil, errors.New("transactions are not supported")
+}
+
+type searchStmt struct {
+	driver *searchDriver
+	query  string
+}
+
+func (ss *searchStmt) Close() error {
+	return nil
+}
+
+func (ss *searchStmt) NumInput() int {
+	return strings.Count(ss.query, "?")
+}
+
+func (ss *searchStmt) Exec([]driver.Value) (driver.Result, error) {
+	return nil, errors.New("exec is not supported")
+}
+
+/// Query serves the users whose status passes the `status <> ?` filter
+/// with the argument bound to its placeholder
+func (ss *searchStmt) Query(args []driver.Value) (driver.Rows, error) {
+	ss.driver.query, ss.driver.args = ss.query, args
+	rows := &searchRows{}
+	filter := strings.Index(ss.query, "status <> ?")
+	for _, user := range ss.driver.users {
+		if filter >= 0 {
+			excluded := args[strings.Count(ss.query[:filter], "?")]
+			if user.Status == excluded {
+				continue
+			}
+		}
+		rows.users = append(rows.users, user)
+	}
+	return rows, nil
+}
+
+type searchRows struct {
+	users []User
+}
+
+func (sr *searchRows) Columns() []string {
+	return []string{"id", "first_name", "last_name", "email", "date_created", "status", "score"}
+}
+
+func (sr *searchRows) Close() error {
+	return nil
+}
+
+func (sr *searchRows) Next(dest []driver.Value) error {
+	if len(sr.users) == 0 {
+		return io.EOF
+	}
+	user := sr.users[0]
+	sr.users = sr.users[1:]
+	copy(dest, []driver.Value{user.Id, user.FirstName, user.LastName, user.Email, user.DateCreated, user.Status, 1.0})
+	return nil
+}
+
+func TestMySQLSearchIndexLeavesOutDeletedUsers(t *testing.T) {
+	searchDriver := &searchDriver{users: []User{
+		{Id: 1, FirstName: "Ann", LastName: "Lee", Email: "ann@mail.com", Status: StatusActive},
+		{Id: 2, FirstName: "Ann", LastName: "Ray", Email: "ann.ray@mail.com", Status: StatusDeleted},
+		{Id: 3, FirstName: "Ann", LastName: "Cole", Email: "ann.cole@mail.com", Status: StatusSuspended},
+	}}
+	db := sql.OpenDB(searchDriver)
+	defer db.Close()
+
+	results, err := NewMySQLSearchIndex(db).Search(SearchQuery{Text: "ann"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []int64
+	for _, result := range results {
+		ids = append(ids, result.User.Id)
+	}
+	if len(ids) != 2 || ids[0] != 1 || ids[1] != 3 {
+		t.Errorf("got users %v, want 1 and 3 without the deleted one", ids)
+	}
+	if !strings.Contains(searchDriver.query, "status <> ? AND (MATCH") {
+		t.Errorf("the match conditions aren't grouped under the status filter:\n%s", searchDriver.query)
+	}
+}
+
+func TestMySQLSearchIndexMatchesEveryWordAsAPrefix(t *testing.T) {
+	searchDriver := &searchDriver{}
+	db := sql.OpenDB(searchDriver)
+	defer db.Close()
+
+	if _, err := NewMySQLSearchIndex(db).Search(SearchQuery{Text: "Al de_la", Limit: 5}); err != nil {
+		t.Fatal(err)
+	}
+	want := []driver.Value{"al* de* la*", StatusDeleted, "al* de* la*",
+		"al%", "al%", "al%", "de%", "de%", "de%", "la%", "la%", "la%", int64(5)}
+	if len(searchDriver.args) != len(want) {
+		t.Fatalf("got args %v, want %v", searchDriver.args, want)
+	}
+	for i := range want {
+		if searchDriver.args[i] != want[i] {
+			t.Errorf("arg %d: got %v, want %v", i, searchDriver.args[i], want[i])
+		}
+	}
+}
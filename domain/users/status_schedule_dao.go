@@ -0,0 +1,227 @@
+package users
+
+import (
+	"database/sql"
+	"fmt"
+	"github.com/Abacode7/bookstore_users-api/utils/date_utils"
+	"github.com/Abacode7/bookstore_utils-go/v2/logger"
+	"github.com/Abacode7/bookstore_utils-go/v2/rest_error"
+	"net/http"
+	"time"
+)
+
+const (
+	scheduleColumns             = `id, user_id, kind, to_status, reason, scheduled_by, due_at, state, date_created, date_processed, last_error`
+	insertScheduledChangeQuery  = `INSERT INTO users_status_schedule (user_id, kind, to_status, reason, scheduled_by, due_at, state, date_created) VALUES (?, ?, ?, ?, ?, ?, ?, ?);`
+	findScheduledChangesQuery   = `SELECT ` + scheduleColumns + ` FROM users_status_schedule WHERE user_id=? ORDER BY id DESC LIMIT ?;`
+	cancelScheduledChangeQuery  = `UPDATE users_status_schedule SET state=?, date_processed=? WHERE id=? AND user_id=? AND state=?;`
+	cancelPendingExpiryQuery    = `UPDATE users_status_schedule SET state=?, date_processed=? WHERE user_id=? AND kind=? AND state=?;`
+	claimDueScheduledQuery      = `SELECT ` + scheduleColumns + ` FROM users_status_schedule WHERE state=? AND due_at <= ? AND (next_attempt_at IS NULL OR next_attempt_at <= ?) ORDER BY due_at, id LIMIT ? FOR UPDATE SKIP LOCKED;`
+	markScheduledChangeQuery    = `UPDATE users_status_schedule SET state=?, date_processed=?, last_error=? WHERE id=?;`
+	deferScheduledChangeQuery   = `UPDATE users_status_schedule SET next_attempt_at=?, last_error=? WHERE id=?;`
+	maxScheduledChangesListSize = 100
+	/// scheduleRetryDelay is how long a change that failed with a server
+	/// error waits before it is claimed again
+	scheduleRetryDelay = 5 * time.Minute
+)
+
+type IStatusScheduleDao interface {
+	Schedule(ScheduledStatusChange) (*ScheduledStatusChange, rest_error.RestErr)
+	SetExpiry(ScheduledStatusChange) (*ScheduledStatusChange, rest_error.RestErr)
+	FindByUser(int64) ([]ScheduledStatusChange, rest_error.RestErr)
+	Cancel(userId int64, changeId int64) rest_error.RestErr
+	CancelExpiry(int64) rest_error.RestErr
+	ProcessDue(limit int, apply func(ScheduledStatusChange) rest_error.RestErr) (int, rest_error.RestErr)
+}
+
+type statusScheduleDao struct {
+	client *sql.DB
+}
+
+/// NewStatusScheduleDao is a constructor for statusScheduleDao
+func NewStatusScheduleDao(db *sql.DB) IStatusScheduleDao {
+	return &statusScheduleDao{db}
+}
+
+/// Schedule stores a pending change
+func (ssd *statusScheduleDao) Schedule(change ScheduledStatusChange) (*ScheduledStatusChange, rest_error.RestErr) {
+	if err := insertScheduledChange(ssd.client, &change); err != nil {
+		return nil, err
+	}
+	return &change, nil
+}
+
+/// SetExpiry replaces the pending expiry of the user with expiry
+func (ssd *statusScheduleDao) SetExpiry(expiry ScheduledStatusChange) (*ScheduledStatusChange, rest_error.RestErr) {
+	tx, err := ssd.client.Begin()
+	if err != nil {
+		logger.Error("error starting set expiry transaction", err)
+		return nil, rest_error.NewInternalServerError("database error")
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(cancelPendingExpiryQuery, ScheduleCancelled, date_utils.GetDbFormattedTime(), expiry.UserId,
+		ScheduleKindExpiry, SchedulePending)
+	if err != nil {
+		logger.Error("error executing cancel pending expiry query", err)
+		return nil, rest_error.NewInternalServerError("database error")
+	}
+	if restErr := insertScheduledChange(tx, &expiry); restErr != nil {
+		return nil, restErr
+	}
+	if err := tx.Commit(); err != nil {
+		logger.Error("error committing set expiry transaction", err)
+		return nil, rest_error.NewInternalServerError("database error")
+	}
+	return &expiry, nil
+}
+
+/// FindByUser gets the latest scheduled changes of a user, whatever
+/// their state
+func (ssd *statusScheduleDao) FindByUser(userId int64) ([]ScheduledStatusChange, rest_error.RestErr) {
+	rows, err := ssd.client.Query(findScheduledChangesQuery, userId, maxScheduledChangesListSize)
+	if err != nil {
+		logger.Error("error executing find scheduled changes query", err)
+		return nil, rest_error.NewInternalServerError("database error")
+	}
+	defer rows.Close()
+	return scanScheduledChanges(rows)
+}
+
+/// Cancel cancels the pending change with changeId of the user
+func (ssd *statusScheduleDao) Cancel(userId int64, changeId int64) rest_error.RestErr {
+	result, err := ssd.client.Exec(cancelScheduledChangeQuery, ScheduleCancelled, date_utils.GetDbFormattedTime(), changeId,
+		userId, SchedulePending)
+	if err != nil {
+		logger.Error("error executing cancel scheduled change query", err)
+		return rest_error.NewInternalServerError("database error")
+	}
+	rowsAff, err := result.RowsAffected()
+	if err != nil {
+		logger.Error("error retrieving rows affected", err)
+		return rest_error.NewInternalServerError("database error")
+	}
+	if rowsAff < 1 {
+		return rest_error.NewNotFoundError("pending scheduled status change not found")
+	}
+	return nil
+}
+
+/// CancelExpiry cancels the pending expiry of the user, if it has one
+func (ssd *statusScheduleDao) CancelExpiry(userId int64) rest_error.RestErr {
+	_, err := ssd.client.Exec(cancelPendingExpiryQuery, ScheduleCancelled, date_utils.GetDbFormattedTime(), userId,
+		ScheduleKindExpiry, SchedulePending)
+	if err != nil {
+		logger.Error("error executing cancel pending expiry query", err)
+		return rest_error.NewInternalServerError("database error")
+	}
+	return nil
+}
+
+/// ProcessDue locks up to limit pending changes that are due and hands
+/// them to apply in order. Applied changes are marked as such, those
+/// apply rejects fail, and those it couldn't apply because of a server
+/// error stay pending, with their error, and are only claimed again
+/// after scheduleRetryDelay. Locked rows are skipped, so
+/// schedulers on several instances don't apply the same change. It
+/// returns how many changes were marked, those left pending aren't
+/// counted so that a caller draining the due changes doesn't claim them
+/// again straight away.
+func (ssd *statusScheduleDao) ProcessDue(limit int, apply func(ScheduledStatusChange) rest_error.RestErr) (int, rest_error.RestErr) {
+	tx, err := ssd.client.Begin()
+	if err != nil {
+		logger.Error("error starting process scheduled changes transaction", err)
+		return 0, rest_error.NewInternalServerError("database error")
+	}
+	defer tx.Rollback()
+
+	now := date_utils.GetDbFormattedTime()
+	rows, err := tx.Query(claimDueScheduledQuery, SchedulePending, now, now, limit)
+	if err != nil {
+		logger.Error("error executing claim scheduled changes query", err)
+		return 0, rest_error.NewInternalServerError("database error")
+	}
+	changes, restErr := scanScheduledChanges(rows)
+	rows.Close()
+	if restErr != nil {
+		return 0, restErr
+	}
+
+	marked := 0
+	for _, change := range changes {
+		state, lastError := ScheduleApplied, sql.NullString{}
+		if applyErr := apply(change); applyErr != nil {
+			if applyErr.Status() >= http.StatusInternalServerError {
+				logger.Error(fmt.Sprintf("error applying scheduled status change %d", change.Id), applyErr)
+				nextAttemptAt := date_utils.FormatDbTime(date_utils.GetTime().Add(scheduleRetryDelay))
+				lastError = sql.NullString{String: truncate(applyErr.Message(), maxStatusReasonLength), Valid: true}
+				if _, err := tx.Exec(deferScheduledChangeQuery, nextAttemptAt, lastError, change.Id); err != nil {
+					logger.Error("error executing defer scheduled change query", err)
+					return 0, rest_error.NewInternalServerError("database error")
+				}
+				continue
+			}
+			state, lastError = ScheduleFailed, sql.NullString{String: truncate(applyErr.Message(), maxStatusReasonLength), Valid: true}
+		}
+		if _, err := tx.Exec(markScheduledChangeQuery, state, date_utils.GetDbFormattedTime(), lastError, change.Id); err != nil {
+			logger.Error("error executing mark scheduled change query", err)
+			return 0, rest_error.NewInternalServerError("database error")
+		}
+		marked++
+	}
+	if err := tx.Commit(); err != nil {
+		logger.Error("error committing process scheduled changes transaction", err)
+		return 0, rest_error.NewInternalServerError("database error")
+	}
+	return marked, nil
+}
+
+/// scheduleExecer is satisfied by both *sql.DB and *sql.Tx
+type scheduleExecer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+func insertScheduledChange(execer scheduleExecer, change *ScheduledStatusChange) rest_error.RestErr {
+	change.State = SchedulePending
+	change.DateCreated = date_utils.GetDbFormattedTime()
+	result, err := execer.Exec(insertScheduledChangeQuery, change.UserId, change.Kind, change.ToStatus, change.Reason,
+		change.ScheduledBy, change.DueAt, change.State, change.DateCreated)
+	if err != nil {
+		logger.Error("error executing insert scheduled change query", err)
+		return rest_error.NewInternalServerError("database error")
+	}
+	if change.Id, err = result.LastInsertId(); err != nil {
+		logger.Error("error retrieving last insert id", err)
+		return rest_error.NewInternalServerError("database error")
+	}
+	return nil
+}
+
+func scanScheduledChanges(rows *sql.Rows) ([]ScheduledStatusChange, rest_error.RestErr) {
+	changes := make([]ScheduledStatusChange, 0)
+	for rows.Next() {
+		var change ScheduledStatusChange
+		var dateProcessed, lastError sql.NullString
+		err := rows.Scan(&change.Id, &change.UserId, &change.Kind, &change.ToStatus, &change.Reason, &change.ScheduledBy,
+			&change.DueAt, &change.State, &change.DateCreated, &dateProcessed, &lastError)
+		if err != nil {
+			logger.Error("error scanning scheduled change", err)
+			return nil, rest_error.NewInternalServerError("database error")
+		}
+		change.DateProcessed = dateProcessed.String
+		change.LastError = lastError.String
+		changes = append(changes, change)
+	}
+	if err := rows.Err(); err != nil {
+		logger.Error("error iterating scheduled changes", err)
+		return nil, rest_error.NewInternalServerError("database error")
+	}
+	return changes, nil
+}
+
+func truncate(value string, length int) string {
+	if len(value) <= length {
+		return value
+	}
+	return value[:length]
+}
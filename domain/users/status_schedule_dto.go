@@ -0,0 +1,81 @@
+package users
+
+import (
+	"fmt"
+	"github.com/Abacode7/bookstore_users-api/utils/error_utils"
+	"github.com/Abacode7/bookstore_utils-go/v2/rest_error"
+	"strings"
+	"time"
+)
+
+/// Kinds of scheduled status changes, a user has at most one pending
+/// expiry
+const (
+	ScheduleKindChange = "change"
+	ScheduleKindExpiry = "expiry"
+)
+
+/// States of scheduled status changes
+const (
+	SchedulePending   = "pending"
+	ScheduleApplied   = "applied"
+	ScheduleFailed    = "failed"
+	ScheduleCancelled = "cancelled"
+)
+
+const (
+	/// ExpiryStatus is the status users move to when they expire, an
+	/// admin can reactivate them
+	ExpiryStatus = StatusDeactivated
+	ExpiryReason = "account expired"
+)
+
+/// ScheduledStatusChange is a status change applied to a user once DueAt
+/// has passed. A change that can't be applied, because the user's status
+/// no longer allows it, fails with LastError.
+type ScheduledStatusChange struct {
+	Id            int64  `json:"id"`
+	UserId        int64  `json:"user_id"`
+	Kind          string `json:"kind"`
+	ToStatus      string `json:"to_status"`
+	Reason        string `json:"reason"`
+	ScheduledBy   int64  `json:"scheduled_by"`
+	DueAt         string `json:"due_at"`
+	State         string `json:"state"`
+	DateCreated   string `json:"date_created"`
+	DateProcessed string `json:"date_processed,omitempty"`
+	LastError     string `json:"last_error,omitempty"`
+}
+
+/// StatusScheduleRequest schedules a change of a user to Status at DueAt
+type StatusScheduleRequest struct {
+	Status string    `json:"status"`
+	Reason string    `json:"reason"`
+	DueAt  time.Time `json:"due_at"`
+}
+
+/// ExpiryRequest sets when a user expires
+type ExpiryRequest struct {
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+func (request *StatusScheduleRequest) Validate(now time.Time) rest_error.RestErr {
+	if err := ValidateStatus(request.Status); err != nil {
+		return err
+	}
+	request.Reason = strings.TrimSpace(request.Reason)
+	if len(request.Reason) > maxStatusReasonLength {
+		return error_utils.NewFieldError("reason", fmt.Sprintf("reason must be at most %d characters", maxStatusReasonLength))
+	}
+	if !request.DueAt.After(now) {
+		return error_utils.NewFieldError("due_at", "due_at must be in the future")
+	}
+	return nil
+}
+
+func (request *ExpiryRequest) Validate(now time.Time) rest_error.RestErr {
+	if !request.ExpiresAt.After(now) {
+		return error_utils.NewFieldError("expires_at", "expires_at must be in the future")
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+package users
+
+import (
+	"fmt"
+	"github.com/Abacode7/bookstore_users-api/utils/error_utils"
+	"github.com/Abacode7/bookstore_utils-go/v2/rest_error"
+	"strings"
+)
+
+/// MaxBatchGetIds is how many users a batch get may ask for at once
+const MaxBatchGetIds = 100
+
+/// BatchGetRequest asks for several users by id
+type BatchGetRequest struct {
+	Ids []int64 `json:"ids"`
+}
+
+/// BatchGetResult is the users found, in the order they were asked for,
+/// and the ids no user was found for
+type BatchGetResult struct {
+	Users   Users
+	Missing []int64
+}
+
+func (request *BatchGetRequest) Validate() rest_error.RestErr {
+	if len(request.Ids) == 0 {
+		return error_utils.NewFieldError("ids", "at least one id is required")
+	}
+	if len(request.Ids) > MaxBatchGetIds {
+		return error_utils.NewFieldError("ids", fmt.Sprintf("at most %d ids are allowed", MaxBatchGetIds))
+	}
+	for _, userId := range request.Ids {
+		if userId <= 0 {
+			return error_utils.NewFieldError("ids", fmt.Sprintf("invalid user id %d", userId))
+		}
+	}
+	return nil
+}
+
+/// Batch operations
+const (
+	BatchOpCreate = "create"
+	BatchOpUpdate = "update"
+	BatchOpDelete = "delete"
+	BatchOpStatus = "status"
+
+	/// MaxBatchOperations is how many operations a batch may hold
+	MaxBatchOperations = 500
+)
+
+/// BatchOperation is one write of a batch. Create takes User, update
+/// takes UserId and the User fields to change, delete takes UserId and
+/// status takes UserId, Status and optionally the Reason for the change.
+type BatchOperation struct {
+	Op     string `json:"op"`
+	UserId int64  `json:"user_id"`
+	User   *User  `json:"user"`
+	Status string `json:"status"`
+	Reason string `json:"reason"`
+}
+
+/// BatchRequest is a list of writes applied in order. An atomic batch
+/// runs in a single transaction and stops at the first failure, other
+/// batches apply every operation on its own.
+type BatchRequest struct {
+	Atomic     bool             `json:"atomic"`
+	Operations []BatchOperation `json:"operations"`
+}
+
+/// BatchOperationResult is the outcome of the operation at Index, User
+/// is the created or updated user and Err is set if the operation failed
+type BatchOperationResult struct {
+	Index int
+	Op    string
+	User  *User
+	Err   rest_error.RestErr
+}
+
+/// Validate checks the shape of the batch, the operations themselves are
+/// validated as they are applied
+func (request *BatchRequest) Validate() rest_error.RestErr {
+	if len(request.Operations) == 0 {
+		return error_utils.NewFieldError("operations", "at least one operation is required")
+	}
+	if len(request.Operations) > MaxBatchOperations {
+		return error_utils.NewFieldError("operations", fmt.Sprintf("at most %d operations are allowed", MaxBatchOperations))
+	}
+	for i, operation := range request.Operations {
+		if err := operation.validate(fmt.Sprintf("operations[%d]", i)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (operation *BatchOperation) validate(field string) rest_error.RestErr {
+	switch operation.Op {
+	case BatchOpCreate:
+		if operation.User == nil {
+			return error_utils.NewFieldError(field+".user", "a user is required")
+		}
+		return nil
+	case BatchOpUpdate, BatchOpDelete, BatchOpStatus:
+	default:
+		return error_utils.NewFieldError(field+".op", "op must be one of create, update, delete, status")
+	}
+	if operation.UserId <= 0 {
+		return error_utils.NewFieldError(field+".user_id", "a user id is required")
+	}
+	if operation.Op == BatchOpUpdate {
+		if operation.User == nil {
+			return error_utils.NewFieldError(field+".user", "a user is required")
+		}
+		/// A new email is only saved once its owner confirms it
+		if operation.User.Email != "" {
+			return error_utils.NewFieldError(field+".user.email", "email changes need the user's confirmation and can't be batched")
+		}
+		if operation.User.Status != "" {
+			return error_utils.NewFieldError(field+".user.status", "statuses are changed with status operations")
+		}
+	}
+	if operation.Op == BatchOpStatus {
+		if strings.TrimSpace(operation.Status) == "" {
+			return error_utils.NewFieldError(field+".status", "a status is required")
+		}
+		if len(operation.Reason) > maxStatusReasonLength {
+			return error_utils.NewFieldError(field+".reason", fmt.Sprintf("reason must be at most %d characters", maxStatusReasonLength))
+		}
+	}
+	return nil
+}
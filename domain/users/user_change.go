@@ -0,0 +1,56 @@
+package users
+
+import (
+	"github.com/Abacode7/bookstore_utils-go/v2/rest_error"
+)
+
+/// UserChange is an entry of the user change feed, User is the private
+/// representation of the user after the change
+type UserChange struct {
+	Id          int64       `json:"id"`
+	Type        string      `json:"type"`
+	UserId      int64       `json:"user_id"`
+	Status      string      `json:"status,omitempty"`
+	User        interface{} `json:"user,omitempty"`
+	DateCreated string      `json:"date_created"`
+}
+
+/// UserChangeFilter selects changes by type and user status, an empty
+/// list selects all
+type UserChangeFilter struct {
+	Types    []string
+	Statuses []string
+}
+
+func (filter *UserChangeFilter) Validate() rest_error.RestErr {
+	for _, changeType := range filter.Types {
+		switch changeType {
+		case EventUserCreated, EventUserUpdated, EventUserDeactivated, EventUserDeleted:
+		default:
+			return rest_error.NewBadRequestError("invalid change type: " + changeType)
+		}
+	}
+	for _, status := range filter.Statuses {
+		if !contains(Statuses, status) {
+			return rest_error.NewBadRequestError("invalid status: " + status)
+		}
+	}
+	return nil
+}
+
+/// Matches reports whether change is selected by filter
+func (filter UserChangeFilter) Matches(change UserChange) bool {
+	return matchesAny(filter.Types, change.Type) && matchesAny(filter.Statuses, change.Status)
+}
+
+func matchesAny(values []string, value string) bool {
+	if len(values) == 0 {
+		return true
+	}
+	for _, candidate := range values {
+		if candidate == value {
+			return true
+		}
+	}
+	return false
+}
@@ -2,8 +2,12 @@ package users
 
 import (
 	"database/sql"
+	"fmt"
+	"github.com/Abacode7/bookstore_users-api/domain/outbox"
 	"github.com/Abacode7/bookstore_utils-go/v2/logger"
 	"github.com/Abacode7/bookstore_utils-go/v2/rest_error"
+	"github.com/go-sql-driver/mysql"
+	"strings"
 )
 
 const (
@@ -11,67 +15,105 @@ const (
 	getUserQuery      = `SELECT id, first_name, last_name, email, date_created, status, password FROM users WHERE id=?;`
 	findByStatusQuery = `SELECT id, first_name, last_name, email, date_created, status FROM users WHERE status=?;`
 	updateUserQuery   = `UPDATE users SET first_name=?, last_name=?, email=?, status=?, password=? WHERE id=?;`
-	deleteUserQuery   = `DELETE FROM users WHERE id=?;`
-	findByEmailQuery  = `SELECT * FROM users WHERE email = ? AND status = ?;`
+	deleteUserQuery   = `DELETE FROM users WHERE id=? AND status=?;`
+	lockStatusQuery   = `SELECT status FROM users WHERE id=? FOR UPDATE;`
+	findByEmailQuery  = `SELECT id, first_name, last_name, email, date_created, status, password FROM users WHERE email=? AND status=?;`
+	emailInUseQuery   = `SELECT EXISTS(SELECT 1 FROM users WHERE email=?);`
+	/// getUsersQuery takes the placeholders of the ids, so it can't be
+	/// prepared once like the others
+	getUsersQuery           = `SELECT id, first_name, last_name, email, date_created, status, password FROM users WHERE id IN (%s);`
+	updateStatusQuery       = `UPDATE users SET status=? WHERE id=?;`
+	insertStatusChangeQuery = `INSERT INTO users_status_history (user_id, from_status, to_status, reason, changed_by, date_created) VALUES (?, ?, ?, ?, ?, ?);`
+	findStatusHistoryQuery  = `SELECT id, user_id, from_status, to_status, reason, changed_by, date_created FROM users_status_history WHERE user_id=? ORDER BY id DESC;`
+
+	mysqlDuplicateEntry = 1062
 )
 
 type IUserDao interface {
 	Save(User) (*User, rest_error.RestErr)
 	Get(int64) (*User, rest_error.RestErr)
+	GetMany([]int64) (Users, rest_error.RestErr)
 	FindByStatus(string) (Users, rest_error.RestErr)
 	Update(User) (*User, rest_error.RestErr)
 	Delete(int64) rest_error.RestErr
 	FindByEmail(string) (*User, rest_error.RestErr)
+	EmailInUse(string) (bool, rest_error.RestErr)
+	RecordLogin(User) rest_error.RestErr
+	ChangeStatus(*StatusChange) (*User, rest_error.RestErr)
+	FindStatusHistory(int64) ([]StatusChange, rest_error.RestErr)
+	Transaction(func(IUserDao) rest_error.RestErr) rest_error.RestErr
 }
 
+/// userDao holds its statements prepared for the lifetime of the
+/// process, database/sql re-prepares them on new pool connections.
+/// Every mutation writes its domain event to the outbox in the same
+/// transaction.
 type userDao struct {
-	client *sql.DB
+	client           *sql.DB
+	events           outbox.IEventDao
+	insertStmt       *sql.Stmt
+	getStmt          *sql.Stmt
+	findByStatusStmt *sql.Stmt
+	updateStmt       *sql.Stmt
+	deleteStmt       *sql.Stmt
+	findByEmailStmt  *sql.Stmt
+	emailInUseStmt   *sql.Stmt
+	lockStatusStmt   *sql.Stmt
+	updateStatusStmt *sql.Stmt
+	insertChangeStmt *sql.Stmt
+	findHistoryStmt  *sql.Stmt
 }
 
-/// NewUserDao is a constructor for userDao
-func NewUserDao(db *sql.DB) IUserDao {
-	return &userDao{db}
+/// NewUserDao is a constructor for userDao, it fails if any of the
+/// queries can't be prepared
+func NewUserDao(db *sql.DB, events outbox.IEventDao) (IUserDao, error) {
+	ud := &userDao{client: db, events: events}
+	stmts := []struct {
+		stmt  **sql.Stmt
+		query string
+	}{
+		{&ud.insertStmt, insertUserQuery},
+		{&ud.getStmt, getUserQuery},
+		{&ud.findByStatusStmt, findByStatusQuery},
+		{&ud.updateStmt, updateUserQuery},
+		{&ud.deleteStmt, deleteUserQuery},
+		{&ud.findByEmailStmt, findByEmailQuery},
+		{&ud.emailInUseStmt, emailInUseQuery},
+		{&ud.lockStatusStmt, lockStatusQuery},
+		{&ud.updateStatusStmt, updateStatusQuery},
+		{&ud.insertChangeStmt, insertStatusChangeQuery},
+		{&ud.findHistoryStmt, findStatusHistoryQuery},
+	}
+	for i, s := range stmts {
+		stmt, err := db.Prepare(s.query)
+		if err != nil {
+			logger.Error("error preparing user query", err)
+			/// Closes the statements prepared so far
+			for _, prepared := range stmts[:i] {
+				(*prepared.stmt).Close()
+			}
+			return nil, err
+		}
+		*s.stmt = stmt
+	}
+	return ud, nil
 }
 
 /// Save stores the user in the database
 func (ud *userDao) Save(user User) (*User, rest_error.RestErr) {
-	stmt, err := ud.client.Prepare(insertUserQuery)
-	if err != nil {
-		logger.Error("error preparing insert query", err)
-		restErr := rest_error.NewInternalServerError("Prepare err: database error")
-		return nil, restErr
-	}
-	defer stmt.Close()
-
-	result, execErr := stmt.Exec(user.FirstName, user.LastName, user.Email, user.DateCreated, user.Status, user.Password)
-	if execErr != nil {
-		logger.Error("error executing prepare query", execErr)
-		restErr := rest_error.NewInternalServerError("Exec error: database error")
-		return nil, restErr
-	}
-	userId, queryErr := result.LastInsertId()
-	if queryErr != nil {
-		logger.Error("error retrieving last insert id", queryErr)
-		restErr := rest_error.NewInternalServerError("Result err: database error")
-		return nil, restErr
-	}
-	user.Id = userId
-
-	return &user, nil
+	var savedUser *User
+	err := ud.Transaction(func(txDao IUserDao) rest_error.RestErr {
+		var err rest_error.RestErr
+		savedUser, err = txDao.Save(user)
+		return err
+	})
+	return savedUser, err
 }
 
 /// Gets a user with id userID
 func (ud *userDao) Get(userID int64) (*User, rest_error.RestErr) {
-	stmt, err := ud.client.Prepare(getUserQuery)
-	if err != nil {
-		logger.Error("error preparing get query", err)
-		sqlErr := rest_error.NewInternalServerError("database error")
-		return nil, sqlErr
-	}
-	defer stmt.Close()
-
 	var user User
-	row := stmt.QueryRow(userID)
+	row := ud.getStmt.QueryRow(userID)
 	rowErr := row.Scan(&user.Id, &user.FirstName, &user.LastName, &user.Email, &user.DateCreated, &user.Status, &user.Password)
 	if rowErr != nil {
 		if rowErr == sql.ErrNoRows {
@@ -83,59 +125,99 @@ func (ud *userDao) Get(userID int64) (*User, rest_error.RestErr) {
 	return &user, nil
 }
 
-/// Update modifies the values of a user with specified id
-func (ud *userDao) Update(user User) (*User, rest_error.RestErr) {
-	stmt, prepErr := ud.client.Prepare(updateUserQuery)
-	if prepErr != nil {
-		logger.Error("error preparing update query", prepErr)
+/// GetMany gets the users with the given ids in a single query, in no
+/// particular order. Ids without a user are left out.
+func (ud *userDao) GetMany(userIds []int64) (Users, rest_error.RestErr) {
+	users := make(Users, 0, len(userIds))
+	if len(userIds) == 0 {
+		return users, nil
+	}
+	args := make([]interface{}, len(userIds))
+	for i, userId := range userIds {
+		args[i] = userId
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(userIds)), ",")
+	rows, queryErr := ud.client.Query(fmt.Sprintf(getUsersQuery, placeholders), args...)
+	if queryErr != nil {
+		logger.Error("error executing get users query", queryErr)
 		return nil, rest_error.NewInternalServerError("database error")
 	}
-	defer stmt.Close()
+	defer rows.Close()
 
-	_, stmtErr := stmt.Exec(user.FirstName, user.LastName, user.Email, user.Status, user.Password, user.Id)
-	if stmtErr != nil {
-		logger.Error("error when trying to update user", stmtErr)
+	for rows.Next() {
+		var user User
+		err := rows.Scan(&user.Id, &user.FirstName, &user.LastName, &user.Email, &user.DateCreated, &user.Status, &user.Password)
+		if err != nil {
+			logger.Error("error scanning user data", err)
+			return nil, rest_error.NewInternalServerError("database error")
+		}
+		users = append(users, user)
+	}
+	if err := rows.Err(); err != nil {
+		logger.Error("error iterating users", err)
 		return nil, rest_error.NewInternalServerError("database error")
 	}
-	return &user, nil
+	return users, nil
 }
 
-/// Delete removes user with userId from the database
+/// Update modifies the values of a user with specified id
+func (ud *userDao) Update(user User) (*User, rest_error.RestErr) {
+	var updatedUser *User
+	err := ud.Transaction(func(txDao IUserDao) rest_error.RestErr {
+		var err rest_error.RestErr
+		updatedUser, err = txDao.Update(user)
+		return err
+	})
+	return updatedUser, err
+}
+
+/// Delete removes the pending user with userId, one who never set up
+/// their account, from the database. Other users are deleted by
+/// ChangeStatus to StatusDeleted, which keeps them as a tombstone with
+/// their status history.
 func (ud *userDao) Delete(userId int64) rest_error.RestErr {
-	stmt, prepErr := ud.client.Prepare(deleteUserQuery)
-	if prepErr != nil {
-		logger.Error("error preparing delete query", prepErr)
-		return rest_error.NewInternalServerError("database error")
-	}
-	defer stmt.Close()
+	return ud.Transaction(func(txDao IUserDao) rest_error.RestErr {
+		return txDao.Delete(userId)
+	})
+}
 
-	result, stmtErr := stmt.Exec(userId)
-	if stmtErr != nil {
-		logger.Error("error executing delete query", stmtErr)
-		return rest_error.NewInternalServerError("database error")
-	}
+/// ChangeStatus moves a user to change.ToStatus if its current status
+/// allows it, recording the change in its status history. change is
+/// completed with the previous status and its id.
+func (ud *userDao) ChangeStatus(change *StatusChange) (*User, rest_error.RestErr) {
+	var user *User
+	err := ud.Transaction(func(txDao IUserDao) rest_error.RestErr {
+		var err rest_error.RestErr
+		user, err = txDao.ChangeStatus(change)
+		return err
+	})
+	return user, err
+}
 
-	rowsAff, err := result.RowsAffected()
+/// Transaction runs fn with an IUserDao whose writes share a single
+/// database transaction, committed if fn succeeds and rolled back
+/// otherwise
+func (ud *userDao) Transaction(fn func(IUserDao) rest_error.RestErr) rest_error.RestErr {
+	tx, err := ud.client.Begin()
 	if err != nil {
-		logger.Error("error retrieving rows affected", err)
+		logger.Error("error starting user transaction", err)
 		return rest_error.NewInternalServerError("database error")
 	}
-	if rowsAff < 1 {
-		return rest_error.NewBadRequestError("user with id doesnt exist")
+	defer tx.Rollback()
+
+	if restErr := fn(&txUserDao{userDao: ud, tx: tx}); restErr != nil {
+		return restErr
+	}
+	if err := tx.Commit(); err != nil {
+		logger.Error("error committing user transaction", err)
+		return rest_error.NewInternalServerError("database error")
 	}
 	return nil
 }
 
 /// FindByStatus gets all users with given status
 func (ud *userDao) FindByStatus(status string) (Users, rest_error.RestErr) {
-	stmt, prepErr := ud.client.Prepare(findByStatusQuery)
-	if prepErr != nil {
-		logger.Error("error preparing findByStatus query", prepErr)
-		return nil, rest_error.NewInternalServerError("database error")
-	}
-	defer stmt.Close()
-
-	rows, stmtErr := stmt.Query(status)
+	rows, stmtErr := ud.findByStatusStmt.Query(status)
 	if stmtErr != nil {
 		logger.Error("error executing findByStatus query", stmtErr)
 		return nil, rest_error.NewInternalServerError("database error")
@@ -147,7 +229,7 @@ func (ud *userDao) FindByStatus(status string) (Users, rest_error.RestErr) {
 		var user User
 		err := rows.Scan(&user.Id, &user.FirstName, &user.LastName, &user.Email, &user.DateCreated, &user.Status)
 		if err != nil {
-			logger.Error("error scanning retrieved data", stmtErr)
+			logger.Error("error scanning retrieved data", err)
 			return nil, rest_error.NewInternalServerError("database error")
 		}
 		users = append(users, user)
@@ -155,15 +237,9 @@ func (ud *userDao) FindByStatus(status string) (Users, rest_error.RestErr) {
 	return users, nil
 }
 
-/// FindByEmailAndPassword gets the user with given email and password
+/// FindByEmail gets the active user with given email
 func (ud *userDao) FindByEmail(email string) (*User, rest_error.RestErr) {
-	stmt, prepErr := ud.client.Prepare(findByEmailQuery)
-	if prepErr != nil {
-		logger.Error("error executing findByEmailAndPassword query", prepErr)
-		err := rest_error.NewInternalServerError("database error")
-		return nil, err
-	}
-	rows := stmt.QueryRow(email, StatusActive)
+	rows := ud.findByEmailStmt.QueryRow(email, StatusActive)
 	var user User
 	err := rows.Scan(&user.Id, &user.FirstName, &user.LastName, &user.Email, &user.DateCreated, &user.Status, &user.Password)
 	if err != nil {
@@ -176,3 +252,66 @@ func (ud *userDao) FindByEmail(email string) (*User, rest_error.RestErr) {
 	}
 	return &user, nil
 }
+
+/// EmailInUse reports whether a user of any status has email, pending
+/// and deleted users keep theirs
+func (ud *userDao) EmailInUse(email string) (bool, rest_error.RestErr) {
+	var inUse bool
+	if err := ud.emailInUseStmt.QueryRow(email).Scan(&inUse); err != nil {
+		logger.Error("error executing email in use query", err)
+		return false, rest_error.NewInternalServerError("database error")
+	}
+	return inUse, nil
+}
+
+/// FindStatusHistory gets the status changes of user with userId, latest
+/// first
+func (ud *userDao) FindStatusHistory(userId int64) ([]StatusChange, rest_error.RestErr) {
+	rows, stmtErr := ud.findHistoryStmt.Query(userId)
+	if stmtErr != nil {
+		logger.Error("error executing find status history query", stmtErr)
+		return nil, rest_error.NewInternalServerError("database error")
+	}
+	defer rows.Close()
+
+	history := make([]StatusChange, 0)
+	for rows.Next() {
+		var change StatusChange
+		err := rows.Scan(&change.Id, &change.UserId, &change.FromStatus, &change.ToStatus, &change.Reason,
+			&change.ChangedBy, &change.DateCreated)
+		if err != nil {
+			logger.Error("error scanning status change", err)
+			return nil, rest_error.NewInternalServerError("database error")
+		}
+		history = append(history, change)
+	}
+	return history, nil
+}
+
+/// RecordLogin writes a login event of user to the outbox
+func (ud *userDao) RecordLogin(user User) rest_error.RestErr {
+	event, restErr := newUserEvent(EventUserLogin, user)
+	if restErr != nil {
+		return restErr
+	}
+	return ud.events.Append(ud.client, *event)
+}
+
+/// writeEvent appends an eventType event about user within tx
+func (ud *userDao) writeEvent(tx *sql.Tx, eventType string, user User) rest_error.RestErr {
+	event, restErr := newUserEvent(eventType, user)
+	if restErr != nil {
+		return restErr
+	}
+	return ud.events.Append(tx, *event)
+}
+
+/// EmailInUseMessage is the message of the conflict returned when
+/// another user already has the email address
+const EmailInUseMessage = "email address is already in use"
+
+/// isDuplicateEntry reports whether err is a unique index violation
+func isDuplicateEntry(err error) bool {
+	mysqlErr, ok := err.(*mysql.MySQLError)
+	return ok && mysqlErr.Number == mysqlDuplicateEntry
+}
@@ -0,0 +1,212 @@
+package users
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+/// countingDriver is a database/sql driver serving a single user row
+/// without a database, counting the statements prepared and closed
+type countingDriver struct {
+	prepares int64
+	closes   int64
+	/// failPrepare fails preparing the queries containing it
+	failPrepare string
+}
+
+func (cd *countingDriver) Connect(context.Context) (driver.Conn, error) {
+	return &countingConn{cd}, nil
+}
+
+func (cd *countingDriver) Driver() driver.Driver {
+	return cd
+}
+
+func (cd *countingDriver) Open(string) (driver.Conn, error) {
+	return &countingConn{cd}, nil
+}
+
+func (cd *countingDriver) openStatements() int64 {
+	return atomic.LoadInt64(&cd.prepares) - atomic.LoadInt64(&cd.closes)
+}
+
+type countingConn struct {
+	driver *countingDriver
+}
+
+func (cc *countingConn) Prepare(query string) (driver.Stmt, error) {
+	if cc.driver.failPrepare != "" && strings.Contains(query, cc.driver.failPrepare) {
+		return nil, errors.New("prepare failed")
+	}
+	atomic.AddInt64(&cc.driver.prepares, 1)
+	return &countingStmt{driver: cc.driver, query: query}, nil
+}
+
+func (cc *countingConn) Close() error {
+	return nil
+}
+
+func (cc *countingConn) Begin() (driver.Tx, error) {
+	return nil, errors.New("transactions are not supported")
+}
+
+type countingStmt struct {
+	driver *countingDriver
+	query  string
+}
+
+func (cs *countingStmt) Close() error {
+	atomic.AddInt64(&cs.driver.closes, 1)
+	return nil
+}
+
+func (cs *countingStmt) NumInput() int {
+	return strings.Count(cs.query, "?")
+}
+
+func (cs *countingStmt) Exec([]driver.Value) (driver.Result, error) {
+	return driver.RowsAffected(1), nil
+}
+
+func (cs *countingStmt) Query([]driver.Value) (driver.Rows, error) {
+	return &userRows{}, nil
+}
+
+/// userRows is the single user row every query returns
+type userRows struct {
+	done bool
+}
+
+func (ur *userRows) Columns() []string {
+	return []string{"id", "first_name", "last_name", "email", "date_created", "status", "password"}
+}
+
+func (ur *userRows) Close() error {
+	return nil
+}
+
+func (ur *userRows) Next(dest []driver.Value) error {
+	if ur.done {
+		return io.EOF
+	}
+	ur.done = true
+	copy(dest, []driver.Value{int64(1), "Ann", "Lee", "ann@mail.com", "2026-01-01 00:00:00", StatusActive, "hash"})
+	return nil
+}
+
+func newCountingDB(countingDriver *countingDriver) *sql.DB {
+	db := sql.OpenDB(countingDriver)
+	/// A single connection prepares each statement once
+	db.SetMaxOpenConns(1)
+	return db
+}
+
+/// getUserPreparingPerCall is Get as it was before the statements were
+/// prepared once, it prepares and closes its statement on every call
+func getUserPreparingPerCall(db *sql.DB, userId int64) (*User, error) {
+	stmt, err := db.Prepare(getUserQuery)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+	var user User
+	err = stmt.QueryRow(userId).Scan(&user.Id, &user.FirstName, &user.LastName, &user.Email, &user.DateCreated,
+		&user.Status, &user.Password)
+	return &user, err
+}
+
+func BenchmarkUserDaoGetPreparingPerCall(b *testing.B) {
+	countingDriver := &countingDriver{}
+	db := newCountingDB(countingDriver)
+	defer db.Close()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := getUserPreparingPerCall(db, 1); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.ReportMetric(float64(atomic.LoadInt64(&countingDriver.prepares))/float64(b.N), "prepares/op")
+}
+
+func BenchmarkUserDaoGet(b *testing.B) {
+	countingDriver := &countingDriver{}
+	db := newCountingDB(countingDriver)
+	defer db.Close()
+	dao, err := NewUserDao(db, nil)
+	if err != nil {
+		b.Fatal(err)
+	}
+	/// The first call prepares the statements on the pool's connection
+	dao.Get(1)
+	prepared := atomic.LoadInt64(&countingDriver.prepares)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := dao.Get(1); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.ReportMetric(float64(atomic.LoadInt64(&countingDriver.prepares)-prepared)/float64(b.N), "prepares/op")
+}
+
+func BenchmarkUserDaoFindByEmail(b *testing.B) {
+	countingDriver := &countingDriver{}
+	db := newCountingDB(countingDriver)
+	defer db.Close()
+	dao, err := NewUserDao(db, nil)
+	if err != nil {
+		b.Fatal(err)
+	}
+	dao.FindByEmail("ann@mail.com")
+	prepared := atomic.LoadInt64(&countingDriver.prepares)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := dao.FindByEmail("ann@mail.com"); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.ReportMetric(float64(atomic.LoadInt64(&countingDriver.prepares)-prepared)/float64(b.N), "prepares/op")
+}
+
+func TestUserDaoPreparesStatementsOnce(t *testing.T) {
+	countingDriver := &countingDriver{}
+	db := newCountingDB(countingDriver)
+	defer db.Close()
+	dao, err := NewUserDao(db, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dao.Get(1)
+	prepared := atomic.LoadInt64(&countingDriver.prepares)
+	for i := 0; i < 10; i++ {
+		if _, err := dao.Get(1); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if prepares := atomic.LoadInt64(&countingDriver.prepares) - prepared; prepares != 0 {
+		t.Errorf("%d statements prepared by 10 reads, want none", prepares)
+	}
+}
+
+func TestNewUserDaoClosesStatementsOnFailure(t *testing.T) {
+	countingDriver := &countingDriver{failPrepare: "users_status_history"}
+	db := newCountingDB(countingDriver)
+	defer db.Close()
+	if _, err := NewUserDao(db, nil); err == nil {
+		t.Fatal("NewUserDao succeeded, want the prepare failure")
+	}
+	if atomic.LoadInt64(&countingDriver.prepares) == 0 {
+		t.Fatal("no statement prepared before the failure")
+	}
+	if open := countingDriver.openStatements(); open != 0 {
+		t.Errorf("%d statements left open", open)
+	}
+}
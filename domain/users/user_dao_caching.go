@@ -0,0 +1,198 @@
+package users
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/Abacode7/bookstore_users-api/datasources/cache"
+	"github.com/Abacode7/bookstore_utils-go/v2/logger"
+	"github.com/Abacode7/bookstore_utils-go/v2/rest_error"
+	"sync"
+	"time"
+)
+
+/// generationStripes is the number of generation counters users are
+/// spread over, users sharing a stripe only skip each other's stores
+const generationStripes = 1024
+
+/// cachingUserDao serves Get from a cache, loading missing users through
+/// the IUserDao it wraps. Writes invalidate the cached user, so status and
+/// email changes are visible on the next read.
+type cachingUserDao struct {
+	IUserDao
+	cache   cache.ICache
+	ttl     time.Duration
+	metrics *cache.Metrics
+	loads   cache.SingleFlight
+	/// generations count the invalidations of the users of each stripe,
+	/// a load only stores its user if none happened while it ran
+	mu          sync.Mutex
+	generations [generationStripes]uint64
+}
+
+type userLoad struct {
+	user *User
+	err  rest_error.RestErr
+}
+
+/// NewCachingUserDao is a constructor for an IUserDao that reads users
+/// through c, keeping them for at most ttl
+func NewCachingUserDao(dao IUserDao, c cache.ICache, ttl time.Duration, metrics *cache.Metrics) IUserDao {
+	return &cachingUserDao{IUserDao: dao, cache: c, ttl: ttl, metrics: metrics}
+}
+
+func (cud *cachingUserDao) Get(userId int64) (*User, rest_error.RestErr) {
+	key := userCacheKey(userId)
+	if user := cud.cached(key); user != nil {
+		cud.metrics.Hit()
+		return user, nil
+	}
+	cud.metrics.Miss()
+
+	/// Concurrent misses share a single database read
+	result := cud.loads.Do(key, func() interface{} {
+		generation := cud.generation(userId)
+		user, err := cud.IUserDao.Get(userId)
+		if err == nil {
+			cud.store(generation, *user)
+		}
+		return userLoad{user, err}
+	}).(userLoad)
+	if result.err != nil {
+		return nil, result.err
+	}
+	/// Each caller gets its own copy, the load is shared
+	user := *result.user
+	return &user, nil
+}
+
+/// GetMany serves the cached users and loads the others with a single
+/// query through the wrapped IUserDao
+func (cud *cachingUserDao) GetMany(userIds []int64) (Users, rest_error.RestErr) {
+	users := make(Users, 0, len(userIds))
+	var missing []int64
+	generations := make(map[int64]uint64)
+	for _, userId := range userIds {
+		if user := cud.cached(userCacheKey(userId)); user != nil {
+			cud.metrics.Hit()
+			users = append(users, *user)
+			continue
+		}
+		cud.metrics.Miss()
+		missing = append(missing, userId)
+		generations[userId] = cud.generation(userId)
+	}
+	if len(missing) == 0 {
+		return users, nil
+	}
+	loaded, err := cud.IUserDao.GetMany(missing)
+	if err != nil {
+		return nil, err
+	}
+	for _, user := range loaded {
+		cud.store(generations[user.Id], user)
+		users = append(users, user)
+	}
+	return users, nil
+}
+
+func (cud *cachingUserDao) Update(user User) (*User, rest_error.RestErr) {
+	/// Invalidating on both sides of the write drops what was cached
+	/// before and during the update, and a load racing it doesn't store
+	/// the old row since the generation changed
+	cud.invalidate(user.Id)
+	updatedUser, err := cud.IUserDao.Update(user)
+	cud.invalidate(user.Id)
+	if err != nil {
+		return nil, err
+	}
+	return updatedUser, nil
+}
+
+func (cud *cachingUserDao) ChangeStatus(change *StatusChange) (*User, rest_error.RestErr) {
+	cud.invalidate(change.UserId)
+	user, err := cud.IUserDao.ChangeStatus(change)
+	cud.invalidate(change.UserId)
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+func (cud *cachingUserDao) Delete(userId int64) rest_error.RestErr {
+	err := cud.IUserDao.Delete(userId)
+	cud.invalidate(userId)
+	return err
+}
+
+/// Transaction invalidates the users written in the transaction once it
+/// is over, whether it committed or not
+func (cud *cachingUserDao) Transaction(fn func(IUserDao) rest_error.RestErr) rest_error.RestErr {
+	writes, err := recordTransaction(cud.IUserDao, fn)
+	for _, write := range writes {
+		cud.invalidate(write.UserId)
+	}
+	return err
+}
+
+/// cached returns nil on a miss, cache failures degrade to a miss
+func (cud *cachingUserDao) cached(key string) *User {
+	value, found, err := cud.cache.Get(key)
+	if err != nil {
+		cud.metrics.Error()
+		logger.Error("error reading user from cache", err)
+		return nil
+	}
+	if !found {
+		return nil
+	}
+	var user User
+	if err := json.Unmarshal(value, &user); err != nil {
+		cud.metrics.Error()
+		logger.Error("error decoding cached user", err)
+		return nil
+	}
+	return &user
+}
+
+/// store caches user, read while the user's stripe was at generation,
+/// unless the user was invalidated since
+func (cud *cachingUserDao) store(generation uint64, user User) {
+	value, err := json.Marshal(user)
+	if err != nil {
+		logger.Error("error encoding user for cache", err)
+		return
+	}
+	cud.mu.Lock()
+	defer cud.mu.Unlock()
+	if cud.generations[generationStripe(user.Id)] != generation {
+		return
+	}
+	if err := cud.cache.Set(userCacheKey(user.Id), value, cud.ttl); err != nil {
+		cud.metrics.Error()
+		logger.Error("error writing user to cache", err)
+	}
+}
+
+func (cud *cachingUserDao) generation(userId int64) uint64 {
+	cud.mu.Lock()
+	defer cud.mu.Unlock()
+	return cud.generations[generationStripe(userId)]
+}
+
+func (cud *cachingUserDao) invalidate(userId int64) {
+	cud.mu.Lock()
+	cud.generations[generationStripe(userId)]++
+	cud.mu.Unlock()
+	if err := cud.cache.Delete(userCacheKey(userId)); err != nil {
+		cud.metrics.Error()
+		logger.Error(fmt.Sprintf("error invalidating cached user %d", userId), err)
+	}
+}
+
+func generationStripe(userId int64) uint64 {
+	return uint64(userId) % generationStripes
+}
+
+func userCacheKey(userId int64) string {
+	return fmt.Sprintf("users:%d", userId)
+}
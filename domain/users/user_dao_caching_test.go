@@ -0,0 +1,146 @@
+package users
+
+import (
+	"github.com/Abacode7/bookstore_users-api/datasources/cache"
+	"github.com/Abacode7/bookstore_utils-go/v2/rest_error"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+)
+
+/// rowUserDao is an IUserDao over a single in-memory row, Get can be
+/// held after it read the row to race it with writes
+type rowUserDao struct {
+	IUserDao
+	mu    sync.Mutex
+	row   User
+	gets  int
+	read  chan struct{}
+	delay chan struct{}
+}
+
+func (rud *rowUserDao) Get(userId int64) (*User, rest_error.RestErr) {
+	rud.mu.Lock()
+	user := rud.row
+	rud.gets++
+	read, delay := rud.read, rud.delay
+	rud.mu.Unlock()
+	if delay != nil {
+		read <- struct{}{}
+		<-delay
+	}
+	return &user, nil
+}
+
+func (rud *rowUserDao) Update(user User) (*User, rest_error.RestErr) {
+	rud.mu.Lock()
+	defer rud.mu.Unlock()
+	rud.row = user
+	return &user, nil
+}
+
+func (rud *rowUserDao) holdGets() {
+	rud.mu.Lock()
+	defer rud.mu.Unlock()
+	rud.read = make(chan struct{})
+	rud.delay = make(chan struct{})
+}
+
+func (rud *rowUserDao) releaseGets() {
+	rud.mu.Lock()
+	delay := rud.delay
+	rud.read, rud.delay = nil, nil
+	rud.mu.Unlock()
+	close(delay)
+}
+
+func newTestCachingUserDao(row User) (IUserDao, *rowUserDao, *cache.Metrics) {
+	dao := &rowUserDao{row: row}
+	metrics := &cache.Metrics{}
+	return NewCachingUserDao(dao, cache.NewLRUCache(10), time.Hour, metrics), dao, metrics
+}
+
+func TestCachingUserDaoServesRepeatedReadsFromCache(t *testing.T) {
+	cachingDao, dao, metrics := newTestCachingUserDao(User{Id: 1, FirstName: "Ann"})
+	for i := 0; i < 3; i++ {
+		user, err := cachingDao.Get(1)
+		if err != nil || user.FirstName != "Ann" {
+			t.Fatalf("get = %v, %v", user, err)
+		}
+	}
+	if dao.gets != 1 {
+		t.Errorf("%d database reads, want 1", dao.gets)
+	}
+	if snapshot := metrics.Snapshot(); snapshot.Hits != 2 || snapshot.Misses != 1 {
+		t.Errorf("metrics = %+v, want 2 hits and 1 miss", snapshot)
+	}
+}
+
+func TestCachingUserDaoUpdateInvalidates(t *testing.T) {
+	cachingDao, _, _ := newTestCachingUserDao(User{Id: 1, FirstName: "Ann"})
+	cachingDao.Get(1)
+	if _, err := cachingDao.Update(User{Id: 1, FirstName: "Bea"}); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	if user, _ := cachingDao.Get(1); user.FirstName != "Bea" {
+		t.Errorf("get after update = %q, want Bea", user.FirstName)
+	}
+}
+
+/// A load that read the row before an update committed must not cache
+/// it once the update invalidated the user
+func TestCachingUserDaoLoadRacingUpdateIsNotCached(t *testing.T) {
+	cachingDao, dao, _ := newTestCachingUserDao(User{Id: 1, FirstName: "Ann"})
+	dao.holdGets()
+	loaded := make(chan *User)
+	go func() {
+		user, _ := cachingDao.Get(1)
+		loaded <- user
+	}()
+	<-dao.read
+	if _, err := cachingDao.Update(User{Id: 1, FirstName: "Bea"}); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	dao.releaseGets()
+	if user := <-loaded; user.FirstName != "Ann" {
+		t.Fatalf("racing load = %q, want the row it read", user.FirstName)
+	}
+
+	if user, _ := cachingDao.Get(1); user.FirstName != "Bea" {
+		t.Errorf("get after the racing load = %q, want Bea", user.FirstName)
+	}
+	if dao.gets != 2 {
+		t.Errorf("%d database reads, want the racing load left uncached", dao.gets)
+	}
+}
+
+func TestCachingUserDaoSharesConcurrentMisses(t *testing.T) {
+	cachingDao, dao, _ := newTestCachingUserDao(User{Id: 1, FirstName: "Ann"})
+	dao.holdGets()
+	const readers = 5
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		cachingDao.Get(1)
+	}()
+	<-dao.read
+	for i := 1; i < readers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if user, err := cachingDao.Get(1); err != nil || user.FirstName != "Ann" {
+				t.Errorf("get = %v, %v", user, err)
+			}
+		}()
+	}
+	for loads := &cachingDao.(*cachingUserDao).loads; loads.Waiters(userCacheKey(1)) < readers-1; {
+		runtime.Gosched()
+	}
+	dao.releaseGets()
+	wg.Wait()
+	if dao.gets != 1 {
+		t.Errorf("%d database reads for %d concurrent misses, want 1", dao.gets, readers)
+	}
+}
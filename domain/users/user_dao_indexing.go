@@ -0,0 +1,87 @@
+package users
+
+import (
+	"github.com/Abacode7/bookstore_utils-go/v2/logger"
+	"github.com/Abacode7/bookstore_utils-go/v2/rest_error"
+)
+
+/// indexingUserDao keeps a search index up to date with the users
+/// stored through the IUserDao it wraps
+type indexingUserDao struct {
+	IUserDao
+	index ISearchIndex
+}
+
+/// NewIndexingUserDao is a constructor for an IUserDao that updates index
+/// after every successful write of dao
+func NewIndexingUserDao(dao IUserDao, index ISearchIndex) IUserDao {
+	return &indexingUserDao{IUserDao: dao, index: index}
+}
+
+func (iud *indexingUserDao) Save(user User) (*User, rest_error.RestErr) {
+	savedUser, err := iud.IUserDao.Save(user)
+	if err != nil {
+		return nil, err
+	}
+	iud.reindex(*savedUser)
+	return savedUser, nil
+}
+
+func (iud *indexingUserDao) Update(user User) (*User, rest_error.RestErr) {
+	updatedUser, err := iud.IUserDao.Update(user)
+	if err != nil {
+		return nil, err
+	}
+	iud.reindex(*updatedUser)
+	return updatedUser, nil
+}
+
+func (iud *indexingUserDao) ChangeStatus(change *StatusChange) (*User, rest_error.RestErr) {
+	user, err := iud.IUserDao.ChangeStatus(change)
+	if err != nil {
+		return nil, err
+	}
+	iud.reindex(*user)
+	return user, nil
+}
+
+func (iud *indexingUserDao) Delete(userId int64) rest_error.RestErr {
+	if err := iud.IUserDao.Delete(userId); err != nil {
+		return err
+	}
+	if err := iud.index.Remove(userId); err != nil {
+		logger.Error("error removing user from search index", err)
+	}
+	return nil
+}
+
+/// Transaction updates index with the writes of the transaction once it
+/// has committed
+func (iud *indexingUserDao) Transaction(fn func(IUserDao) rest_error.RestErr) rest_error.RestErr {
+	writes, err := recordTransaction(iud.IUserDao, fn)
+	if err != nil {
+		return err
+	}
+	for _, write := range writes {
+		if write.User != nil {
+			iud.reindex(*write.User)
+		} else if err := iud.index.Remove(write.UserId); err != nil {
+			logger.Error("error removing user from search index", err)
+		}
+	}
+	return nil
+}
+
+/// reindex logs rather than returns failures, the write itself succeeded.
+/// Deleted users are kept as a tombstone but aren't searchable.
+func (iud *indexingUserDao) reindex(user User) {
+	if user.Status == StatusDeleted {
+		if err := iud.index.Remove(user.Id); err != nil {
+			logger.Error("error removing user from search index", err)
+		}
+		return
+	}
+	if err := iud.index.Index(user); err != nil {
+		logger.Error("error indexing user", err)
+	}
+}
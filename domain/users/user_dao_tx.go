@@ -0,0 +1,267 @@
+package users
+
+import (
+	"database/sql"
+	"github.com/Abacode7/bookstore_users-api/utils/date_utils"
+	"github.com/Abacode7/bookstore_users-api/utils/error_utils"
+	"github.com/Abacode7/bookstore_utils-go/v2/logger"
+	"github.com/Abacode7/bookstore_utils-go/v2/rest_error"
+)
+
+/// txUserDao is the IUserDao of a transaction started by
+/// userDao.Transaction. Writes and Get go through the transaction, so
+/// later operations see earlier ones.
+type txUserDao struct {
+	*userDao
+	tx *sql.Tx
+}
+
+/// Save stores the user in the database
+func (td *txUserDao) Save(user User) (*User, rest_error.RestErr) {
+	result, execErr := td.tx.Stmt(td.insertStmt).Exec(user.FirstName, user.LastName, user.Email, user.DateCreated, user.Status, user.Password)
+	if isDuplicateEntry(execErr) {
+		return nil, error_utils.NewConflictError(EmailInUseMessage)
+	}
+	if execErr != nil {
+		logger.Error("error executing prepare query", execErr)
+		restErr := rest_error.NewInternalServerError("database error")
+		return nil, restErr
+	}
+	userId, queryErr := result.LastInsertId()
+	if queryErr != nil {
+		logger.Error("error retrieving last insert id", queryErr)
+		restErr := rest_error.NewInternalServerError("database error")
+		return nil, restErr
+	}
+	user.Id = userId
+
+	if restErr := td.writeEvent(td.tx, EventUserCreated, user); restErr != nil {
+		return nil, restErr
+	}
+	return &user, nil
+}
+
+/// Update modifies the values of a user with specified id. A change of
+/// status has to be an allowed transition and is recorded in the user's
+/// status history.
+func (td *txUserDao) Update(user User) (*User, rest_error.RestErr) {
+	oldStatus, restErr := td.lockStatus(user.Id)
+	if restErr != nil {
+		return nil, restErr
+	}
+	if user.Status != oldStatus {
+		if err := ValidateTransition(oldStatus, user.Status); err != nil {
+			return nil, err
+		}
+	}
+
+	_, stmtErr := td.tx.Stmt(td.updateStmt).Exec(user.FirstName, user.LastName, user.Email, user.Status, user.Password, user.Id)
+	if isDuplicateEntry(stmtErr) {
+		return nil, error_utils.NewConflictError(EmailInUseMessage)
+	}
+	if stmtErr != nil {
+		logger.Error("error when trying to update user", stmtErr)
+		return nil, rest_error.NewInternalServerError("database error")
+	}
+
+	if user.Status != oldStatus {
+		change := StatusChange{UserId: user.Id, FromStatus: oldStatus, ToStatus: user.Status}
+		if restErr := td.recordStatusChange(&change); restErr != nil {
+			return nil, restErr
+		}
+	}
+	if restErr := td.writeUpdateEvents(oldStatus, user); restErr != nil {
+		return nil, restErr
+	}
+	return &user, nil
+}
+
+/// ChangeStatus moves a user to change.ToStatus within the transaction
+func (td *txUserDao) ChangeStatus(change *StatusChange) (*User, rest_error.RestErr) {
+	oldStatus, restErr := td.lockStatus(change.UserId)
+	if restErr != nil {
+		return nil, restErr
+	}
+	if err := ValidateTransition(oldStatus, change.ToStatus); err != nil {
+		return nil, err
+	}
+	if _, err := td.tx.Stmt(td.updateStatusStmt).Exec(change.ToStatus, change.UserId); err != nil {
+		logger.Error("error updating user status", err)
+		return nil, rest_error.NewInternalServerError("database error")
+	}
+	change.FromStatus = oldStatus
+	if restErr := td.recordStatusChange(change); restErr != nil {
+		return nil, restErr
+	}
+
+	user, restErr := td.Get(change.UserId)
+	if restErr != nil {
+		return nil, restErr
+	}
+	if restErr := td.writeUpdateEvents(oldStatus, *user); restErr != nil {
+		return nil, restErr
+	}
+	return user, nil
+}
+
+/// Delete removes the pending user with userId within the transaction
+func (td *txUserDao) Delete(userId int64) rest_error.RestErr {
+	result, stmtErr := td.tx.Stmt(td.deleteStmt).Exec(userId, StatusPending)
+	if stmtErr != nil {
+		logger.Error("error executing delete query", stmtErr)
+		return rest_error.NewInternalServerError("database error")
+	}
+
+	rowsAff, err := result.RowsAffected()
+	if err != nil {
+		logger.Error("error retrieving rows affected", err)
+		return rest_error.NewInternalServerError("database error")
+	}
+	if rowsAff < 1 {
+		return error_utils.NewConflictError("only a pending user can be removed, other users are deleted by status")
+	}
+
+	return td.writeDeletedEvent(userId)
+}
+
+/// writeDeletedEvent writes the EventUserDeleted of user with userId
+func (td *txUserDao) writeDeletedEvent(userId int64) rest_error.RestErr {
+	event, restErr := newEvent(EventUserDeleted, userId, deletedUser{Id: userId})
+	if restErr != nil {
+		return restErr
+	}
+	return td.events.Append(td.tx, *event)
+}
+
+/// Get reads the user with userId within the transaction
+func (td *txUserDao) Get(userId int64) (*User, rest_error.RestErr) {
+	var user User
+	row := td.tx.Stmt(td.getStmt).QueryRow(userId)
+	rowErr := row.Scan(&user.Id, &user.FirstName, &user.LastName, &user.Email, &user.DateCreated, &user.Status, &user.Password)
+	if rowErr != nil {
+		if rowErr == sql.ErrNoRows {
+			return nil, rest_error.NewNotFoundError("invalid user id: user not found")
+		}
+		logger.Error("error scanning user data", rowErr)
+		return nil, rest_error.NewInternalServerError("database error")
+	}
+	return &user, nil
+}
+
+/// lockStatus reads the status of the user with userId, locking its row
+/// until the transaction ends
+func (td *txUserDao) lockStatus(userId int64) (string, rest_error.RestErr) {
+	var status string
+	if err := td.tx.Stmt(td.lockStatusStmt).QueryRow(userId).Scan(&status); err != nil {
+		if err == sql.ErrNoRows {
+			return "", rest_error.NewNotFoundError("invalid user id: user not found")
+		}
+		logger.Error("error scanning user status", err)
+		return "", rest_error.NewInternalServerError("database error")
+	}
+	return status, nil
+}
+
+/// recordStatusChange adds change to its user's status history
+func (td *txUserDao) recordStatusChange(change *StatusChange) rest_error.RestErr {
+	if change.DateCreated == "" {
+		change.DateCreated = date_utils.GetDbFormattedTime()
+	}
+	result, err := td.tx.Stmt(td.insertChangeStmt).Exec(change.UserId, change.FromStatus, change.ToStatus, change.Reason,
+		change.ChangedBy, change.DateCreated)
+	if err != nil {
+		logger.Error("error recording status change", err)
+		return rest_error.NewInternalServerError("database error")
+	}
+	if change.Id, err = result.LastInsertId(); err != nil {
+		logger.Error("error retrieving last insert id", err)
+		return rest_error.NewInternalServerError("database error")
+	}
+	return nil
+}
+
+/// writeUpdateEvents appends the update event of user, and its
+/// deactivation event if its status was oldStatus before
+func (td *txUserDao) writeUpdateEvents(oldStatus string, user User) rest_error.RestErr {
+	if restErr := td.writeEvent(td.tx, EventUserUpdated, user); restErr != nil {
+		return restErr
+	}
+	if user.Status == StatusDeactivated && oldStatus != StatusDeactivated {
+		return td.writeEvent(td.tx, EventUserDeactivated, user)
+	}
+	if user.Status == StatusDeleted && oldStatus != StatusDeleted {
+		return td.writeDeletedEvent(user.Id)
+	}
+	return nil
+}
+
+/// Transaction runs fn within the transaction already in progress
+func (td *txUserDao) Transaction(fn func(IUserDao) rest_error.RestErr) rest_error.RestErr {
+	return fn(td)
+}
+
+/// userWrite is a user saved or updated through a recordingUserDao, or
+/// the id of a user deleted through it, User is then nil
+type userWrite struct {
+	UserId int64
+	User   *User
+}
+
+/// recordingUserDao records the successful writes made through the
+/// IUserDao it wraps, so that decorators can act on the writes of a
+/// transaction once it is over
+type recordingUserDao struct {
+	IUserDao
+	writes *[]userWrite
+}
+
+func (rud *recordingUserDao) Save(user User) (*User, rest_error.RestErr) {
+	savedUser, err := rud.IUserDao.Save(user)
+	if err != nil {
+		return nil, err
+	}
+	*rud.writes = append(*rud.writes, userWrite{UserId: savedUser.Id, User: savedUser})
+	return savedUser, nil
+}
+
+func (rud *recordingUserDao) Update(user User) (*User, rest_error.RestErr) {
+	updatedUser, err := rud.IUserDao.Update(user)
+	if err != nil {
+		return nil, err
+	}
+	*rud.writes = append(*rud.writes, userWrite{UserId: updatedUser.Id, User: updatedUser})
+	return updatedUser, nil
+}
+
+func (rud *recordingUserDao) ChangeStatus(change *StatusChange) (*User, rest_error.RestErr) {
+	user, err := rud.IUserDao.ChangeStatus(change)
+	if err != nil {
+		return nil, err
+	}
+	*rud.writes = append(*rud.writes, userWrite{UserId: user.Id, User: user})
+	return user, nil
+}
+
+func (rud *recordingUserDao) Delete(userId int64) rest_error.RestErr {
+	if err := rud.IUserDao.Delete(userId); err != nil {
+		return err
+	}
+	*rud.writes = append(*rud.writes, userWrite{UserId: userId})
+	return nil
+}
+
+func (rud *recordingUserDao) Transaction(fn func(IUserDao) rest_error.RestErr) rest_error.RestErr {
+	return rud.IUserDao.Transaction(func(txDao IUserDao) rest_error.RestErr {
+		return fn(&recordingUserDao{IUserDao: txDao, writes: rud.writes})
+	})
+}
+
+/// recordTransaction runs fn in a transaction of dao, returning the
+/// writes it made along with the transaction's error
+func recordTransaction(dao IUserDao, fn func(IUserDao) rest_error.RestErr) ([]userWrite, rest_error.RestErr) {
+	var writes []userWrite
+	err := dao.Transaction(func(txDao IUserDao) rest_error.RestErr {
+		return fn(&recordingUserDao{IUserDao: txDao, writes: &writes})
+	})
+	return writes, err
+}
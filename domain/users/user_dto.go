@@ -1,32 +1,38 @@
 package users
 
 import (
+	"github.com/Abacode7/bookstore_users-api/utils/error_utils"
 	"github.com/Abacode7/bookstore_utils-go/v2/rest_error"
 )
 
-const (
-	StatusActive   = "active"
-	StatusInactive = "inactive"
-)
-
 type Users []User
 
 type User struct {
-	Id          int64  `json:"id"`
-	FirstName   string `json:"first_name"`
-	LastName    string `json:"last_name"`
-	Email       string `json:"email"`
-	DateCreated string `json:"date_created"`
-	Status      string `json:"status"`
-	Password    string `json:"password"`
+	Id          int64    `json:"id"`
+	FirstName   string   `json:"first_name"`
+	LastName    string   `json:"last_name"`
+	Email       string   `json:"email"`
+	DateCreated string   `json:"date_created"`
+	Status      string   `json:"status"`
+	Password    string   `json:"password"`
+	Profile     *Profile `json:"profile,omitempty"`
 }
 
 func (user *User) Validate() rest_error.RestErr {
 	if user.Email == "" {
-		return rest_error.NewBadRequestError("invalid email address")
+		return error_utils.NewFieldError("email", "invalid email address")
 	}
 	if user.Password == "" {
-		return rest_error.NewBadRequestError("invalid password")
+		return error_utils.NewFieldError("password", "invalid password")
 	}
 	return nil
 }
+
+/// ValidateUpdate checks the fields of an updated user, the password is
+/// validated separately as updates may keep the stored one
+func (user *User) ValidateUpdate() rest_error.RestErr {
+	if user.Email == "" {
+		return error_utils.NewFieldError("email", "invalid email address")
+	}
+	return ValidateStatus(user.Status)
+}
@@ -0,0 +1,40 @@
+package users
+
+import (
+	"github.com/Abacode7/bookstore_users-api/domain/outbox"
+	"github.com/Abacode7/bookstore_utils-go/v2/logger"
+	"github.com/Abacode7/bookstore_utils-go/v2/rest_error"
+)
+
+/// User domain events, written to the outbox with every change and login
+const (
+	EventUserCreated     = "user.created"
+	EventUserUpdated     = "user.updated"
+	EventUserDeactivated = "user.deactivated"
+	EventUserDeleted     = "user.deleted"
+	EventUserLogin       = "user.login"
+)
+
+/// deletedUser is the payload of EventUserDeleted
+type deletedUser struct {
+	Id int64 `json:"id"`
+}
+
+/// newUserEvent builds an event carrying the private, password free,
+/// representation of user
+func newUserEvent(eventType string, user User) (*outbox.Event, rest_error.RestErr) {
+	payload, restErr := user.Marshall(false)
+	if restErr != nil {
+		return nil, restErr
+	}
+	return newEvent(eventType, user.Id, payload)
+}
+
+func newEvent(eventType string, userId int64, payload interface{}) (*outbox.Event, rest_error.RestErr) {
+	event, err := outbox.NewEvent(eventType, userId, payload)
+	if err != nil {
+		logger.Error("error encoding user event", err)
+		return nil, rest_error.NewInternalServerError("error encoding user event")
+	}
+	return event, nil
+}
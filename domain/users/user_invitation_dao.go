@@ -0,0 +1,101 @@
+package users
+
+import (
+	"database/sql"
+	"github.com/Abacode7/bookstore_utils-go/v2/logger"
+	"github.com/Abacode7/bookstore_utils-go/v2/rest_error"
+)
+
+const (
+	userInvitationColumns          = `id, user_id, invited_by, token_hash, status, date_created, expires_at`
+	insertUserInvitationQuery      = `INSERT INTO users_invitations (user_id, invited_by, token_hash, status, date_created, expires_at) VALUES (?, ?, ?, ?, ?, ?);`
+	getUserInvitationQuery         = `SELECT ` + userInvitationColumns + ` FROM users_invitations WHERE id=?;`
+	findUserInvitationByTokenQuery = `SELECT ` + userInvitationColumns + ` FROM users_invitations WHERE token_hash=?;`
+	updateUserInvitationQuery      = `UPDATE users_invitations SET token_hash=?, status=?, expires_at=? WHERE id=?;`
+)
+
+type IUserInvitationDao interface {
+	Save(UserInvitation) (*UserInvitation, rest_error.RestErr)
+	Get(int64) (*UserInvitation, rest_error.RestErr)
+	FindByToken(string) (*UserInvitation, rest_error.RestErr)
+	Update(UserInvitation) rest_error.RestErr
+}
+
+type userInvitationDao struct {
+	client *sql.DB
+}
+
+/// NewUserInvitationDao is a constructor for userInvitationDao
+func NewUserInvitationDao(db *sql.DB) IUserInvitationDao {
+	return &userInvitationDao{db}
+}
+
+/// Save stores a user invitation
+func (uid *userInvitationDao) Save(invitation UserInvitation) (*UserInvitation, rest_error.RestErr) {
+	stmt, err := uid.client.Prepare(insertUserInvitationQuery)
+	if err != nil {
+		logger.Error("error preparing insert user invitation query", err)
+		return nil, rest_error.NewInternalServerError("database error")
+	}
+	defer stmt.Close()
+
+	result, err := stmt.Exec(invitation.UserId, invitation.InvitedBy, invitation.TokenHash,
+		invitation.Status, invitation.DateCreated, invitation.ExpiresAt)
+	if err != nil {
+		logger.Error("error executing insert user invitation query", err)
+		return nil, rest_error.NewInternalServerError("database error")
+	}
+	invitation.Id, err = result.LastInsertId()
+	if err != nil {
+		logger.Error("error retrieving last insert id", err)
+		return nil, rest_error.NewInternalServerError("database error")
+	}
+	return &invitation, nil
+}
+
+/// Get gets the user invitation with invitationId
+func (uid *userInvitationDao) Get(invitationId int64) (*UserInvitation, rest_error.RestErr) {
+	return uid.findOne(getUserInvitationQuery, invitationId)
+}
+
+/// FindByToken gets the user invitation with the given token hash
+func (uid *userInvitationDao) FindByToken(tokenHash string) (*UserInvitation, rest_error.RestErr) {
+	return uid.findOne(findUserInvitationByTokenQuery, tokenHash)
+}
+
+func (uid *userInvitationDao) findOne(query string, arg interface{}) (*UserInvitation, rest_error.RestErr) {
+	stmt, err := uid.client.Prepare(query)
+	if err != nil {
+		logger.Error("error preparing find user invitation query", err)
+		return nil, rest_error.NewInternalServerError("database error")
+	}
+	defer stmt.Close()
+
+	var invitation UserInvitation
+	err = stmt.QueryRow(arg).Scan(&invitation.Id, &invitation.UserId, &invitation.InvitedBy, &invitation.TokenHash,
+		&invitation.Status, &invitation.DateCreated, &invitation.ExpiresAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, rest_error.NewNotFoundError("invitation not found")
+		}
+		logger.Error("error scanning user invitation", err)
+		return nil, rest_error.NewInternalServerError("database error")
+	}
+	return &invitation, nil
+}
+
+/// Update modifies the token, status and expiry of a user invitation
+func (uid *userInvitationDao) Update(invitation UserInvitation) rest_error.RestErr {
+	stmt, err := uid.client.Prepare(updateUserInvitationQuery)
+	if err != nil {
+		logger.Error("error preparing update user invitation query", err)
+		return rest_error.NewInternalServerError("database error")
+	}
+	defer stmt.Close()
+
+	if _, err := stmt.Exec(invitation.TokenHash, invitation.Status, invitation.ExpiresAt, invitation.Id); err != nil {
+		logger.Error("error executing update user invitation query", err)
+		return rest_error.NewInternalServerError("database error")
+	}
+	return nil
+}
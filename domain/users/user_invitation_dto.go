@@ -0,0 +1,59 @@
+package users
+
+import (
+	"github.com/Abacode7/bookstore_users-api/utils/error_utils"
+	"github.com/Abacode7/bookstore_utils-go/v2/rest_error"
+	"strings"
+)
+
+const (
+	InvitationPending  = "pending"
+	InvitationAccepted = "accepted"
+	InvitationRevoked  = "revoked"
+)
+
+/// UserInvitation is an admin's invitation for an invited user to set up
+/// their account
+type UserInvitation struct {
+	Id          int64  `json:"id"`
+	UserId      int64  `json:"user_id"`
+	InvitedBy   int64  `json:"invited_by"`
+	TokenHash   string `json:"-"`
+	Status      string `json:"status"`
+	DateCreated string `json:"date_created"`
+	ExpiresAt   string `json:"expires_at"`
+}
+
+/// UserInvitationRequest is an admin's request to invite a user
+type UserInvitationRequest struct {
+	Email     string `json:"email"`
+	FirstName string `json:"first_name"`
+	LastName  string `json:"last_name"`
+}
+
+/// AcceptInvitationRequest completes an invited user's account
+type AcceptInvitationRequest struct {
+	Token     string `json:"token"`
+	Password  string `json:"password"`
+	FirstName string `json:"first_name"`
+	LastName  string `json:"last_name"`
+}
+
+func (request *UserInvitationRequest) Validate() rest_error.RestErr {
+	request.Email = strings.TrimSpace(request.Email)
+	if request.Email == "" {
+		return error_utils.NewFieldError("email", "invalid email address")
+	}
+	return nil
+}
+
+func (request *AcceptInvitationRequest) Validate() rest_error.RestErr {
+	request.Token = strings.TrimSpace(request.Token)
+	if request.Token == "" {
+		return error_utils.NewFieldError("token", "invalid token")
+	}
+	if request.Password == "" {
+		return error_utils.NewFieldError("password", "invalid password")
+	}
+	return nil
+}
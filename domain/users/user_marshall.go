@@ -11,15 +11,17 @@ type PublicUser struct {
 	FirstName string `json:"first_name"`
 	LastName  string `json:"last_name"`
 	//Email       string `json:"email"`
+	Profile *PublicProfile `json:"profile,omitempty"`
 }
 
 type PrivateUser struct {
-	Id          int64  `json:"id"`
-	FirstName   string `json:"first_name"`
-	LastName    string `json:"last_name"`
-	Email       string `json:"email"`
-	DateCreated string `json:"date_created"`
-	Status      string `json:"status"`
+	Id          int64    `json:"id"`
+	FirstName   string   `json:"first_name"`
+	LastName    string   `json:"last_name"`
+	Email       string   `json:"email"`
+	DateCreated string   `json:"date_created"`
+	Status      string   `json:"status"`
+	Profile     *Profile `json:"profile,omitempty"`
 }
 
 func (user *User) Marshall(isPublic bool) (interface{}, rest_error.RestErr) {
@@ -34,6 +36,7 @@ func (user *User) Marshall(isPublic bool) (interface{}, rest_error.RestErr) {
 			logger.Error("error while formatting user data", err)
 			return nil, rest_error.NewInternalServerError("error while formatting user data")
 		}
+		publicUser.Profile = user.publicProfile()
 		return publicUser, nil
 	}
 	var privateUser PrivateUser
@@ -56,6 +59,9 @@ func (users Users) Marshall(isPublic bool) (interface{}, rest_error.RestErr) {
 			logger.Error("error while formatting user data", err)
 			return nil, rest_error.NewInternalServerError("error while formatting user data")
 		}
+		for i := range publicUsers {
+			publicUsers[i].Profile = users[i].publicProfile()
+		}
 		return publicUsers, nil
 	}
 	var privateUsers []PrivateUser
@@ -65,3 +71,45 @@ func (users Users) Marshall(isPublic bool) (interface{}, rest_error.RestErr) {
 	}
 	return privateUsers, nil
 }
+
+/// publicProfile returns the public part of the user's profile, if loaded
+func (user *User) publicProfile() *PublicProfile {
+	if user.Profile == nil {
+		return nil
+	}
+	return user.Profile.Public()
+}
+
+type searchResultJSON struct {
+	User       interface{}       `json:"user"`
+	Score      float64           `json:"score"`
+	Highlights map[string]string `json:"highlights"`
+}
+
+func (results SearchResults) Marshall(isPublic bool) (interface{}, rest_error.RestErr) {
+	return results.MarshallWith(isPublic, (*User).Marshall)
+}
+
+/// MarshallWith marshalls the results with the users in the shape
+/// marshallUser gives them
+func (results SearchResults) MarshallWith(isPublic bool,
+	marshallUser func(*User, bool) (interface{}, rest_error.RestErr)) (interface{}, rest_error.RestErr) {
+	marshalled := make([]searchResultJSON, 0, len(results))
+	for i := range results {
+		user, err := marshallUser(&results[i].User, isPublic)
+		if err != nil {
+			return nil, err
+		}
+		highlights := results[i].Highlights
+		if isPublic {
+			highlights = make(map[string]string)
+			for _, field := range []string{"first_name", "last_name"} {
+				if value, ok := results[i].Highlights[field]; ok {
+					highlights[field] = value
+				}
+			}
+		}
+		marshalled = append(marshalled, searchResultJSON{User: user, Score: results[i].Score, Highlights: highlights})
+	}
+	return marshalled, nil
+}
@@ -0,0 +1,79 @@
+package users
+
+import (
+	"github.com/Abacode7/bookstore_users-api/utils/date_utils"
+	"github.com/Abacode7/bookstore_utils-go/v2/logger"
+	"github.com/Abacode7/bookstore_utils-go/v2/rest_error"
+	"time"
+)
+
+/// PublicUserV2 is the v2 PublicUser, with the names nested in the profile
+type PublicUserV2 struct {
+	Profile PublicProfileV2 `json:"profile"`
+}
+
+/// PrivateUserV2 is the v2 PrivateUser. The names are nested in the
+/// profile and date_created is replaced by an RFC 3339 created_at.
+type PrivateUserV2 struct {
+	Id        int64     `json:"id"`
+	Email     string    `json:"email"`
+	Status    string    `json:"status"`
+	CreatedAt time.Time `json:"created_at"`
+	Profile   ProfileV2 `json:"profile"`
+}
+
+/// ProfileV2 is the user's names and, when loaded, the rest of their profile
+type ProfileV2 struct {
+	FirstName string `json:"first_name"`
+	LastName  string `json:"last_name"`
+	*Profile
+}
+
+/// PublicProfileV2 is the user's names and the public part of their profile
+type PublicProfileV2 struct {
+	FirstName string `json:"first_name"`
+	LastName  string `json:"last_name"`
+	*PublicProfile
+}
+
+/// MarshallV2 is Marshall for v2 of the api
+func (user *User) MarshallV2(isPublic bool) (interface{}, rest_error.RestErr) {
+	if isPublic {
+		return PublicUserV2{
+			Profile: PublicProfileV2{
+				FirstName:     user.FirstName,
+				LastName:      user.LastName,
+				PublicProfile: user.publicProfile(),
+			},
+		}, nil
+	}
+	createdAt, err := date_utils.ParseDbTime(user.DateCreated)
+	if err != nil {
+		logger.Error("error while formatting user data", err)
+		return nil, rest_error.NewInternalServerError("error while formatting user data")
+	}
+	return PrivateUserV2{
+		Id:        user.Id,
+		Email:     user.Email,
+		Status:    user.Status,
+		CreatedAt: createdAt,
+		Profile: ProfileV2{
+			FirstName: user.FirstName,
+			LastName:  user.LastName,
+			Profile:   user.Profile,
+		},
+	}, nil
+}
+
+/// MarshallV2 is Marshall for v2 of the api
+func (users Users) MarshallV2(isPublic bool) (interface{}, rest_error.RestErr) {
+	result := make([]interface{}, 0, len(users))
+	for i := range users {
+		user, err := users[i].MarshallV2(isPublic)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, user)
+	}
+	return result, nil
+}
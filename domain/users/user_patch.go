@@ -0,0 +1,137 @@
+package users
+
+import (
+	"encoding/json"
+	"github.com/Abacode7/bookstore_users-api/utils/error_utils"
+	"github.com/Abacode7/bookstore_users-api/utils/patch_utils"
+	"github.com/Abacode7/bookstore_utils-go/v2/logger"
+	"github.com/Abacode7/bookstore_utils-go/v2/rest_error"
+	"sort"
+)
+
+const (
+	MergePatchContentType = "application/merge-patch+json"
+	JSONPatchContentType  = "application/json-patch+json"
+)
+
+/// UserPatch is a partial update of a user, either an RFC 7396 merge
+/// patch or an RFC 6902 JSON Patch depending on ContentType
+type UserPatch struct {
+	ContentType string
+	Body        []byte
+}
+
+/// userDocument is the patchable representation of a user. The password
+/// is never part of it, patches may only add one.
+type userDocument struct {
+	FirstName *string `json:"first_name"`
+	LastName  *string `json:"last_name"`
+	Email     *string `json:"email"`
+	Status    *string `json:"status"`
+	Password  *string `json:"password,omitempty"`
+}
+
+/// userDocumentFields are the json names of userDocument's fields
+var userDocumentFields = []string{"first_name", "last_name", "email", "status", "password"}
+
+/// Apply patches user and returns the result. Fields set to null are
+/// cleared, and Password holds the new plain text password if the patch
+/// sets one, or is empty otherwise.
+func (patch UserPatch) Apply(user User) (*User, rest_error.RestErr) {
+	doc, err := toJSONValue(userDocument{
+		FirstName: &user.FirstName,
+		LastName:  &user.LastName,
+		Email:     &user.Email,
+		Status:    &user.Status,
+	})
+	if err != nil {
+		logger.Error("error building user patch document", err)
+		return nil, rest_error.NewInternalServerError("error while patching user")
+	}
+
+	switch patch.ContentType {
+	case MergePatchContentType:
+		var mergePatch interface{}
+		if err := json.Unmarshal(patch.Body, &mergePatch); err != nil {
+			return nil, rest_error.NewBadRequestError("invalid merge patch body")
+		}
+		doc = patch_utils.MergePatch(doc, mergePatch)
+	case JSONPatchContentType:
+		var operations []patch_utils.Operation
+		if err := json.Unmarshal(patch.Body, &operations); err != nil {
+			return nil, rest_error.NewBadRequestError("invalid json patch body")
+		}
+		doc, err = patch_utils.ApplyJSONPatch(doc, operations)
+		if err == patch_utils.ErrTestFailed {
+			return nil, error_utils.NewConflictError("json patch test operation failed")
+		}
+		if err != nil {
+			return nil, rest_error.NewBadRequestError("invalid json patch: " + err.Error())
+		}
+	default:
+		return nil, error_utils.NewUnsupportedMediaTypeError("unsupported patch content type")
+	}
+
+	patched, patchedErr := decodeUserDocument(doc)
+	if patchedErr != nil {
+		return nil, patchedErr
+	}
+	user.FirstName = stringValue(patched.FirstName)
+	user.LastName = stringValue(patched.LastName)
+	user.Email = stringValue(patched.Email)
+	user.Status = stringValue(patched.Status)
+	user.Password = stringValue(patched.Password)
+	return &user, nil
+}
+
+/// decodeUserDocument reads a patched document back. Its errors name the
+/// offending field, the patch is the client's but the types are ours.
+func decodeUserDocument(doc interface{}) (*userDocument, rest_error.RestErr) {
+	members, ok := doc.(map[string]interface{})
+	if !ok {
+		return nil, rest_error.NewBadRequestError("patched user must be an object")
+	}
+	names := make([]string, 0, len(members))
+	for name := range members {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	values := make(map[string]*string, len(names))
+	for _, name := range names {
+		if !contains(userDocumentFields, name) {
+			return nil, error_utils.NewFieldError(name, "unknown field "+name)
+		}
+		switch value := members[name].(type) {
+		case nil:
+		case string:
+			values[name] = &value
+		default:
+			return nil, error_utils.NewFieldError(name, name+" must be a string or null")
+		}
+	}
+	return &userDocument{
+		FirstName: values["first_name"],
+		LastName:  values["last_name"],
+		Email:     values["email"],
+		Status:    values["status"],
+		Password:  values["password"],
+	}, nil
+}
+
+func toJSONValue(value interface{}) (interface{}, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	var result interface{}
+	err = json.Unmarshal(data, &result)
+	return result, err
+}
+
+func stringValue(value *string) string {
+	if value == nil {
+		return ""
+	}
+	return *value
+}
@@ -0,0 +1,34 @@
+package users
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestUserPatchErrorsNameTheField(t *testing.T) {
+	user := User{Id: 1, FirstName: "Ann", LastName: "Lee", Email: "ann@mail.com", Status: StatusActive}
+	tests := []struct {
+		contentType, body, message string
+	}{
+		{MergePatchContentType, `{"first_name":42}`, "first_name must be a string or null"},
+		{MergePatchContentType, `{"status":{"name":"active"}}`, "status must be a string or null"},
+		{MergePatchContentType, `{"nickname":"annie"}`, "unknown field nickname"},
+		{MergePatchContentType, `["ann"]`, "patched user must be an object"},
+		{JSONPatchContentType, `[{"op":"add","path":"/email","value":true}]`, "email must be a string or null"},
+		{JSONPatchContentType, `[{"op":"move","from":"/email","path":"/id"}]`, "unknown field id"},
+	}
+	for _, test := range tests {
+		_, err := UserPatch{ContentType: test.contentType, Body: []byte(test.body)}.Apply(user)
+		if err == nil || err.Status() != http.StatusBadRequest || err.Message() != test.message {
+			t.Errorf("%s: got %v, want a bad request %q", test.body, err, test.message)
+		}
+	}
+
+	patched, err := UserPatch{ContentType: MergePatchContentType, Body: []byte(`{"last_name":null,"password":"secret"}`)}.Apply(user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if patched.LastName != "" || patched.Password != "secret" || patched.FirstName != "Ann" {
+		t.Errorf("got %+v, want the last name cleared and the password set", patched)
+	}
+}
@@ -0,0 +1,181 @@
+package users
+
+import (
+	"github.com/Abacode7/bookstore_utils-go/v2/rest_error"
+	"html"
+	"strings"
+	"unicode"
+)
+
+const (
+	DefaultSearchLimit = 20
+	MaxSearchLimit     = 100
+
+	highlightStart = "<em>"
+	highlightEnd   = "</em>"
+
+	matchExact  = 3
+	matchPrefix = 2
+	matchFuzzy  = 1
+)
+
+/// ISearchIndex finds users by partial name or email. Index and Remove
+/// keep indexes that don't read the users table directly up to date.
+type ISearchIndex interface {
+	Search(SearchQuery) (SearchResults, rest_error.RestErr)
+	Index(User) rest_error.RestErr
+	Remove(int64) rest_error.RestErr
+}
+
+type SearchQuery struct {
+	Text  string
+	Limit int
+}
+
+/// SearchResult is a matching user, its relevance and its fields with
+/// the matching words highlighted
+type SearchResult struct {
+	User       User
+	Score      float64
+	Highlights map[string]string
+}
+
+type SearchResults []SearchResult
+
+func (query *SearchQuery) Validate() rest_error.RestErr {
+	query.Text = strings.TrimSpace(query.Text)
+	if len(Tokenize(query.Text)) == 0 {
+		return rest_error.NewBadRequestError("invalid search query")
+	}
+	if query.Limit <= 0 {
+		query.Limit = DefaultSearchLimit
+	}
+	if query.Limit > MaxSearchLimit {
+		query.Limit = MaxSearchLimit
+	}
+	return nil
+}
+
+/// Tokenize splits text into the lower cased words it is searched by,
+/// e.g. "John.Doe@mail.com" into john, doe, mail and com
+func Tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
+
+/// matchTerm scores how word matches a query term: exactly, as a prefix
+/// or within one edit for terms long enough to tolerate typos
+func matchTerm(word string, term string) int {
+	switch {
+	case word == term:
+		return matchExact
+	case strings.HasPrefix(word, term):
+		return matchPrefix
+	case len(term) >= 4 && withinOneEdit(word, term):
+		return matchFuzzy
+	default:
+		return 0
+	}
+}
+
+/// withinOneEdit reports whether a and b differ by at most one
+/// insertion, deletion or substitution
+func withinOneEdit(a string, b string) bool {
+	ra, rb := []rune(a), []rune(b)
+	if len(ra) < len(rb) {
+		ra, rb = rb, ra
+	}
+	if len(ra)-len(rb) > 1 {
+		return false
+	}
+	i, j, edits := 0, 0, 0
+	for i < len(ra) && j < len(rb) {
+		if ra[i] == rb[j] {
+			i++
+			j++
+			continue
+		}
+		edits++
+		if edits > 1 {
+			return false
+		}
+		if len(ra) == len(rb) {
+			j++
+		}
+		i++
+	}
+	return edits+(len(ra)-i) <= 1
+}
+
+/// scoreUser sums the best match of every term against the user's
+/// searchable fields, with fields highlighted where they matched
+func scoreUser(user User, terms []string) (float64, map[string]string) {
+	fields := map[string]string{
+		"first_name": user.FirstName,
+		"last_name":  user.LastName,
+		"email":      user.Email,
+	}
+	var score float64
+	for _, term := range terms {
+		best := 0
+		for _, value := range fields {
+			for _, word := range Tokenize(value) {
+				if m := matchTerm(word, term); m > best {
+					best = m
+				}
+			}
+		}
+		score += float64(best)
+	}
+	highlights := make(map[string]string)
+	for name, value := range fields {
+		if highlighted, ok := highlight(value, terms); ok {
+			highlights[name] = highlighted
+		}
+	}
+	return score, highlights
+}
+
+/// highlight wraps the parts of value's words matching any of the terms.
+/// Prefix matches only highlight the prefix. The rest of value is html
+/// escaped, it is user controlled.
+func highlight(value string, terms []string) (string, bool) {
+	runes := []rune(value)
+	var result strings.Builder
+	matched := false
+	for i := 0; i < len(runes); {
+		if !unicode.IsLetter(runes[i]) && !unicode.IsNumber(runes[i]) {
+			result.WriteString(html.EscapeString(string(runes[i])))
+			i++
+			continue
+		}
+		end := i
+		for end < len(runes) && (unicode.IsLetter(runes[end]) || unicode.IsNumber(runes[end])) {
+			end++
+		}
+		word := string(runes[i:end])
+		length := 0
+		for _, term := range terms {
+			switch matchTerm(strings.ToLower(word), term) {
+			case matchExact, matchFuzzy:
+				length = end - i
+			case matchPrefix:
+				if termLength := len([]rune(term)); termLength > length {
+					length = termLength
+				}
+			}
+		}
+		if length > 0 {
+			matched = true
+			result.WriteString(highlightStart)
+			result.WriteString(html.EscapeString(string(runes[i : i+length])))
+			result.WriteString(highlightEnd)
+			result.WriteString(html.EscapeString(string(runes[i+length : end])))
+		} else {
+			result.WriteString(html.EscapeString(word))
+		}
+		i = end
+	}
+	return result.String(), matched
+}
@@ -0,0 +1,93 @@
+package users
+
+import (
+	"fmt"
+	"github.com/Abacode7/bookstore_users-api/utils/error_utils"
+	"github.com/Abacode7/bookstore_utils-go/v2/rest_error"
+	"strings"
+)
+
+/// Account statuses. Only active users can log in.
+const (
+	/// StatusPending users were provisioned by an admin and have no
+	/// password until they accept their invitation
+	StatusPending     = "pending"
+	StatusActive      = "active"
+	StatusSuspended   = "suspended"
+	StatusLocked      = "locked"
+	StatusDeactivated = "deactivated"
+	/// StatusDeleted users are kept as a tombstone, no status follows it
+	StatusDeleted = "deleted"
+	/// DeletionReason is recorded when a user is deleted through the
+	/// delete endpoint rather than a status change
+	DeletionReason = "account deleted"
+
+	/// maxStatusReasonLength is the size of the reason column
+	maxStatusReasonLength = 255
+)
+
+/// Statuses are all the account statuses
+var Statuses = []string{StatusPending, StatusActive, StatusSuspended, StatusLocked, StatusDeactivated, StatusDeleted}
+
+/// statusTransitions are the statuses each status may change to
+var statusTransitions = map[string][]string{
+	StatusPending:     {StatusActive, StatusDeleted},
+	StatusActive:      {StatusSuspended, StatusLocked, StatusDeactivated, StatusDeleted},
+	StatusSuspended:   {StatusActive, StatusDeactivated, StatusDeleted},
+	StatusLocked:      {StatusActive, StatusSuspended, StatusDeactivated, StatusDeleted},
+	StatusDeactivated: {StatusActive, StatusDeleted},
+	StatusDeleted:     {},
+}
+
+/// StatusChange is an entry of a user's status history. ChangedBy is
+/// the id of the admin who made it, 0 when the user or the service did.
+type StatusChange struct {
+	Id          int64  `json:"id"`
+	UserId      int64  `json:"user_id"`
+	FromStatus  string `json:"from_status"`
+	ToStatus    string `json:"to_status"`
+	Reason      string `json:"reason"`
+	ChangedBy   int64  `json:"changed_by"`
+	DateCreated string `json:"date_created"`
+}
+
+/// StatusChangeRequest is the body of the suspend and reactivate endpoints
+type StatusChangeRequest struct {
+	Reason string `json:"reason"`
+}
+
+/// ValidateStatus checks status is one of Statuses
+func ValidateStatus(status string) rest_error.RestErr {
+	if !contains(Statuses, status) {
+		return error_utils.NewFieldError("status", "status must be one of "+strings.Join(Statuses, ", "))
+	}
+	return nil
+}
+
+/// ValidateTransition checks a user may go from status from to status to
+func ValidateTransition(from string, to string) rest_error.RestErr {
+	if err := ValidateStatus(to); err != nil {
+		return err
+	}
+	if !contains(statusTransitions[from], to) {
+		return error_utils.NewConflictError(fmt.Sprintf("a %s user can't become %s", from, to))
+	}
+	return nil
+}
+
+/// IsSelfServiceTransition reports whether users may make the change
+/// themselves through a user update, other changes are for admins
+func IsSelfServiceTransition(from string, to string) bool {
+	return from == StatusActive && to == StatusDeactivated
+}
+
+func (request *StatusChangeRequest) Validate() rest_error.RestErr {
+	request.Reason = strings.TrimSpace(request.Reason)
+	if request.Reason == "" {
+		return error_utils.NewFieldError("reason", "a reason is required")
+	}
+	if len(request.Reason) > maxStatusReasonLength {
+		return error_utils.NewFieldError("reason", fmt.Sprintf("reason must be at most %d characters", maxStatusReasonLength))
+	}
+	return nil
+}
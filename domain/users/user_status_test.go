@@ -0,0 +1,51 @@
+package users
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestStatusTransitions(t *testing.T) {
+	allowed := map[string][]string{
+		StatusPending:     {StatusActive, StatusDeleted},
+		StatusActive:      {StatusSuspended, StatusLocked, StatusDeactivated, StatusDeleted},
+		StatusSuspended:   {StatusActive, StatusDeactivated, StatusDeleted},
+		StatusLocked:      {StatusActive, StatusSuspended, StatusDeactivated, StatusDeleted},
+		StatusDeactivated: {StatusActive, StatusDeleted},
+		StatusDeleted:     {},
+	}
+	for _, from := range Statuses {
+		for _, to := range Statuses {
+			err := ValidateTransition(from, to)
+			if want := contains(allowed[from], to); want != (err == nil) {
+				t.Errorf("%s to %s: got %v, want allowed %t", from, to, err, want)
+			}
+			if err != nil && err.Status() != http.StatusConflict {
+				t.Errorf("%s to %s: got status %d, want a conflict", from, to, err.Status())
+			}
+		}
+	}
+	if err := ValidateTransition(StatusActive, "archived"); err == nil || err.Status() != http.StatusBadRequest {
+		t.Errorf("unknown status: got %v, want a bad request", err)
+	}
+}
+
+func TestIsSelfServiceTransition(t *testing.T) {
+	tests := []struct {
+		from, to string
+		want     bool
+	}{
+		{StatusActive, StatusDeactivated, true},
+		{StatusActive, StatusSuspended, false},
+		{StatusActive, StatusDeleted, false},
+		{StatusDeactivated, StatusActive, false},
+		{StatusSuspended, StatusActive, false},
+		{StatusLocked, StatusDeactivated, false},
+		{StatusPending, StatusActive, false},
+	}
+	for _, test := range tests {
+		if got := IsSelfServiceTransition(test.from, test.to); got != test.want {
+			t.Errorf("%s to %s: got %t, want %t", test.from, test.to, got, test.want)
+		}
+	}
+}
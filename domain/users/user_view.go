@@ -0,0 +1,130 @@
+package users
+
+import (
+	"encoding/json"
+	"github.com/Abacode7/bookstore_users-api/utils/error_utils"
+	"github.com/Abacode7/bookstore_utils-go/v2/logger"
+	"github.com/Abacode7/bookstore_utils-go/v2/rest_error"
+	"reflect"
+	"strings"
+)
+
+/// Related resources a user read can embed with include
+const (
+	IncludeAddresses        = "addresses"
+	IncludeDefaultAddresses = "default_addresses"
+	IncludeOrganizations    = "organizations"
+	IncludeRoles            = "roles"
+)
+
+/// Includes are all the includes. They're private, so only internal
+/// callers may ask for them.
+var Includes = []string{IncludeAddresses, IncludeDefaultAddresses, IncludeOrganizations, IncludeRoles}
+
+/// The fields of each user shape, which fields selects from
+var (
+	PublicUserFields    = jsonFields(PublicUser{})
+	PrivateUserFields   = jsonFields(PrivateUser{})
+	PublicUserV2Fields  = jsonFields(PublicUserV2{})
+	PrivateUserV2Fields = jsonFields(PrivateUserV2{})
+)
+
+/// UserView is the fields and related resources a user read asks for.
+/// No Fields selects all of them.
+type UserView struct {
+	Fields   []string
+	Includes []string
+}
+
+/// NewUserView parses the comma separated fields and include parameters
+func NewUserView(fields string, include string) UserView {
+	return UserView{Fields: splitList(fields), Includes: splitList(include)}
+}
+
+/// IsDefault reports whether the view is the full user with nothing embedded
+func (view UserView) IsDefault() bool {
+	return len(view.Fields) == 0 && len(view.Includes) == 0
+}
+
+/// Validate checks the view selects fields of the user shape and known
+/// includes. Public callers may only select publicFields and can't
+/// include anything.
+func (view UserView) Validate(isPublic bool, publicFields []string, privateFields []string) rest_error.RestErr {
+	for _, field := range view.Fields {
+		if !contains(privateFields, field) && !contains(publicFields, field) {
+			return error_utils.NewFieldError("fields", "unknown field "+field)
+		}
+		if isPublic && !contains(publicFields, field) {
+			return error_utils.NewForbiddenError("field " + field + " is private")
+		}
+	}
+	for _, include := range view.Includes {
+		if !contains(Includes, include) {
+			return error_utils.NewFieldError("include", "unknown include "+include)
+		}
+		if isPublic {
+			return error_utils.NewForbiddenError("include " + include + " is private")
+		}
+	}
+	return nil
+}
+
+/// Apply keeps the selected fields of a marshalled user and adds the
+/// embedded resources
+func (view UserView) Apply(marshalled interface{}, embedded map[string]interface{}) (interface{}, rest_error.RestErr) {
+	if view.IsDefault() {
+		return marshalled, nil
+	}
+	jsonData, err := json.Marshal(marshalled)
+	if err != nil {
+		logger.Error("error while marshalling user data", err)
+		return nil, rest_error.NewInternalServerError("error while marshalling user data")
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(jsonData, &fields); err != nil {
+		logger.Error("error while formatting user data", err)
+		return nil, rest_error.NewInternalServerError("error while formatting user data")
+	}
+	result := make(map[string]interface{}, len(fields)+len(embedded))
+	for name, value := range fields {
+		if len(view.Fields) == 0 || contains(view.Fields, name) {
+			result[name] = value
+		}
+	}
+	for name, resource := range embedded {
+		result[name] = resource
+	}
+	return result, nil
+}
+
+/// jsonFields lists the json names of shape's fields
+func jsonFields(shape interface{}) []string {
+	shapeType := reflect.TypeOf(shape)
+	fields := make([]string, 0, shapeType.NumField())
+	for i := 0; i < shapeType.NumField(); i++ {
+		name := strings.Split(shapeType.Field(i).Tag.Get("json"), ",")[0]
+		if name != "" && name != "-" {
+			fields = append(fields, name)
+		}
+	}
+	return fields
+}
+
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" && !contains(items, item) {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func contains(items []string, item string) bool {
+	for _, candidate := range items {
+		if candidate == item {
+			return true
+		}
+	}
+	return false
+}
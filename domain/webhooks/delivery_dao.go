@@ -0,0 +1,260 @@
+package webhooks
+
+import (
+	"database/sql"
+	"fmt"
+	"github.com/Abacode7/bookstore_users-api/utils/date_utils"
+	"github.com/Abacode7/bookstore_utils-go/v2/logger"
+	"github.com/Abacode7/bookstore_utils-go/v2/rest_error"
+	"strings"
+	"time"
+)
+
+const (
+	/// Deliveries are unique per subscription and event, so an event the
+	/// outbox relays twice is only enqueued once
+	enqueueDeliveryQuery        = `INSERT IGNORE INTO webhook_deliveries (subscription_id, event_id, event_type, payload, status, attempts, next_attempt_at, date_created) VALUES (?, ?, ?, ?, ?, 0, ?, ?);`
+	deliveryColumns             = `d.id, d.subscription_id, d.event_id, d.event_type, d.payload, d.status, d.attempts, d.next_attempt_at, d.last_status_code, d.last_error, d.date_created`
+	findDeliveriesQuery         = `SELECT ` + deliveryColumns + ` FROM webhook_deliveries d WHERE d.subscription_id=? ORDER BY d.id DESC LIMIT ?;`
+	findDeliveriesByStatusQuery = `SELECT ` + deliveryColumns + ` FROM webhook_deliveries d WHERE d.subscription_id=? AND d.status=? ORDER BY d.id DESC LIMIT ?;`
+	findDeadDeliveriesQuery     = `SELECT ` + deliveryColumns + ` FROM webhook_deliveries d WHERE d.status=? ORDER BY d.id DESC LIMIT ?;`
+	claimDueDeliveriesQuery     = `SELECT ` + deliveryColumns + `, s.url, s.secret FROM webhook_deliveries d JOIN webhook_subscriptions s ON s.id = d.subscription_id WHERE d.status=? AND d.next_attempt_at <= ? ORDER BY d.id LIMIT ? FOR UPDATE OF d SKIP LOCKED;`
+	leaseDeliveriesQuery        = `UPDATE webhook_deliveries SET next_attempt_at=? WHERE id IN (%s);`
+	markDeliveryQuery           = `UPDATE webhook_deliveries SET status=?, attempts=attempts+1, next_attempt_at=?, last_status_code=?, last_error=? WHERE id=?;`
+	requeueDeliveryQuery        = `UPDATE webhook_deliveries SET status=?, attempts=0, next_attempt_at=? WHERE id=? AND status=?;`
+
+	maxLastErrorLength = 255
+	/// claimLease is how long claimed deliveries are held by the
+	/// dispatcher that claimed them, it outlasts sending a batch
+	claimLease = 15 * time.Minute
+)
+
+type IDeliveryDao interface {
+	Enqueue(Delivery) rest_error.RestErr
+	FindBySubscription(subscriptionId int64, status string, limit int) (Deliveries, rest_error.RestErr)
+	FindDead(limit int) (Deliveries, rest_error.RestErr)
+	Requeue(int64) rest_error.RestErr
+	ProcessDue(limit int, send func(Delivery) (int, error), retryDelay func(attempts int) time.Duration, maxAttempts int) (int, rest_error.RestErr)
+}
+
+type deliveryDao struct {
+	client *sql.DB
+}
+
+/// NewDeliveryDao is a constructor for deliveryDao
+func NewDeliveryDao(db *sql.DB) IDeliveryDao {
+	return &deliveryDao{db}
+}
+
+/// Enqueue stores a pending delivery, due at once, unless the event was
+/// already enqueued for the subscription
+func (dd *deliveryDao) Enqueue(delivery Delivery) rest_error.RestErr {
+	stmt, err := dd.client.Prepare(enqueueDeliveryQuery)
+	if err != nil {
+		logger.Error("error preparing enqueue delivery query", err)
+		return rest_error.NewInternalServerError("database error")
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(delivery.SubscriptionId, delivery.EventId, delivery.EventType, string(delivery.Payload),
+		DeliveryPending, delivery.DateCreated, delivery.DateCreated)
+	if err != nil {
+		logger.Error("error executing enqueue delivery query", err)
+		return rest_error.NewInternalServerError("database error")
+	}
+	return nil
+}
+
+/// FindBySubscription gets the latest deliveries of subscriptionId, only
+/// those in status unless it's empty
+func (dd *deliveryDao) FindBySubscription(subscriptionId int64, status string, limit int) (Deliveries, rest_error.RestErr) {
+	if status == "" {
+		return dd.find(findDeliveriesQuery, subscriptionId, limit)
+	}
+	return dd.find(findDeliveriesByStatusQuery, subscriptionId, status, limit)
+}
+
+/// FindDead gets the latest deliveries in the dead letter list
+func (dd *deliveryDao) FindDead(limit int) (Deliveries, rest_error.RestErr) {
+	return dd.find(findDeadDeliveriesQuery, DeliveryDead, limit)
+}
+
+/// Requeue moves the dead delivery with deliveryId back to pending with
+/// a fresh set of attempts
+func (dd *deliveryDao) Requeue(deliveryId int64) rest_error.RestErr {
+	stmt, err := dd.client.Prepare(requeueDeliveryQuery)
+	if err != nil {
+		logger.Error("error preparing requeue delivery query", err)
+		return rest_error.NewInternalServerError("database error")
+	}
+	defer stmt.Close()
+
+	result, err := stmt.Exec(DeliveryPending, date_utils.GetDbFormattedTime(), deliveryId, DeliveryDead)
+	if err != nil {
+		logger.Error("error executing requeue delivery query", err)
+		return rest_error.NewInternalServerError("database error")
+	}
+	rowsAff, err := result.RowsAffected()
+	if err != nil {
+		logger.Error("error retrieving rows affected", err)
+		return rest_error.NewInternalServerError("database error")
+	}
+	if rowsAff < 1 {
+		return rest_error.NewNotFoundError("dead webhook delivery not found")
+	}
+	return nil
+}
+
+/// ProcessDue claims up to limit pending deliveries that are due and
+/// sends them in order. A failed delivery is retried after retryDelay of
+/// its attempts, once it has failed maxAttempts times it is dead. Claimed
+/// deliveries are leased to this dispatcher and sent outside of any
+/// transaction. It returns how many deliveries were processed.
+func (dd *deliveryDao) ProcessDue(limit int, send func(Delivery) (int, error), retryDelay func(attempts int) time.Duration, maxAttempts int) (int, rest_error.RestErr) {
+	deliveries, restErr := dd.claimDue(limit)
+	if restErr != nil {
+		return 0, restErr
+	}
+	for _, delivery := range deliveries {
+		statusCode, sendErr := send(delivery)
+		if sendErr != nil {
+			logger.Error(fmt.Sprintf("error sending webhook delivery %d", delivery.Id), sendErr)
+		}
+		delivery.RecordAttempt(statusCode, sendErr, date_utils.GetTime(), retryDelay, maxAttempts)
+		if err := dd.mark(delivery); err != nil {
+			return 0, err
+		}
+	}
+	return len(deliveries), nil
+}
+
+/// claimDue locks up to limit due deliveries and leases them for
+/// claimLease by moving their next attempt past it. Other dispatchers
+/// skip them until their outcome is marked, or the lease ends if this
+/// dispatcher dies.
+func (dd *deliveryDao) claimDue(limit int) (Deliveries, rest_error.RestErr) {
+	tx, err := dd.client.Begin()
+	if err != nil {
+		logger.Error("error starting claim deliveries transaction", err)
+		return nil, rest_error.NewInternalServerError("database error")
+	}
+	defer tx.Rollback()
+
+	deliveries, restErr := queryDue(tx, limit)
+	if restErr != nil || len(deliveries) == 0 {
+		return deliveries, restErr
+	}
+	args := []interface{}{date_utils.FormatDbTime(date_utils.GetTime().Add(claimLease))}
+	placeholders := make([]string, 0, len(deliveries))
+	for _, delivery := range deliveries {
+		args = append(args, delivery.Id)
+		placeholders = append(placeholders, "?")
+	}
+	if _, err := tx.Exec(fmt.Sprintf(leaseDeliveriesQuery, strings.Join(placeholders, ", ")), args...); err != nil {
+		logger.Error("error executing lease deliveries query", err)
+		return nil, rest_error.NewInternalServerError("database error")
+	}
+	if err := tx.Commit(); err != nil {
+		logger.Error("error committing claim deliveries transaction", err)
+		return nil, rest_error.NewInternalServerError("database error")
+	}
+	return deliveries, nil
+}
+
+func queryDue(tx *sql.Tx, limit int) (Deliveries, rest_error.RestErr) {
+	rows, err := tx.Query(claimDueDeliveriesQuery, DeliveryPending, date_utils.GetDbFormattedTime(), limit)
+	if err != nil {
+		logger.Error("error executing claim deliveries query", err)
+		return nil, rest_error.NewInternalServerError("database error")
+	}
+	defer rows.Close()
+
+	deliveries := make(Deliveries, 0)
+	for rows.Next() {
+		var url, secret string
+		delivery, err := scanDelivery(rows, &url, &secret)
+		if err != nil {
+			logger.Error("error scanning delivery", err)
+			return nil, rest_error.NewInternalServerError("database error")
+		}
+		delivery.Url, delivery.Secret = url, secret
+		deliveries = append(deliveries, *delivery)
+	}
+	if err := rows.Err(); err != nil {
+		logger.Error("error iterating deliveries", err)
+		return nil, rest_error.NewInternalServerError("database error")
+	}
+	return deliveries, nil
+}
+
+/// mark stores the outcome of the delivery's last attempt
+func (dd *deliveryDao) mark(delivery Delivery) rest_error.RestErr {
+	var nextAttempt, lastError sql.NullString
+	if delivery.NextAttemptAt != "" {
+		nextAttempt = sql.NullString{String: delivery.NextAttemptAt, Valid: true}
+	}
+	if delivery.LastError != "" {
+		lastError = sql.NullString{String: truncate(delivery.LastError, maxLastErrorLength), Valid: true}
+	}
+	var lastStatusCode sql.NullInt64
+	if delivery.LastStatusCode != 0 {
+		lastStatusCode = sql.NullInt64{Int64: int64(delivery.LastStatusCode), Valid: true}
+	}
+	if _, err := dd.client.Exec(markDeliveryQuery, delivery.Status, nextAttempt, lastStatusCode, lastError, delivery.Id); err != nil {
+		logger.Error("error executing mark delivery query", err)
+		return rest_error.NewInternalServerError("database error")
+	}
+	return nil
+}
+
+func (dd *deliveryDao) find(query string, args ...interface{}) (Deliveries, rest_error.RestErr) {
+	stmt, err := dd.client.Prepare(query)
+	if err != nil {
+		logger.Error("error preparing find deliveries query", err)
+		return nil, rest_error.NewInternalServerError("database error")
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.Query(args...)
+	if err != nil {
+		logger.Error("error executing find deliveries query", err)
+		return nil, rest_error.NewInternalServerError("database error")
+	}
+	defer rows.Close()
+
+	deliveries := make(Deliveries, 0)
+	for rows.Next() {
+		delivery, err := scanDelivery(rows)
+		if err != nil {
+			logger.Error("error scanning delivery", err)
+			return nil, rest_error.NewInternalServerError("database error")
+		}
+		deliveries = append(deliveries, *delivery)
+	}
+	return deliveries, nil
+}
+
+/// scanDelivery scans the delivery columns followed by extra columns
+func scanDelivery(row scanner, extra ...interface{}) (*Delivery, error) {
+	var delivery Delivery
+	var payload string
+	var nextAttemptAt, lastError sql.NullString
+	var lastStatusCode sql.NullInt64
+	dest := []interface{}{&delivery.Id, &delivery.SubscriptionId, &delivery.EventId, &delivery.EventType, &payload,
+		&delivery.Status, &delivery.Attempts, &nextAttemptAt, &lastStatusCode, &lastError, &delivery.DateCreated}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return nil, err
+	}
+	delivery.Payload = []byte(payload)
+	delivery.NextAttemptAt = nextAttemptAt.String
+	delivery.LastStatusCode = int(lastStatusCode.Int64)
+	delivery.LastError = lastError.String
+	return &delivery, nil
+}
+
+func truncate(value string, length int) string {
+	if len(value) <= length {
+		return value
+	}
+	return value[:length]
+}
@@ -0,0 +1,154 @@
+package webhooks
+
+import (
+	"database/sql"
+	"github.com/Abacode7/bookstore_utils-go/v2/logger"
+	"github.com/Abacode7/bookstore_utils-go/v2/rest_error"
+	"strings"
+)
+
+const (
+	insertSubscriptionQuery       = `INSERT INTO webhook_subscriptions (url, event_types, secret, date_created) VALUES (?, ?, ?, ?);`
+	getSubscriptionQuery          = `SELECT id, url, event_types, secret, date_created FROM webhook_subscriptions WHERE id=?;`
+	findSubscriptionsQuery        = `SELECT id, url, event_types, secret, date_created FROM webhook_subscriptions ORDER BY id;`
+	findSubscriptionsByEventQuery = `SELECT id, url, event_types, secret, date_created FROM webhook_subscriptions WHERE FIND_IN_SET(?, event_types) > 0;`
+	deleteSubscriptionQuery       = `DELETE FROM webhook_subscriptions WHERE id=?;`
+	eventTypesSeparator           = ","
+)
+
+type ISubscriptionDao interface {
+	Save(Subscription) (*Subscription, rest_error.RestErr)
+	Get(int64) (*Subscription, rest_error.RestErr)
+	FindAll() (Subscriptions, rest_error.RestErr)
+	FindByEventType(string) (Subscriptions, rest_error.RestErr)
+	Delete(int64) rest_error.RestErr
+}
+
+type subscriptionDao struct {
+	client *sql.DB
+}
+
+/// NewSubscriptionDao is a constructor for subscriptionDao
+func NewSubscriptionDao(db *sql.DB) ISubscriptionDao {
+	return &subscriptionDao{db}
+}
+
+/// Save stores a webhook subscription
+func (sd *subscriptionDao) Save(subscription Subscription) (*Subscription, rest_error.RestErr) {
+	stmt, err := sd.client.Prepare(insertSubscriptionQuery)
+	if err != nil {
+		logger.Error("error preparing insert subscription query", err)
+		return nil, rest_error.NewInternalServerError("database error")
+	}
+	defer stmt.Close()
+
+	result, err := stmt.Exec(subscription.Url, strings.Join(subscription.EventTypes, eventTypesSeparator),
+		subscription.Secret, subscription.DateCreated)
+	if err != nil {
+		logger.Error("error executing insert subscription query", err)
+		return nil, rest_error.NewInternalServerError("database error")
+	}
+	subscription.Id, err = result.LastInsertId()
+	if err != nil {
+		logger.Error("error retrieving last insert id", err)
+		return nil, rest_error.NewInternalServerError("database error")
+	}
+	return &subscription, nil
+}
+
+/// Get gets the subscription with subscriptionId
+func (sd *subscriptionDao) Get(subscriptionId int64) (*Subscription, rest_error.RestErr) {
+	stmt, err := sd.client.Prepare(getSubscriptionQuery)
+	if err != nil {
+		logger.Error("error preparing get subscription query", err)
+		return nil, rest_error.NewInternalServerError("database error")
+	}
+	defer stmt.Close()
+
+	subscription, err := scanSubscription(stmt.QueryRow(subscriptionId))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, rest_error.NewNotFoundError("webhook subscription not found")
+		}
+		logger.Error("error scanning subscription", err)
+		return nil, rest_error.NewInternalServerError("database error")
+	}
+	return subscription, nil
+}
+
+/// FindAll gets every subscription
+func (sd *subscriptionDao) FindAll() (Subscriptions, rest_error.RestErr) {
+	return sd.find(findSubscriptionsQuery)
+}
+
+/// FindByEventType gets the subscriptions to events of eventType
+func (sd *subscriptionDao) FindByEventType(eventType string) (Subscriptions, rest_error.RestErr) {
+	return sd.find(findSubscriptionsByEventQuery, eventType)
+}
+
+/// Delete removes the subscription with subscriptionId and its deliveries
+func (sd *subscriptionDao) Delete(subscriptionId int64) rest_error.RestErr {
+	stmt, err := sd.client.Prepare(deleteSubscriptionQuery)
+	if err != nil {
+		logger.Error("error preparing delete subscription query", err)
+		return rest_error.NewInternalServerError("database error")
+	}
+	defer stmt.Close()
+
+	result, err := stmt.Exec(subscriptionId)
+	if err != nil {
+		logger.Error("error executing delete subscription query", err)
+		return rest_error.NewInternalServerError("database error")
+	}
+	rowsAff, err := result.RowsAffected()
+	if err != nil {
+		logger.Error("error retrieving rows affected", err)
+		return rest_error.NewInternalServerError("database error")
+	}
+	if rowsAff < 1 {
+		return rest_error.NewNotFoundError("webhook subscription not found")
+	}
+	return nil
+}
+
+func (sd *subscriptionDao) find(query string, args ...interface{}) (Subscriptions, rest_error.RestErr) {
+	stmt, err := sd.client.Prepare(query)
+	if err != nil {
+		logger.Error("error preparing find subscriptions query", err)
+		return nil, rest_error.NewInternalServerError("database error")
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.Query(args...)
+	if err != nil {
+		logger.Error("error executing find subscriptions query", err)
+		return nil, rest_error.NewInternalServerError("database error")
+	}
+	defer rows.Close()
+
+	subscriptions := make(Subscriptions, 0)
+	for rows.Next() {
+		subscription, err := scanSubscription(rows)
+		if err != nil {
+			logger.Error("error scanning subscription", err)
+			return nil, rest_error.NewInternalServerError("database error")
+		}
+		subscriptions = append(subscriptions, *subscription)
+	}
+	return subscriptions, nil
+}
+
+/// scanner is satisfied by both *sql.Row and *sql.Rows
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanSubscription(row scanner) (*Subscription, error) {
+	var subscription Subscription
+	var eventTypes string
+	if err := row.Scan(&subscription.Id, &subscription.Url, &eventTypes, &subscription.Secret, &subscription.DateCreated); err != nil {
+		return nil, err
+	}
+	subscription.EventTypes = strings.Split(eventTypes, eventTypesSeparator)
+	return &subscription, nil
+}
@@ -0,0 +1,115 @@
+package webhooks
+
+import (
+	"encoding/json"
+	"github.com/Abacode7/bookstore_users-api/domain/users"
+	"github.com/Abacode7/bookstore_users-api/utils/date_utils"
+	"github.com/Abacode7/bookstore_users-api/utils/error_utils"
+	"github.com/Abacode7/bookstore_utils-go/v2/rest_error"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	DeliveryPending   = "pending"
+	DeliveryDelivered = "delivered"
+	/// DeliveryDead deliveries ran out of attempts and wait in the dead
+	/// letter list until an admin retries them
+	DeliveryDead = "dead"
+)
+
+/// EventTypes are the user events partners can subscribe to
+var EventTypes = []string{
+	users.EventUserCreated,
+	users.EventUserUpdated,
+	users.EventUserDeactivated,
+	users.EventUserDeleted,
+	users.EventUserLogin,
+}
+
+/// Subscription registers url for callbacks on events of EventTypes,
+/// signed with Secret
+type Subscription struct {
+	Id          int64    `json:"id"`
+	Url         string   `json:"url"`
+	EventTypes  []string `json:"event_types"`
+	Secret      string   `json:"secret,omitempty"`
+	DateCreated string   `json:"date_created"`
+}
+
+type Subscriptions []Subscription
+
+/// Delivery is one event sent, or to be sent, to one subscription
+type Delivery struct {
+	Id             int64           `json:"id"`
+	SubscriptionId int64           `json:"subscription_id"`
+	EventId        int64           `json:"event_id"`
+	EventType      string          `json:"event_type"`
+	Payload        json.RawMessage `json:"-"`
+	Status         string          `json:"status"`
+	Attempts       int             `json:"attempts"`
+	NextAttemptAt  string          `json:"next_attempt_at,omitempty"`
+	LastStatusCode int             `json:"last_status_code,omitempty"`
+	LastError      string          `json:"last_error,omitempty"`
+	DateCreated    string          `json:"date_created"`
+	/// Url and Secret of the subscription, loaded to send the delivery
+	Url    string `json:"-"`
+	Secret string `json:"-"`
+}
+
+type Deliveries []Delivery
+
+func (subscription *Subscription) Validate() rest_error.RestErr {
+	subscription.Url = strings.TrimSpace(subscription.Url)
+	callbackUrl, err := url.Parse(subscription.Url)
+	if err != nil || (callbackUrl.Scheme != "http" && callbackUrl.Scheme != "https") || callbackUrl.Host == "" {
+		return error_utils.NewFieldError("url", "invalid webhook url")
+	}
+	if len(subscription.EventTypes) == 0 {
+		return error_utils.NewFieldError("event_types", "at least one event type is required")
+	}
+	for _, eventType := range subscription.EventTypes {
+		if !ValidEventType(eventType) {
+			return error_utils.NewFieldError("event_types", "invalid event type: "+eventType)
+		}
+	}
+	return nil
+}
+
+/// ValidEventType reports whether eventType can be subscribed to
+func ValidEventType(eventType string) bool {
+	for _, known := range EventTypes {
+		if eventType == known {
+			return true
+		}
+	}
+	return false
+}
+
+/// ValidDeliveryStatus reports whether status is a delivery status
+func ValidDeliveryStatus(status string) bool {
+	return status == DeliveryPending || status == DeliveryDelivered || status == DeliveryDead
+}
+
+/// RecordAttempt records the outcome of sending the delivery at now. A
+/// failed delivery is retried after retryDelay of its attempts, once it
+/// has failed maxAttempts times it is dead.
+func (delivery *Delivery) RecordAttempt(statusCode int, sendErr error, now time.Time,
+	retryDelay func(attempts int) time.Duration, maxAttempts int) {
+	delivery.Attempts++
+	delivery.LastStatusCode = statusCode
+	delivery.NextAttemptAt = ""
+	delivery.LastError = ""
+	if sendErr == nil {
+		delivery.Status = DeliveryDelivered
+		return
+	}
+	delivery.LastError = sendErr.Error()
+	if delivery.Attempts >= maxAttempts {
+		delivery.Status = DeliveryDead
+		return
+	}
+	delivery.Status = DeliveryPending
+	delivery.NextAttemptAt = date_utils.FormatDbTime(now.Add(retryDelay(delivery.Attempts)))
+}
@@ -0,0 +1,80 @@
+package grpc_server
+
+import (
+	"context"
+	"github.com/Abacode7/bookstore_oauth-go/oauth"
+	"github.com/Abacode7/bookstore_users-api/services"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const (
+	authorizationMetadataKey = "authorization"
+	publicMetadataKey        = "x-public"
+	bearerPrefix             = "bearer "
+)
+
+type callerKey struct{}
+
+/// authenticate does for grpc calls what middlewares.Authenticate does
+/// for REST requests. The bearer token of the authorization metadata is
+/// validated by the oauth api and calls flagged x-public must carry one.
+/// The caller is stored in the context for the handlers.
+func authenticate(ctx context.Context, request interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	oauthRequest, err := toOauthRequest(ctx)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "error authenticating request")
+	}
+	if err := oauth.Authenticate(oauthRequest); err != nil {
+		return nil, status.Error(statusCode(err.Status, err.Message), err.Message)
+	}
+	callerId := oauth.GetCallerId(oauthRequest)
+	public := oauth.IsPublic(oauthRequest)
+	if callerId <= 0 && public {
+		return nil, status.Error(codes.Unauthenticated, "missing or invalid access token")
+	}
+	caller := services.Caller{UserId: callerId, Privileged: !public}
+	return handler(context.WithValue(ctx, callerKey{}, caller), request)
+}
+
+/// toOauthRequest presents the call's metadata the way the oauth client
+/// expects an http request: the token as access_token and the public
+/// flag as the X-Public header
+func toOauthRequest(ctx context.Context) (*http.Request, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	query := url.Values{}
+	for _, value := range md.Get(authorizationMetadataKey) {
+		if len(value) > len(bearerPrefix) && strings.EqualFold(value[:len(bearerPrefix)], bearerPrefix) {
+			query.Set("access_token", strings.TrimSpace(value[len(bearerPrefix):]))
+		}
+	}
+	request, err := http.NewRequest(http.MethodGet, "/?"+query.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	if values := md.Get(publicMetadataKey); len(values) > 0 {
+		request.Header.Set("X-Public", values[0])
+	}
+	return request, nil
+}
+
+/// getCaller gets the caller authenticate stored in ctx
+func getCaller(ctx context.Context) services.Caller {
+	caller, _ := ctx.Value(callerKey{}).(services.Caller)
+	return caller
+}
+
+/// authorizeUser checks that the caller may act on the user with
+/// userId: either it's themselves or the caller is privileged
+func authorizeUser(ctx context.Context, userId int64) error {
+	caller := getCaller(ctx)
+	if caller.Privileged || caller.UserId == userId {
+		return nil
+	}
+	return status.Error(codes.PermissionDenied, "not allowed to act on this user")
+}
@@ -0,0 +1,63 @@
+package grpc_server
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"github.com/Abacode7/bookstore_users-api/protos/userspb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"io/ioutil"
+	"net"
+)
+
+/// Config is where and how the grpc server listens. With CertFile and
+/// KeyFile it serves TLS, and with ClientCAFile too it requires clients
+/// to present a certificate signed by that CA.
+type Config struct {
+	Address      string
+	CertFile     string
+	KeyFile      string
+	ClientCAFile string
+}
+
+/// Start serves userServer as config says until the listener fails
+func Start(config Config, userServer userspb.UserServiceServer) error {
+	options := []grpc.ServerOption{grpc.UnaryInterceptor(authenticate)}
+	if config.CertFile != "" || config.KeyFile != "" {
+		tlsConfig, err := config.tlsConfig()
+		if err != nil {
+			return err
+		}
+		options = append(options, grpc.Creds(credentials.NewTLS(tlsConfig)))
+	}
+	listener, err := net.Listen("tcp", config.Address)
+	if err != nil {
+		return err
+	}
+	server := grpc.NewServer(options...)
+	userspb.RegisterUserServiceServer(server, userServer)
+	return server.Serve(listener)
+}
+
+func (config Config) tlsConfig() (*tls.Config, error) {
+	certificate, err := tls.LoadX509KeyPair(config.CertFile, config.KeyFile)
+	if err != nil {
+		return nil, err
+	}
+	tlsConfig := &tls.Config{Certificates: []tls.Certificate{certificate}, MinVersion: tls.VersionTLS12}
+	if config.ClientCAFile == "" {
+		return tlsConfig, nil
+	}
+	pem, err := ioutil.ReadFile(config.ClientCAFile)
+	if err != nil {
+		return nil, err
+	}
+	clientCAs := x509.NewCertPool()
+	if !clientCAs.AppendCertsFromPEM(pem) {
+		return nil, errors.New("no certificates in grpc client ca file")
+	}
+	tlsConfig.ClientCAs = clientCAs
+	tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	return tlsConfig, nil
+}
@@ -0,0 +1,44 @@
+package grpc_server
+
+import (
+	"github.com/Abacode7/bookstore_users-api/domain/users"
+	"github.com/Abacode7/bookstore_utils-go/v2/rest_error"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"net/http"
+)
+
+/// statusError converts err into a grpc status error with the code
+/// closest to its http status
+func statusError(err rest_error.RestErr) error {
+	return status.Error(statusCode(err.Status(), err.Message()), err.Message())
+}
+
+/// statusCode maps an http status to a grpc code. Conflicts are
+/// FailedPrecondition, the user's state doesn't allow the call, except
+/// for a taken email address which is AlreadyExists.
+func statusCode(httpStatus int, message string) codes.Code {
+	switch httpStatus {
+	case http.StatusBadRequest, http.StatusUnsupportedMediaType:
+		return codes.InvalidArgument
+	case http.StatusUnauthorized:
+		return codes.Unauthenticated
+	case http.StatusForbidden:
+		return codes.PermissionDenied
+	case http.StatusNotFound, http.StatusGone:
+		return codes.NotFound
+	case http.StatusConflict:
+		if message == users.EmailInUseMessage {
+			return codes.AlreadyExists
+		}
+		return codes.FailedPrecondition
+	case http.StatusFailedDependency:
+		return codes.Aborted
+	case http.StatusTooManyRequests:
+		return codes.ResourceExhausted
+	case http.StatusInternalServerError:
+		return codes.Internal
+	default:
+		return codes.Unknown
+	}
+}
@@ -0,0 +1,116 @@
+package grpc_server
+
+import (
+	"context"
+	"github.com/Abacode7/bookstore_users-api/domain/users"
+	"github.com/Abacode7/bookstore_users-api/protos/userspb"
+	"github.com/Abacode7/bookstore_users-api/services"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+/// userServer serves userspb.UserService on top of IUserService. Calls
+/// are authenticated by the authenticate interceptor and checked like
+/// their REST counterparts: public callers only act on their own user
+/// and get the public fields of others.
+type userServer struct {
+	userspb.UnimplementedUserServiceServer
+	userService services.IUserService
+}
+
+/// NewUserServer is userServer's constructor
+func NewUserServer(us services.IUserService) userspb.UserServiceServer {
+	return &userServer{userService: us}
+}
+
+func (us *userServer) CreateUser(ctx context.Context, request *userspb.CreateUserRequest) (*userspb.User, error) {
+	user, err := us.userService.CreateUser(users.User{
+		FirstName: request.FirstName,
+		LastName:  request.LastName,
+		Email:     request.Email,
+		Password:  request.Password,
+	})
+	if err != nil {
+		return nil, statusError(err)
+	}
+	return toProto(ctx, *user), nil
+}
+
+func (us *userServer) GetUser(ctx context.Context, request *userspb.GetUserRequest) (*userspb.User, error) {
+	user, err := us.userService.GetUser(request.Id)
+	if err != nil {
+		return nil, statusError(err)
+	}
+	return toProto(ctx, *user), nil
+}
+
+func (us *userServer) SearchUsers(ctx context.Context, request *userspb.SearchUsersRequest) (*userspb.SearchUsersResponse, error) {
+	result, err := us.userService.SearchUser(request.Status)
+	if err != nil {
+		return nil, statusError(err)
+	}
+	response := &userspb.SearchUsersResponse{Users: make([]*userspb.User, 0, len(result))}
+	for _, user := range result {
+		response.Users = append(response.Users, toProto(ctx, user))
+	}
+	return response, nil
+}
+
+func (us *userServer) UpdateUser(ctx context.Context, request *userspb.UpdateUserRequest) (*userspb.User, error) {
+	if err := authorizeUser(ctx, request.Id); err != nil {
+		return nil, err
+	}
+	user, err := us.userService.UpdateUser(!request.Partial, users.User{
+		Id:        request.Id,
+		FirstName: request.FirstName,
+		LastName:  request.LastName,
+		Email:     request.Email,
+		Status:    request.Status,
+		Password:  request.Password,
+	})
+	if err != nil {
+		return nil, statusError(err)
+	}
+	return toProto(ctx, *user), nil
+}
+
+func (us *userServer) DeleteUser(ctx context.Context, request *userspb.DeleteUserRequest) (*userspb.DeleteUserResponse, error) {
+	if err := authorizeUser(ctx, request.Id); err != nil {
+		return nil, err
+	}
+	if err := us.userService.DeleteUser(getCaller(ctx), request.Id); err != nil {
+		return nil, statusError(err)
+	}
+	return &userspb.DeleteUserResponse{}, nil
+}
+
+/// LoginUser checks a user's credentials for the oauth api. Only
+/// privileged callers may use it, the grpc api has no rate limits to
+/// stop public callers from guessing passwords.
+func (us *userServer) LoginUser(ctx context.Context, request *userspb.LoginUserRequest) (*userspb.User, error) {
+	if !getCaller(ctx).Privileged {
+		return nil, status.Error(codes.PermissionDenied, "not allowed to log users in")
+	}
+	user, err := us.userService.LoginUser(users.UserLoginRequest{Email: request.Email, Password: request.Password})
+	if err != nil {
+		return nil, statusError(err)
+	}
+	return toProto(ctx, *user), nil
+}
+
+/// toProto represents user for the caller, without its password. Public
+/// callers only get the names of users other than themselves.
+func toProto(ctx context.Context, user users.User) *userspb.User {
+	caller := getCaller(ctx)
+	if !caller.Privileged && caller.UserId != user.Id {
+		return &userspb.User{FirstName: user.FirstName, LastName: user.LastName}
+	}
+	return &userspb.User{
+		Id:          user.Id,
+		FirstName:   user.FirstName,
+		LastName:    user.LastName,
+		Email:       user.Email,
+		DateCreated: user.DateCreated,
+		Status:      user.Status,
+	}
+}
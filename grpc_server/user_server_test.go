@@ -0,0 +1,136 @@
+package grpc_server
+
+import (
+	"context"
+	"github.com/Abacode7/bookstore_users-api/domain/users"
+	"github.com/Abacode7/bookstore_users-api/protos/userspb"
+	"github.com/Abacode7/bookstore_users-api/services"
+	"github.com/Abacode7/bookstore_users-api/utils/error_utils"
+	"github.com/Abacode7/bookstore_utils-go/v2/rest_error"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"net/http"
+	"testing"
+)
+
+/// fakeUserService serves the calls the tests make and records the
+/// users updated and deleted
+type fakeUserService struct {
+	services.IUserService
+	updated []int64
+	deleted []int64
+}
+
+func (fs *fakeUserService) GetUser(userId int64) (*users.User, rest_error.RestErr) {
+	return &users.User{Id: userId, FirstName: "John", LastName: "Doe", Email: "john@example.com", Status: users.StatusActive}, nil
+}
+
+func (fs *fakeUserService) UpdateUser(isTotal bool, user users.User) (*users.User, rest_error.RestErr) {
+	fs.updated = append(fs.updated, user.Id)
+	return &user, nil
+}
+
+func (fs *fakeUserService) DeleteUser(caller services.Caller, userId int64) rest_error.RestErr {
+	fs.deleted = append(fs.deleted, userId)
+	return nil
+}
+
+func (fs *fakeUserService) LoginUser(request users.UserLoginRequest) (*users.User, rest_error.RestErr) {
+	return &users.User{Id: 1, Email: request.Email}, nil
+}
+
+func withCaller(caller services.Caller) context.Context {
+	return context.WithValue(context.Background(), callerKey{}, caller)
+}
+
+func TestUserServerChecksOwnership(t *testing.T) {
+	service := &fakeUserService{}
+	server := NewUserServer(service)
+	owner := withCaller(services.Caller{UserId: 1})
+	other := withCaller(services.Caller{UserId: 2})
+
+	if _, err := server.UpdateUser(other, &userspb.UpdateUserRequest{Id: 1}); status.Code(err) != codes.PermissionDenied {
+		t.Errorf("update by another user: got %v, want PermissionDenied", err)
+	}
+	if _, err := server.DeleteUser(other, &userspb.DeleteUserRequest{Id: 1}); status.Code(err) != codes.PermissionDenied {
+		t.Errorf("delete by another user: got %v, want PermissionDenied", err)
+	}
+	if _, err := server.UpdateUser(owner, &userspb.UpdateUserRequest{Id: 1}); err != nil {
+		t.Errorf("update by the owner: %v", err)
+	}
+	if _, err := server.DeleteUser(withCaller(services.Caller{Privileged: true}), &userspb.DeleteUserRequest{Id: 1}); err != nil {
+		t.Errorf("delete by a privileged caller: %v", err)
+	}
+	if len(service.updated) != 1 || len(service.deleted) != 1 {
+		t.Errorf("got updates %v and deletes %v, want one of each", service.updated, service.deleted)
+	}
+}
+
+func TestUserServerLoginIsPrivileged(t *testing.T) {
+	server := NewUserServer(&fakeUserService{})
+	request := &userspb.LoginUserRequest{Email: "john@example.com", Password: "secret"}
+	if _, err := server.LoginUser(withCaller(services.Caller{UserId: 1}), request); status.Code(err) != codes.PermissionDenied {
+		t.Errorf("login by a public caller: got %v, want PermissionDenied", err)
+	}
+	if _, err := server.LoginUser(withCaller(services.Caller{Privileged: true}), request); err != nil {
+		t.Errorf("login by a privileged caller: %v", err)
+	}
+}
+
+func TestUserServerHidesPrivateFieldsOfOthers(t *testing.T) {
+	server := NewUserServer(&fakeUserService{})
+	user, err := server.GetUser(withCaller(services.Caller{UserId: 2}), &userspb.GetUserRequest{Id: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.Id != 0 || user.Email != "" || user.Status != "" || user.FirstName != "John" {
+		t.Errorf("got %v, want only the names", user)
+	}
+	user, err = server.GetUser(withCaller(services.Caller{UserId: 1}), &userspb.GetUserRequest{Id: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.Email != "john@example.com" {
+		t.Errorf("got %v, want the owner to see their email", user)
+	}
+}
+
+func TestAuthenticate(t *testing.T) {
+	info := &grpc.UnaryServerInfo{FullMethod: "/users.v1.UserService/GetUser"}
+	var got services.Caller
+	handler := func(ctx context.Context, request interface{}) (interface{}, error) {
+		got = getCaller(ctx)
+		return nil, nil
+	}
+
+	public := metadata.NewIncomingContext(context.Background(), metadata.Pairs(publicMetadataKey, "true"))
+	if _, err := authenticate(public, nil, info, handler); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("anonymous public call: got %v, want Unauthenticated", err)
+	}
+	if _, err := authenticate(context.Background(), nil, info, handler); err != nil {
+		t.Fatalf("anonymous internal call: %v", err)
+	}
+	if !got.Privileged {
+		t.Errorf("got caller %+v, want an internal call to be privileged", got)
+	}
+}
+
+func TestStatusCode(t *testing.T) {
+	cases := []struct {
+		err  rest_error.RestErr
+		want codes.Code
+	}{
+		{error_utils.NewConflictError(users.EmailInUseMessage), codes.AlreadyExists},
+		{error_utils.NewConflictError("cannot change status from deleted to active"), codes.FailedPrecondition},
+		{error_utils.NewGoneError("export has expired"), codes.NotFound},
+		{error_utils.NewFailedDependencyError("not attempted"), codes.Aborted},
+		{error_utils.NewRestErr("teapot", http.StatusTeapot), codes.Unknown},
+	}
+	for _, c := range cases {
+		if got := status.Code(statusError(c.err)); got != c.want {
+			t.Errorf("%d %q: got %v, want %v", c.err.Status(), c.err.Message(), got, c.want)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package middlewares
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"time"
+)
+
+const (
+	ApiV1 = 1
+	ApiV2 = 2
+
+	apiVersionKey = "api_version"
+)
+
+/// Deprecation describes when a version of the api was deprecated and,
+/// once decided, when it stops being served
+type Deprecation struct {
+	DeprecatedAt time.Time
+	/// Sunset is left zero until a date is decided
+	Sunset time.Time
+	/// SuccessorPrefix turns a request path into the path of the same
+	/// route in the version replacing this one
+	SuccessorPrefix string
+}
+
+/// ApiVersion records the api version the route group serves
+func ApiVersion(version int) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(apiVersionKey, version)
+		c.Next()
+	}
+}
+
+/// GetApiVersion gets the api version of the request, v1 when ApiVersion
+/// didn't run
+func GetApiVersion(c *gin.Context) int {
+	if version := c.GetInt(apiVersionKey); version > 0 {
+		return version
+	}
+	return ApiV1
+}
+
+/// Deprecated announces the deprecation in the Deprecation (RFC 9745)
+/// and Sunset (RFC 8594) headers and links to the successor version
+func Deprecated(deprecation Deprecation) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header("Deprecation", fmt.Sprintf("@%d", deprecation.DeprecatedAt.Unix()))
+		if !deprecation.Sunset.IsZero() {
+			c.Header("Sunset", deprecation.Sunset.UTC().Format(http.TimeFormat))
+		}
+		if deprecation.SuccessorPrefix != "" {
+			c.Header("Link", fmt.Sprintf(`<%s%s>; rel="successor-version"`, deprecation.SuccessorPrefix, c.Request.URL.Path))
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,50 @@
+package middlewares
+
+import (
+	"github.com/Abacode7/bookstore_oauth-go/oauth"
+	"github.com/Abacode7/bookstore_users-api/problems"
+	"github.com/Abacode7/bookstore_users-api/utils/error_utils"
+	"github.com/Abacode7/bookstore_utils-go/v2/rest_error"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	callerIdKey = "caller_id"
+	clientIdKey = "client_id"
+)
+
+/// Authenticate validates the request's access token and stores the
+/// caller and client ids in the context. Public requests must carry a
+/// valid token, internal ones may be anonymous.
+func Authenticate(c *gin.Context) {
+	if err := oauth.Authenticate(c.Request); err != nil {
+		problems.Abort(c, error_utils.NewRestErr(err.Message, err.Status))
+		return
+	}
+	callerId := oauth.GetCallerId(c.Request)
+	if callerId <= 0 && oauth.IsPublic(c.Request) {
+		restErr := rest_error.NewUnauthorizedError("missing or invalid access token")
+		problems.Abort(c, restErr)
+		return
+	}
+	c.Set(callerIdKey, callerId)
+	c.Set(clientIdKey, oauth.GetClientId(c.Request))
+	c.Next()
+}
+
+/// GetCallerId gets the id of the user the request was authenticated as,
+/// 0 if Authenticate didn't run or the request is anonymous
+func GetCallerId(c *gin.Context) int64 {
+	return c.GetInt64(callerIdKey)
+}
+
+/// GetClientId gets the id of the oauth client the request was made with
+func GetClientId(c *gin.Context) int64 {
+	return c.GetInt64(clientIdKey)
+}
+
+/// IsPrivileged reports whether the request comes from inside the
+/// network, where callers may act on any user's records
+func IsPrivileged(c *gin.Context) bool {
+	return !oauth.IsPublic(c.Request)
+}
@@ -0,0 +1,82 @@
+package middlewares
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+/// memoryStoreSweepInterval is how often idle buckets are evicted
+const memoryStoreSweepInterval = time.Minute
+
+/// IRateLimitStore keeps token buckets. Implementations shared between
+/// replicas (e.g. redis) must perform Take atomically.
+type IRateLimitStore interface {
+	/// Take removes a token from the bucket at key, refilled at rate tokens
+	/// per second up to burst. It reports whether a token was available and,
+	/// if not, how long until one will be.
+	Take(key string, rate float64, burst int) (bool, time.Duration, error)
+}
+
+type bucket struct {
+	tokens   float64
+	lastSeen time.Time
+	/// refill is how long the bucket takes to fill up from empty
+	refill time.Duration
+}
+
+type memoryRateLimitStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+/// NewMemoryRateLimitStore is a constructor for an in-process IRateLimitStore
+func NewMemoryRateLimitStore() IRateLimitStore {
+	return &memoryRateLimitStore{
+		buckets:   make(map[string]*bucket),
+		lastSweep: time.Now(),
+		now:       time.Now,
+	}
+}
+
+func (s *memoryRateLimitStore) Take(key string, rate float64, burst int) (bool, time.Duration, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	if now.Sub(s.lastSweep) > memoryStoreSweepInterval {
+		s.sweep(now)
+	}
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{
+			tokens:   float64(burst),
+			lastSeen: now,
+			refill:   time.Duration(float64(burst) / rate * float64(time.Second)),
+		}
+		s.buckets[key] = b
+	}
+	b.tokens = math.Min(float64(burst), b.tokens+now.Sub(b.lastSeen).Seconds()*rate)
+	b.lastSeen = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0, nil
+	}
+	wait := time.Duration((1 - b.tokens) / rate * float64(time.Second))
+	return false, wait, nil
+}
+
+/// sweep drops buckets that have been idle long enough to be full again,
+/// since a missing bucket is treated as a full one
+func (s *memoryRateLimitStore) sweep(now time.Time) {
+	for key, b := range s.buckets {
+		if now.Sub(b.lastSeen) > b.refill {
+			delete(s.buckets, key)
+		}
+	}
+	s.lastSweep = now
+}
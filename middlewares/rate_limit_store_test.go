@@ -0,0 +1,66 @@
+package middlewares
+
+import (
+	"testing"
+	"time"
+)
+
+/// newTestRateLimitStore returns a memory store whose clock is moved by
+/// the returned function
+func newTestRateLimitStore() (IRateLimitStore, func(time.Duration)) {
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	store := NewMemoryRateLimitStore().(*memoryRateLimitStore)
+	store.lastSweep = now
+	store.now = func() time.Time { return now }
+	return store, func(d time.Duration) { now = now.Add(d) }
+}
+
+func TestMemoryRateLimitStoreAllowsBurstThenRefills(t *testing.T) {
+	store, advance := newTestRateLimitStore()
+
+	for i := 0; i < 3; i++ {
+		if allowed, _, _ := store.Take("ip:1", 1, 3); !allowed {
+			t.Fatalf("request %d of the burst was throttled", i)
+		}
+	}
+	allowed, retryAfter, err := store.Take("ip:1", 1, 3)
+	if err != nil || allowed || retryAfter != time.Second {
+		t.Fatalf("got allowed %t, retry after %s, %v, want throttled for 1s", allowed, retryAfter, err)
+	}
+
+	advance(500 * time.Millisecond)
+	if allowed, retryAfter, _ := store.Take("ip:1", 1, 3); allowed || retryAfter != 500*time.Millisecond {
+		t.Errorf("half a token: got allowed %t, retry after %s, want throttled for 500ms", allowed, retryAfter)
+	}
+	advance(500 * time.Millisecond)
+	if allowed, _, _ := store.Take("ip:1", 1, 3); !allowed {
+		t.Error("a refilled token was throttled")
+	}
+
+	/// A long idle time refills up to the burst only
+	advance(time.Hour)
+	for i := 0; i < 3; i++ {
+		if allowed, _, _ := store.Take("ip:1", 1, 3); !allowed {
+			t.Fatalf("request %d after refilling was throttled", i)
+		}
+	}
+	if allowed, _, _ := store.Take("ip:1", 1, 3); allowed {
+		t.Error("got more than the burst after a long idle time")
+	}
+}
+
+func TestMemoryRateLimitStoreKeepsKeysApart(t *testing.T) {
+	store, _ := newTestRateLimitStore()
+
+	if allowed, _, _ := store.Take("export:caller:7", 1, 1); !allowed {
+		t.Fatal("the first request of caller 7 was throttled")
+	}
+	if allowed, _, _ := store.Take("export:caller:7", 1, 1); allowed {
+		t.Error("caller 7 went over its burst")
+	}
+	for _, key := range []string{"export:caller:8", "signup:caller:7"} {
+		if allowed, _, _ := store.Take(key, 1, 1); !allowed {
+			t.Errorf("%s was throttled by caller 7's bucket", key)
+		}
+	}
+}
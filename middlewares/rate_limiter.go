@@ -0,0 +1,99 @@
+package middlewares
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/Abacode7/bookstore_users-api/problems"
+	"github.com/Abacode7/bookstore_users-api/utils/error_utils"
+	"github.com/Abacode7/bookstore_utils-go/v2/logger"
+	"github.com/gin-gonic/gin"
+	"io"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"strings"
+)
+
+/// maxEmailKeyBodySize bounds the request body ByEmail reads
+const maxEmailKeyBodySize = 64 << 10
+
+/// RateLimitKey extracts the value a request is throttled by. An empty
+/// key means the request isn't throttled on that dimension.
+type RateLimitKey func(c *gin.Context) string
+
+/// RateLimit configures the token bucket of one route
+type RateLimit struct {
+	/// Name namespaces the buckets so routes don't share them
+	Name string
+	/// Rate is the number of requests per second a key regains
+	Rate float64
+	/// Burst is the number of requests a key can make at once
+	Burst int
+	/// Keys are the dimensions a request is throttled on, every one
+	/// of them must have a token available for the request to pass
+	Keys []RateLimitKey
+}
+
+/// ByClientIP throttles requests per client ip address
+func ByClientIP(c *gin.Context) string {
+	return "ip:" + c.ClientIP()
+}
+
+/// ByEmail throttles requests per email in the json request body. Bodies
+/// over maxEmailKeyBodySize aren't throttled by email, reading them
+/// further fails.
+func ByEmail(c *gin.Context) string {
+	if c.Request.Body == nil {
+		return ""
+	}
+	limitedBody := http.MaxBytesReader(c.Writer, c.Request.Body, maxEmailKeyBodySize)
+	body, err := ioutil.ReadAll(limitedBody)
+	c.Request.Body = ioutil.NopCloser(io.MultiReader(bytes.NewReader(body), limitedBody))
+	if err != nil {
+		return ""
+	}
+
+	var request struct {
+		Email string `json:"email"`
+	}
+	if err := json.Unmarshal(body, &request); err != nil || request.Email == "" {
+		return ""
+	}
+	return "email:" + strings.ToLower(strings.TrimSpace(request.Email))
+}
+
+/// ByCaller throttles requests per authenticated caller, it only keys
+/// requests of routes that run Authenticate before the rate limiter
+func ByCaller(c *gin.Context) string {
+	callerId := GetCallerId(c)
+	if callerId <= 0 {
+		return ""
+	}
+	return fmt.Sprintf("caller:%d", callerId)
+}
+
+/// RateLimiter rejects requests with 429 Too Many Requests once any of the
+/// route's keys runs out of tokens. Store failures let requests through.
+func RateLimiter(store IRateLimitStore, limit RateLimit) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		for _, keyFunc := range limit.Keys {
+			key := keyFunc(c)
+			if key == "" {
+				continue
+			}
+			allowed, retryAfter, err := store.Take(limit.Name+":"+key, limit.Rate, limit.Burst)
+			if err != nil {
+				logger.Error("error taking rate limit token", err)
+				continue
+			}
+			if !allowed {
+				c.Header("Retry-After", fmt.Sprintf("%d", int64(math.Ceil(retryAfter.Seconds()))))
+				restErr := error_utils.NewTooManyRequestsError("too many requests")
+				problems.Abort(c, restErr)
+				return
+			}
+		}
+		c.Next()
+	}
+}
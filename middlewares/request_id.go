@@ -0,0 +1,40 @@
+package middlewares
+
+import (
+	"github.com/Abacode7/bookstore_users-api/utils/crypto_utils"
+	"github.com/Abacode7/bookstore_utils-go/v2/logger"
+	"github.com/gin-gonic/gin"
+	"regexp"
+)
+
+const (
+	RequestIdHeader = "X-Request-Id"
+	requestIdKey    = "request_id"
+)
+
+/// requestIdRegexp limits the request ids taken from callers to ones
+/// safe to log and echo back
+var requestIdRegexp = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+/// RequestId identifies the request by the X-Request-Id the caller sent,
+/// or a new id, and echoes it in the response
+func RequestId(c *gin.Context) {
+	requestId := c.GetHeader(RequestIdHeader)
+	if !requestIdRegexp.MatchString(requestId) {
+		var err error
+		if requestId, err = crypto_utils.GenerateToken(); err != nil {
+			logger.Error("error generating request id", err)
+			requestId = ""
+		}
+	}
+	if requestId != "" {
+		c.Set(requestIdKey, requestId)
+		c.Header(RequestIdHeader, requestId)
+	}
+	c.Next()
+}
+
+/// GetRequestId gets the id RequestId gave the request
+func GetRequestId(c *gin.Context) string {
+	return c.GetString(requestIdKey)
+}
@@ -0,0 +1,144 @@
+package middlewares
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/Abacode7/bookstore_users-api/openapi"
+	"github.com/Abacode7/bookstore_users-api/problems"
+	"github.com/Abacode7/bookstore_users-api/utils/error_utils"
+	"github.com/Abacode7/bookstore_utils-go/v2/rest_error"
+	"github.com/gin-gonic/gin"
+	"io/ioutil"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+/// maxBodyBytes is the largest request body read for validation, and so
+/// the largest any route accepts
+const maxBodyBytes = 1 << 20
+
+/// errBodyTooLarge is returned for bodies over maxBodyBytes
+var errBodyTooLarge = errors.New("request body is too large")
+
+/// routeParamRegexp matches the :name segments of gin routes, written
+/// {name} in OpenAPI paths
+var routeParamRegexp = regexp.MustCompile(`:([^/]+)`)
+
+/// ValidateRequest rejects requests whose parameters or body don't match
+/// the operation doc describes for their route. Routes doc doesn't
+/// describe are let through.
+func ValidateRequest(doc *openapi.Document) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		path := routeParamRegexp.ReplaceAllString(c.FullPath(), "{$1}")
+		operation := doc.FindOperation(strings.ToLower(c.Request.Method), path)
+		if operation == nil {
+			c.Next()
+			return
+		}
+		if err := validateParameters(doc, operation, c); err != nil {
+			abortInvalid(c, err)
+			return
+		}
+		if err := validateBody(doc, operation, c); err == errBodyTooLarge {
+			problems.Abort(c, error_utils.NewRequestEntityTooLargeError(err.Error()))
+			return
+		} else if err != nil {
+			abortInvalid(c, err)
+			return
+		}
+		c.Next()
+	}
+}
+
+func validateParameters(doc *openapi.Document, operation *openapi.Operation, c *gin.Context) error {
+	for _, parameter := range operation.Parameters {
+		var values []string
+		switch parameter.In {
+		case "path":
+			values = []string{c.Param(parameter.Name)}
+		case "query":
+			values = c.QueryArray(parameter.Name)
+		case "header":
+			if value := c.GetHeader(parameter.Name); value != "" {
+				values = []string{value}
+			}
+		}
+		if len(values) == 0 {
+			if parameter.Required {
+				return &openapi.ValidationError{Path: parameter.Name, Reason: fmt.Sprintf("%s parameter is required", parameter.In)}
+			}
+			continue
+		}
+		for _, value := range values {
+			if err := doc.ValidateParameter(parameter.Schema, value, parameter.Name); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+/// validateBody checks the body against the schema of its content type,
+/// falling back to the json schema for clients that don't set one. The
+/// body is restored for the handlers that bind it, and bodies over
+/// maxBodyBytes are rejected without being read further.
+func validateBody(doc *openapi.Document, operation *openapi.Operation, c *gin.Context) error {
+	if operation.RequestBody == nil {
+		return nil
+	}
+	mediaType, ok := operation.RequestBody.Content[c.ContentType()]
+	if !ok {
+		if mediaType, ok = operation.RequestBody.Content[gin.MIMEJSON]; !ok {
+			return nil
+		}
+	}
+
+	var body []byte
+	if c.Request.Body != nil {
+		var err error
+		reader := http.MaxBytesReader(c.Writer, c.Request.Body, maxBodyBytes)
+		if body, err = ioutil.ReadAll(reader); err != nil {
+			if len(body) == maxBodyBytes {
+				return errBodyTooLarge
+			}
+			return fmt.Errorf("invalid request body")
+		}
+		c.Request.Body = ioutil.NopCloser(bytes.NewBuffer(body))
+	}
+	if len(bytes.TrimSpace(body)) == 0 {
+		if operation.RequestBody.Required {
+			return fmt.Errorf("request body is required")
+		}
+		return nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return fmt.Errorf("invalid json body")
+	}
+	return doc.ValidateValue(mediaType.Schema, value, "body")
+}
+
+/// abortInvalid responds with a validation error for the invalid field,
+/// naming body fields without their body prefix
+func abortInvalid(c *gin.Context, err error) {
+	validationErr, ok := err.(*openapi.ValidationError)
+	if !ok {
+		problems.Abort(c, rest_error.NewBadRequestError(err.Error()))
+		return
+	}
+	message := err.Error()
+	field := strings.TrimPrefix(strings.TrimPrefix(validationErr.Path, "body"), ".")
+	if field != "" {
+		message = field + " " + validationErr.Reason
+	}
+	problems.Abort(c, error_utils.NewValidationError(message, error_utils.FieldError{
+		Field:   field,
+		Message: validationErr.Reason,
+	}))
+}
@@ -0,0 +1,30 @@
+package notifiers
+
+import (
+	"fmt"
+	"github.com/Abacode7/bookstore_utils-go/v2/logger"
+)
+
+/// Message is a notification sent to a user's email address
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+type INotifier interface {
+	Notify(Message) error
+}
+
+type logNotifier struct{}
+
+/// NewLogNotifier is a constructor for a notifier that only logs messages,
+/// for environments without a mail provider
+func NewLogNotifier() INotifier {
+	return &logNotifier{}
+}
+
+func (ln *logNotifier) Notify(message Message) error {
+	logger.Info(fmt.Sprintf("notification to %s: %s\n%s", message.To, message.Subject, message.Body))
+	return nil
+}
@@ -0,0 +1,722 @@
+package openapi
+
+import (
+	"github.com/Abacode7/bookstore_users-api/domain/exports"
+	"github.com/Abacode7/bookstore_users-api/domain/organizations"
+	"github.com/Abacode7/bookstore_users-api/domain/users"
+	"github.com/Abacode7/bookstore_users-api/domain/webhooks"
+	"github.com/Abacode7/bookstore_users-api/problems"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const (
+	jsonContentType = "application/json"
+	securityScheme  = "accessToken"
+)
+
+/// unversionedPaths are served once, outside the api versions
+var unversionedPaths = map[string]bool{"/ping": true, "/openapi.json": true}
+
+/// NewDocument describes every route mapped in app/url_mapping.go
+func NewDocument() *Document {
+	doc := &Document{
+		OpenAPI: "3.0.3",
+		Info: Info{
+			Title: "bookstore users api",
+			Description: "Users, their profiles, addresses and organizations. Routes under /internal are for internal callers only. " +
+				"Errors are RFC 7807 problems for clients that accept application/problem+json.",
+			Version: "1.0.0",
+		},
+		Paths: make(map[string]PathItem),
+		Components: Components{
+			Schemas: schemas(),
+			SecuritySchemes: map[string]SecurityScheme{
+				securityScheme: {
+					Type:        "apiKey",
+					Name:        "access_token",
+					In:          "query",
+					Description: "oauth access token, internal callers set X-Public: false instead",
+				},
+			},
+		},
+	}
+
+	doc.add(http.MethodGet, "/ping", &Operation{
+		OperationId: "ping", Tags: []string{"health"},
+		Responses: map[string]Response{"200": {Description: "the api is up"}},
+	})
+	doc.add(http.MethodGet, "/openapi.json", &Operation{
+		OperationId: "getOpenAPI", Tags: []string{"health"},
+		Responses: map[string]Response{"200": jsonResponse("this document", &Schema{Type: "object"})},
+	})
+
+	userParam := pathParam("user_id")
+	doc.add(http.MethodPost, "/users", &Operation{
+		OperationId: "createUser", Summary: "Sign up", Tags: []string{"users"},
+		RequestBody: jsonBody(ref("NewUser")),
+		Responses:   responses(http.StatusOK, ref("User"), http.StatusBadRequest, http.StatusConflict, http.StatusTooManyRequests),
+	})
+	doc.add(http.MethodGet, "/users/{user_id}", &Operation{
+		OperationId: "getUser", Tags: []string{"users"},
+		Parameters: []Parameter{userParam, fieldsParam(), includeParam()},
+		Responses: responses(http.StatusOK, ref("User"), http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden,
+			http.StatusNotFound, http.StatusGone),
+	})
+	doc.add(http.MethodPut, "/users/{user_id}", secured(&Operation{
+		OperationId: "replaceUser", Tags: []string{"users"},
+		Parameters:  []Parameter{userParam},
+		RequestBody: jsonBody(ref("UserReplacement")),
+		Responses: responses(http.StatusOK, ref("User"), http.StatusBadRequest, http.StatusForbidden, http.StatusNotFound,
+			http.StatusConflict, http.StatusGone),
+	}))
+	doc.add(http.MethodPatch, "/users/{user_id}", secured(&Operation{
+		OperationId: "updateUser", Summary: "Partially update a user with a json body, a merge patch or a json patch", Tags: []string{"users"},
+		Parameters: []Parameter{userParam},
+		RequestBody: &RequestBody{Required: true, Content: map[string]MediaType{
+			jsonContentType:             {Schema: ref("UserUpdate")},
+			users.MergePatchContentType: {Schema: ref("UserMergePatch")},
+			users.JSONPatchContentType:  {Schema: ref("JSONPatch")},
+		}},
+		Responses: responses(http.StatusOK, ref("User"), http.StatusBadRequest, http.StatusForbidden, http.StatusNotFound,
+			http.StatusConflict, http.StatusGone, http.StatusUnsupportedMediaType),
+	}))
+	doc.add(http.MethodDelete, "/users/{user_id}", secured(&Operation{
+		OperationId: "deleteUser", Summary: "Move a user to the deleted status, keeping it as a tombstone", Tags: []string{"users"},
+		Parameters: []Parameter{userParam},
+		Responses:  responses(http.StatusOK, ref("Status"), http.StatusBadRequest, http.StatusForbidden, http.StatusNotFound, http.StatusConflict),
+	}))
+	doc.add(http.MethodPost, "/users/batch-get", &Operation{
+		OperationId: "batchGetUsers", Summary: "Get several users by id, in the order asked for", Tags: []string{"users"},
+		Parameters:  []Parameter{fieldsParam(), includeParam()},
+		RequestBody: jsonBody(ref("BatchGetRequest")),
+		Responses: responses(http.StatusOK, ref("BatchGetResult"), http.StatusBadRequest, http.StatusUnauthorized,
+			http.StatusForbidden),
+	})
+	doc.add(http.MethodPost, "/users/login", &Operation{
+		OperationId: "loginUser", Tags: []string{"users"},
+		RequestBody: jsonBody(object(map[string]*Schema{"email": str(), "password": password()}, "email", "password")),
+		Responses:   responses(http.StatusOK, ref("User"), http.StatusBadRequest, http.StatusNotFound, http.StatusTooManyRequests),
+	})
+	doc.add(http.MethodPost, "/users/email/confirm", &Operation{
+		OperationId: "confirmEmailChange", Tags: []string{"users"},
+		RequestBody: jsonBody(ref("Token")),
+		Responses:   responses(http.StatusOK, ref("User"), http.StatusBadRequest, http.StatusNotFound, http.StatusConflict),
+	})
+	doc.add(http.MethodPost, "/users/email/cancel", &Operation{
+		OperationId: "cancelEmailChange", Tags: []string{"users"},
+		RequestBody: jsonBody(ref("Token")),
+		Responses:   responses(http.StatusOK, ref("Status"), http.StatusBadRequest, http.StatusNotFound),
+	})
+	doc.add(http.MethodPost, "/users/invitations/accept", &Operation{
+		OperationId: "acceptUserInvitation", Tags: []string{"invitations"},
+		RequestBody: jsonBody(object(map[string]*Schema{
+			"token": str(), "password": password(), "first_name": str(), "last_name": str(),
+		}, "token", "password")),
+		Responses: responses(http.StatusOK, ref("User"), http.StatusBadRequest, http.StatusNotFound, http.StatusConflict),
+	})
+
+	addressParam := pathParam("address_id")
+	doc.add(http.MethodGet, "/users/{user_id}/addresses", secured(&Operation{
+		OperationId: "listAddresses", Tags: []string{"addresses"},
+		Parameters: []Parameter{userParam},
+		Responses:  responses(http.StatusOK, array(ref("Address")), http.StatusBadRequest, http.StatusForbidden),
+	}))
+	doc.add(http.MethodPost, "/users/{user_id}/addresses", secured(&Operation{
+		OperationId: "createAddress", Tags: []string{"addresses"},
+		Parameters:  []Parameter{userParam},
+		RequestBody: jsonBody(ref("Address")),
+		Responses:   responses(http.StatusCreated, ref("Address"), http.StatusBadRequest, http.StatusForbidden, http.StatusNotFound),
+	}))
+	doc.add(http.MethodGet, "/users/{user_id}/addresses/{address_id}", secured(&Operation{
+		OperationId: "getAddress", Tags: []string{"addresses"},
+		Parameters: []Parameter{userParam, addressParam},
+		Responses:  responses(http.StatusOK, ref("Address"), http.StatusBadRequest, http.StatusForbidden, http.StatusNotFound),
+	}))
+	for method, id := range map[string]string{http.MethodPut: "replaceAddress", http.MethodPatch: "updateAddress"} {
+		doc.add(method, "/users/{user_id}/addresses/{address_id}", secured(&Operation{
+			OperationId: id, Tags: []string{"addresses"},
+			Parameters:  []Parameter{userParam, addressParam},
+			RequestBody: jsonBody(ref("Address")),
+			Responses:   responses(http.StatusOK, ref("Address"), http.StatusBadRequest, http.StatusForbidden, http.StatusNotFound),
+		}))
+	}
+	doc.add(http.MethodDelete, "/users/{user_id}/addresses/{address_id}", secured(&Operation{
+		OperationId: "deleteAddress", Tags: []string{"addresses"},
+		Parameters: []Parameter{userParam, addressParam},
+		Responses:  responses(http.StatusOK, ref("Status"), http.StatusBadRequest, http.StatusForbidden, http.StatusNotFound),
+	}))
+
+	orgParam := pathParam("org_id")
+	exportsPath := "/users/{user_id}/exports"
+	exportParam := pathParam("export_id")
+	doc.add(http.MethodPost, exportsPath, secured(&Operation{
+		OperationId: "requestExport", Tags: []string{"users"},
+		Summary: "Assemble the personal data held about a user into an archive, in the background. " +
+			"The export's status resource is in the Location header.",
+		Parameters:  []Parameter{userParam},
+		RequestBody: &RequestBody{Content: map[string]MediaType{jsonContentType: {Schema: ref("ExportRequest")}}},
+		Responses: responses(http.StatusAccepted, ref("Export"), http.StatusBadRequest, http.StatusForbidden,
+			http.StatusNotFound, http.StatusConflict, http.StatusTooManyRequests),
+	}))
+	doc.add(http.MethodGet, exportsPath+"/{export_id}", secured(&Operation{
+		OperationId: "getExport", Summary: "The status of an export, with its download_url once completed", Tags: []string{"users"},
+		Parameters: []Parameter{userParam, exportParam},
+		Responses:  responses(http.StatusOK, ref("Export"), http.StatusBadRequest, http.StatusForbidden, http.StatusNotFound),
+	}))
+	downloadResponses := responses(http.StatusOK, nil, http.StatusBadRequest, http.StatusForbidden, http.StatusNotFound,
+		http.StatusConflict, http.StatusGone)
+	downloadResponses["200"] = Response{Description: "The archive", Content: map[string]MediaType{
+		jsonContentType:   {Schema: &Schema{Type: "object"}},
+		"application/zip": {Schema: &Schema{Type: "string", Format: "binary"}},
+	}}
+	doc.add(http.MethodGet, exportsPath+"/{export_id}/download", secured(&Operation{
+		OperationId: "downloadExport", Summary: "The archive of a completed export, until it expires", Tags: []string{"users"},
+		Parameters: []Parameter{userParam, exportParam},
+		Responses:  downloadResponses,
+	}))
+	doc.add(http.MethodGet, "/users/{user_id}/organizations", secured(&Operation{
+		OperationId: "listUserOrganizations", Tags: []string{"organizations"},
+		Parameters: []Parameter{userParam},
+		Responses:  responses(http.StatusOK, array(ref("Membership")), http.StatusBadRequest, http.StatusForbidden),
+	}))
+	doc.add(http.MethodPost, "/organizations", secured(&Operation{
+		OperationId: "createOrganization", Tags: []string{"organizations"},
+		RequestBody: jsonBody(object(map[string]*Schema{"name": nonEmpty()}, "name")),
+		Responses:   responses(http.StatusCreated, ref("Organization"), http.StatusBadRequest),
+	}))
+	doc.add(http.MethodPost, "/organizations/invitations/accept", secured(&Operation{
+		OperationId: "acceptOrganizationInvitation", Tags: []string{"organizations"},
+		RequestBody: jsonBody(ref("Token")),
+		Responses:   responses(http.StatusOK, ref("Member"), http.StatusBadRequest, http.StatusForbidden, http.StatusNotFound),
+	}))
+	doc.add(http.MethodGet, "/organizations/{org_id}", secured(&Operation{
+		OperationId: "getOrganization", Tags: []string{"organizations"},
+		Parameters: []Parameter{orgParam},
+		Responses:  responses(http.StatusOK, ref("Organization"), http.StatusBadRequest, http.StatusForbidden, http.StatusNotFound),
+	}))
+	doc.add(http.MethodGet, "/organizations/{org_id}/members", secured(&Operation{
+		OperationId: "listMembers", Tags: []string{"organizations"},
+		Parameters: []Parameter{orgParam},
+		Responses:  responses(http.StatusOK, array(ref("Member")), http.StatusBadRequest, http.StatusForbidden),
+	}))
+	doc.add(http.MethodPost, "/organizations/{org_id}/invitations", secured(&Operation{
+		OperationId: "inviteMember", Tags: []string{"organizations"},
+		Parameters:  []Parameter{orgParam},
+		RequestBody: jsonBody(object(map[string]*Schema{"email": nonEmpty(), "role": ref("Role")}, "email", "role")),
+		Responses:   responses(http.StatusCreated, ref("OrganizationInvitation"), http.StatusBadRequest, http.StatusForbidden, http.StatusConflict),
+	}))
+	doc.add(http.MethodPut, "/organizations/{org_id}/members/{user_id}", secured(&Operation{
+		OperationId: "updateMember", Tags: []string{"organizations"},
+		Parameters:  []Parameter{orgParam, userParam},
+		RequestBody: jsonBody(object(map[string]*Schema{"role": ref("Role")}, "role")),
+		Responses:   responses(http.StatusOK, ref("Member"), http.StatusBadRequest, http.StatusForbidden, http.StatusNotFound, http.StatusConflict),
+	}))
+	doc.add(http.MethodDelete, "/organizations/{org_id}/members/{user_id}", secured(&Operation{
+		OperationId: "removeMember", Tags: []string{"organizations"},
+		Parameters: []Parameter{orgParam, userParam},
+		Responses:  responses(http.StatusOK, ref("Status"), http.StatusBadRequest, http.StatusForbidden, http.StatusNotFound, http.StatusConflict),
+	}))
+
+	doc.add(http.MethodGet, "/internal/users/search", &Operation{
+		OperationId: "searchUsersByStatus", Tags: []string{"internal"},
+		Parameters: []Parameter{requiredQueryParam("status", ref("UserStatus")), fieldsParam(), includeParam()},
+		Responses:  responses(http.StatusOK, array(ref("User")), http.StatusBadRequest, http.StatusForbidden, http.StatusInternalServerError),
+	})
+	doc.add(http.MethodPost, "/internal/users/batch", secured(&Operation{
+		OperationId: "applyUserBatch", Tags: []string{"internal"},
+		Summary: "Create, update, delete or change the status of many users, in one transaction when atomic. " +
+			"Every operation gets a result, a failed atomic batch reports 424 for the operations it rolled back.",
+		RequestBody: jsonBody(ref("BatchRequest")),
+		Responses:   responses(http.StatusOK, ref("BatchResult"), http.StatusBadRequest, http.StatusForbidden),
+	}))
+	for path, id := range map[string]string{"/internal/users/{user_id}/suspend": "suspendUser", "/internal/users/{user_id}/reactivate": "reactivateUser"} {
+		doc.add(http.MethodPost, path, secured(&Operation{
+			OperationId: id, Tags: []string{"internal"},
+			Parameters:  []Parameter{userParam},
+			RequestBody: jsonBody(ref("StatusChangeRequest")),
+			Responses: responses(http.StatusOK, ref("PrivateUser"), http.StatusBadRequest, http.StatusForbidden, http.StatusNotFound,
+				http.StatusConflict),
+		}))
+	}
+	doc.add(http.MethodGet, "/internal/users/{user_id}/status-history", secured(&Operation{
+		OperationId: "getUserStatusHistory", Summary: "The status changes of a user, latest first", Tags: []string{"internal"},
+		Parameters: []Parameter{userParam},
+		Responses:  responses(http.StatusOK, array(ref("StatusChange")), http.StatusBadRequest, http.StatusForbidden, http.StatusNotFound),
+	}))
+	scheduledChangesPath := "/internal/users/{user_id}/scheduled-status-changes"
+	doc.add(http.MethodGet, scheduledChangesPath, secured(&Operation{
+		OperationId: "listScheduledStatusChanges", Summary: "The latest scheduled status changes of a user", Tags: []string{"internal"},
+		Parameters: []Parameter{userParam},
+		Responses:  responses(http.StatusOK, array(ref("ScheduledStatusChange")), http.StatusBadRequest, http.StatusForbidden, http.StatusNotFound),
+	}))
+	doc.add(http.MethodPost, scheduledChangesPath, secured(&Operation{
+		OperationId: "scheduleStatusChange", Summary: "Change a user's status at a later time", Tags: []string{"internal"},
+		Parameters: []Parameter{userParam},
+		RequestBody: jsonBody(object(map[string]*Schema{
+			"status": ref("UserStatus"), "reason": str(), "due_at": {Type: "string", Format: "date-time"},
+		}, "status", "due_at")),
+		Responses: responses(http.StatusCreated, ref("ScheduledStatusChange"), http.StatusBadRequest, http.StatusForbidden,
+			http.StatusNotFound),
+	}))
+	doc.add(http.MethodDelete, scheduledChangesPath+"/{change_id}", secured(&Operation{
+		OperationId: "cancelScheduledStatusChange", Tags: []string{"internal"},
+		Parameters: []Parameter{userParam, pathParam("change_id")},
+		Responses:  responses(http.StatusOK, ref("Status"), http.StatusBadRequest, http.StatusForbidden, http.StatusNotFound),
+	}))
+	doc.add(http.MethodPut, "/internal/users/{user_id}/expiry", secured(&Operation{
+		OperationId: "setUserExpiry", Summary: "Deactivate a user at expires_at, replacing its previous expiry", Tags: []string{"internal"},
+		Parameters:  []Parameter{userParam},
+		RequestBody: jsonBody(object(map[string]*Schema{"expires_at": {Type: "string", Format: "date-time"}}, "expires_at")),
+		Responses: responses(http.StatusOK, ref("ScheduledStatusChange"), http.StatusBadRequest, http.StatusForbidden,
+			http.StatusNotFound),
+	}))
+	doc.add(http.MethodDelete, "/internal/users/{user_id}/expiry", secured(&Operation{
+		OperationId: "clearUserExpiry", Tags: []string{"internal"},
+		Parameters: []Parameter{userParam},
+		Responses:  responses(http.StatusOK, ref("Status"), http.StatusBadRequest, http.StatusForbidden),
+	}))
+	doc.add(http.MethodGet, "/internal/users/search/text", &Operation{
+		OperationId: "searchUsers", Tags: []string{"internal"},
+		Summary: "Full text search of names and emails, matching any word of q as a prefix. " +
+			"Misspelled words don't match.",
+		Parameters: []Parameter{requiredQueryParam("q", nonEmpty()), queryParam("limit", integer()), fieldsParam(), includeParam()},
+		Responses:  responses(http.StatusOK, array(ref("SearchResult")), http.StatusBadRequest, http.StatusForbidden),
+	})
+	doc.add(http.MethodGet, "/internal/users/changes", &Operation{
+		OperationId: "streamUserChanges", Summary: "Server-sent events of user changes, resumable with Last-Event-ID", Tags: []string{"internal"},
+		Parameters: []Parameter{
+			queryParam("type", str()),
+			queryParam("status", str()),
+			{Name: "Last-Event-ID", In: "header", Schema: str()},
+		},
+		Responses: map[string]Response{
+			"200": {Description: "a stream of UserChange events", Content: map[string]MediaType{"text/event-stream": {Schema: ref("UserChange")}}},
+			"400": errorResponse(http.StatusBadRequest),
+			"403": errorResponse(http.StatusForbidden),
+		},
+	})
+	doc.add(http.MethodGet, "/internal/metrics/users/cache", &Operation{
+		OperationId: "getUserCacheMetrics", Tags: []string{"internal"},
+		Responses: responses(http.StatusOK, ref("CacheMetrics")),
+	})
+
+	invitationParam := pathParam("invitation_id")
+	doc.add(http.MethodPost, "/internal/users/invitations", secured(&Operation{
+		OperationId: "inviteUser", Tags: []string{"invitations"},
+		RequestBody: jsonBody(object(map[string]*Schema{"email": nonEmpty(), "first_name": str(), "last_name": str()}, "email")),
+		Responses:   responses(http.StatusCreated, ref("UserInvitation"), http.StatusBadRequest, http.StatusForbidden, http.StatusConflict),
+	}))
+	doc.add(http.MethodPost, "/internal/users/invitations/{invitation_id}/resend", secured(&Operation{
+		OperationId: "resendUserInvitation", Tags: []string{"invitations"},
+		Parameters: []Parameter{invitationParam},
+		Responses:  responses(http.StatusOK, ref("UserInvitation"), http.StatusBadRequest, http.StatusForbidden, http.StatusNotFound),
+	}))
+	doc.add(http.MethodDelete, "/internal/users/invitations/{invitation_id}", secured(&Operation{
+		OperationId: "revokeUserInvitation", Tags: []string{"invitations"},
+		Parameters: []Parameter{invitationParam},
+		Responses:  responses(http.StatusOK, ref("Status"), http.StatusBadRequest, http.StatusForbidden, http.StatusNotFound),
+	}))
+
+	subscriptionParam := pathParam("subscription_id")
+	limitParam := queryParam("limit", integer())
+	doc.add(http.MethodPost, "/internal/webhooks", secured(&Operation{
+		OperationId: "createWebhook", Tags: []string{"webhooks"},
+		RequestBody: jsonBody(ref("WebhookSubscription")),
+		Responses:   responses(http.StatusCreated, ref("WebhookSubscription"), http.StatusBadRequest, http.StatusForbidden),
+	}))
+	doc.add(http.MethodGet, "/internal/webhooks", secured(&Operation{
+		OperationId: "listWebhooks", Tags: []string{"webhooks"},
+		Responses: responses(http.StatusOK, array(ref("WebhookSubscription")), http.StatusForbidden),
+	}))
+	doc.add(http.MethodGet, "/internal/webhooks/dead-letters", secured(&Operation{
+		OperationId: "listWebhookDeadLetters", Tags: []string{"webhooks"},
+		Parameters: []Parameter{limitParam},
+		Responses:  responses(http.StatusOK, array(ref("WebhookDelivery")), http.StatusBadRequest, http.StatusForbidden),
+	}))
+	doc.add(http.MethodPost, "/internal/webhooks/deliveries/{delivery_id}/retry", secured(&Operation{
+		OperationId: "retryWebhookDelivery", Tags: []string{"webhooks"},
+		Parameters: []Parameter{pathParam("delivery_id")},
+		Responses:  responses(http.StatusAccepted, ref("Status"), http.StatusBadRequest, http.StatusForbidden, http.StatusNotFound),
+	}))
+	doc.add(http.MethodGet, "/internal/webhooks/{subscription_id}", secured(&Operation{
+		OperationId: "getWebhook", Tags: []string{"webhooks"},
+		Parameters: []Parameter{subscriptionParam},
+		Responses:  responses(http.StatusOK, ref("WebhookSubscription"), http.StatusBadRequest, http.StatusForbidden, http.StatusNotFound),
+	}))
+	doc.add(http.MethodDelete, "/internal/webhooks/{subscription_id}", secured(&Operation{
+		OperationId: "deleteWebhook", Tags: []string{"webhooks"},
+		Parameters: []Parameter{subscriptionParam},
+		Responses:  responses(http.StatusOK, ref("Status"), http.StatusBadRequest, http.StatusForbidden, http.StatusNotFound),
+	}))
+	doc.add(http.MethodGet, "/internal/webhooks/{subscription_id}/deliveries", secured(&Operation{
+		OperationId: "listWebhookDeliveries", Summary: "The delivery log of a webhook", Tags: []string{"webhooks"},
+		Parameters: []Parameter{subscriptionParam, queryParam("status", enum(webhooks.DeliveryPending, webhooks.DeliveryDelivered, webhooks.DeliveryDead)), limitParam},
+		Responses:  responses(http.StatusOK, array(ref("WebhookDelivery")), http.StatusBadRequest, http.StatusForbidden, http.StatusNotFound),
+	}))
+	doc.addVersion("/v2", "V2", map[string]string{
+		"User": "UserV2", "SearchResult": "SearchResultV2", "BatchGetResult": "BatchGetResultV2",
+		"BatchResult": "BatchResultV2", "PrivateUser": "PrivateUserV2",
+	})
+	return doc
+}
+
+func schemas() map[string]*Schema {
+	userStatus := ref("UserStatus")
+	profile := object(map[string]*Schema{
+		"phone": str(), "date_of_birth": {Type: "string", Format: "date"}, "locale": str(), "timezone": str(),
+		"attributes": {Type: "object", Description: "custom attributes defined by the tenant", AdditionalProperties: true},
+		"visibility": object(map[string]*Schema{
+			"phone": boolean(), "date_of_birth": boolean(), "locale": boolean(), "timezone": boolean(),
+		}),
+	})
+	publicProfile := object(map[string]*Schema{
+		"phone": str(), "date_of_birth": {Type: "string", Format: "date"}, "locale": str(), "timezone": str(),
+		"attributes": {Type: "object", AdditionalProperties: true},
+	})
+	return map[string]*Schema{
+		"Error": object(map[string]*Schema{
+			"message": str(), "status": integer(), "error": str(),
+		}),
+		"Problem": object(map[string]*Schema{
+			"type": str(), "title": str(), "status": integer(), "detail": str(), "instance": str(),
+			"request_id": str(), "errors": array(ref("FieldError")),
+		}, "type", "title", "status"),
+		"FieldError": object(map[string]*Schema{"field": str(), "message": str()}, "field", "message"),
+		"Status":     object(map[string]*Schema{"status": str()}, "status"),
+		"Token":      object(map[string]*Schema{"token": nonEmpty()}, "token"),
+		"NewUser": object(map[string]*Schema{
+			"first_name": str(), "last_name": str(), "email": nonEmpty(), "password": password(),
+		}, "email", "password"),
+		"UserUpdate":      userUpdate(userStatus),
+		"UserReplacement": userUpdate(userStatus, "email"),
+		"UserMergePatch": object(map[string]*Schema{
+			"first_name": nullable(str()), "last_name": nullable(str()), "email": str(),
+			"password": password(), "status": userStatus,
+		}),
+		"JSONPatch": array(object(map[string]*Schema{
+			"op":    enum("add", "remove", "replace", "move", "copy", "test"),
+			"path":  str(),
+			"from":  str(),
+			"value": {Description: "any json value"},
+		}, "op", "path")),
+		"User": {
+			Description: "A PrivateUser for internal callers and the user themself, a PublicUser otherwise",
+			OneOf:       []*Schema{ref("PrivateUser"), ref("PublicUser")},
+		},
+		"PublicUser": object(map[string]*Schema{
+			"first_name": str(), "last_name": str(), "profile": ref("PublicProfile"),
+		}),
+		"PrivateUser": object(map[string]*Schema{
+			"id": readOnly(integer()), "first_name": str(), "last_name": str(), "email": str(),
+			"date_created": readOnly(str()), "status": userStatus, "profile": ref("Profile"),
+			"default_addresses": ref("DefaultAddresses"), "addresses": array(ref("Address")),
+			"organizations": array(ref("Organization")), "roles": array(ref("OrganizationRole")),
+		}),
+		"Profile":       profile,
+		"PublicProfile": publicProfile,
+		"Address": object(map[string]*Schema{
+			"id": readOnly(integer()), "user_id": readOnly(integer()), "recipient": str(), "line1": str(), "line2": str(),
+			"city": str(), "region": str(), "postal_code": str(), "country": str(),
+			"default_shipping": boolean(), "default_billing": boolean(), "date_created": readOnly(str()),
+		}),
+		"DefaultAddresses": object(map[string]*Schema{"shipping": ref("Address"), "billing": ref("Address")}),
+		"OrganizationRole": object(map[string]*Schema{"organization_id": integer(), "role": ref("Role")}),
+		"Role":             enum(organizations.RoleOwner, organizations.RoleAdmin, organizations.RoleMember),
+		"Organization":     object(map[string]*Schema{"id": readOnly(integer()), "name": str(), "date_created": readOnly(str())}),
+		"Membership": object(map[string]*Schema{
+			"id": integer(), "name": str(), "date_created": str(), "role": ref("Role"),
+		}),
+		"Member": object(map[string]*Schema{
+			"organization_id": integer(), "user_id": integer(), "role": ref("Role"), "date_created": str(),
+		}),
+		"OrganizationInvitation": object(map[string]*Schema{
+			"id": integer(), "organization_id": integer(), "email": str(), "role": ref("Role"), "invited_by": integer(),
+			"status":       enum(organizations.InvitationPending, organizations.InvitationAccepted, organizations.InvitationRevoked),
+			"date_created": str(), "expires_at": str(),
+		}),
+		"UserInvitation": object(map[string]*Schema{
+			"id": integer(), "user_id": integer(), "invited_by": integer(), "status": str(),
+			"date_created": str(), "expires_at": str(),
+		}),
+		"BatchGetRequest": object(map[string]*Schema{
+			"ids": {Type: "array", Items: positive(), MinItems: 1, MaxItems: users.MaxBatchGetIds},
+		}, "ids"),
+		"BatchGetResult": object(map[string]*Schema{
+			"users": array(ref("User")), "missing": array(integer()),
+		}, "users", "missing"),
+		"BatchGetResultV2": object(map[string]*Schema{
+			"users": array(ref("UserV2")), "missing": array(integer()),
+		}, "users", "missing"),
+		"BatchRequest": object(map[string]*Schema{
+			"atomic": boolean(),
+			"operations": {Type: "array", MinItems: 1, MaxItems: users.MaxBatchOperations, Items: object(map[string]*Schema{
+				"op":      enum(users.BatchOpCreate, users.BatchOpUpdate, users.BatchOpDelete, users.BatchOpStatus),
+				"user_id": positive(),
+				"user":    ref("UserUpdate"),
+				"status":  userStatus,
+				"reason":  str(),
+			}, "op")},
+		}, "operations"),
+		"UserStatus":          enum(users.Statuses...),
+		"StatusChangeRequest": object(map[string]*Schema{"reason": nonEmpty()}, "reason"),
+		"StatusChange": object(map[string]*Schema{
+			"id": integer(), "user_id": integer(), "from_status": userStatus, "to_status": userStatus,
+			"reason": str(), "changed_by": integer(), "date_created": str(),
+		}),
+		"ScheduledStatusChange": object(map[string]*Schema{
+			"id": integer(), "user_id": integer(), "kind": enum(users.ScheduleKindChange, users.ScheduleKindExpiry),
+			"to_status": userStatus, "reason": str(), "scheduled_by": integer(), "due_at": str(),
+			"state":        enum(users.SchedulePending, users.ScheduleApplied, users.ScheduleFailed, users.ScheduleCancelled),
+			"date_created": str(), "date_processed": str(), "last_error": str(),
+		}),
+		"ExportRequest": object(map[string]*Schema{"format": enum(exports.FormatJSON, exports.FormatZip)}),
+		"Export": object(map[string]*Schema{
+			"id": integer(), "user_id": integer(), "requested_by": integer(),
+			"format": enum(exports.FormatJSON, exports.FormatZip),
+			"status": enum(exports.StatusPending, exports.StatusCompleted, exports.StatusFailed, exports.StatusExpired),
+			"size":   integer(), "date_created": str(), "date_completed": str(), "expires_at": str(),
+			"last_error": str(), "download_url": str(),
+		}),
+		"BatchResult":   batchResult("PrivateUser"),
+		"BatchResultV2": batchResult("PrivateUserV2"),
+		"SearchResult": object(map[string]*Schema{
+			"user": ref("User"), "score": {Type: "number"},
+			"highlights": {Type: "object", AdditionalProperties: str()},
+		}),
+		"UserV2": {
+			Description: "A PrivateUserV2 for internal callers and the user themself, a PublicUserV2 otherwise",
+			OneOf:       []*Schema{ref("PrivateUserV2"), ref("PublicUserV2")},
+		},
+		"PublicUserV2": object(map[string]*Schema{"profile": ref("PublicProfileV2")}),
+		"PrivateUserV2": object(map[string]*Schema{
+			"id": readOnly(integer()), "email": str(), "status": userStatus,
+			"created_at": readOnly(&Schema{Type: "string", Format: "date-time"}), "profile": ref("ProfileV2"),
+			"default_addresses": ref("DefaultAddresses"), "addresses": array(ref("Address")),
+			"organizations": array(ref("Organization")), "roles": array(ref("OrganizationRole")),
+		}),
+		"ProfileV2":       withNames(profile),
+		"PublicProfileV2": withNames(publicProfile),
+		"SearchResultV2": object(map[string]*Schema{
+			"user": ref("UserV2"), "score": {Type: "number"},
+			"highlights": {Type: "object", AdditionalProperties: str()},
+		}),
+		"UserChange": object(map[string]*Schema{
+			"id": integer(), "type": enum(users.EventUserCreated, users.EventUserUpdated, users.EventUserDeactivated, users.EventUserDeleted),
+			"user_id": integer(), "status": str(), "user": ref("PrivateUser"), "date_created": str(),
+		}),
+		"CacheMetrics": object(map[string]*Schema{
+			"hits": integer(), "misses": integer(), "errors": integer(), "hit_rate": {Type: "number"},
+		}),
+		"WebhookSubscription": object(map[string]*Schema{
+			"id": readOnly(integer()), "url": nonEmpty(), "event_types": array(enum(webhooks.EventTypes...)),
+			"secret":       {Type: "string", Description: "signs deliveries, only returned on creation"},
+			"date_created": readOnly(str()),
+		}, "url", "event_types"),
+		"WebhookDelivery": object(map[string]*Schema{
+			"id": integer(), "subscription_id": integer(), "event_id": integer(), "event_type": str(),
+			"status":   enum(webhooks.DeliveryPending, webhooks.DeliveryDelivered, webhooks.DeliveryDead),
+			"attempts": integer(), "next_attempt_at": str(), "last_status_code": integer(), "last_error": str(),
+			"date_created": str(),
+		}),
+	}
+}
+
+/// batchResult is the response to a batch, with created and updated
+/// users in the user schema
+func batchResult(user string) *Schema {
+	return object(map[string]*Schema{
+		"results": array(object(map[string]*Schema{
+			"index": integer(), "op": str(), "status": integer(), "user": ref(user),
+			"error": {OneOf: []*Schema{ref("Problem"), ref("Error")}},
+		}, "index", "op", "status")),
+		"succeeded": integer(), "failed": integer(),
+	}, "results", "succeeded", "failed")
+}
+
+/// userUpdate is the body of a user update, a replacement requires email
+func userUpdate(status *Schema, required ...string) *Schema {
+	return object(map[string]*Schema{
+		"first_name": str(), "last_name": str(), "email": str(), "password": password(),
+		"status": status, "profile": ref("Profile"),
+	}, required...)
+}
+
+/// add registers operation for method on path
+func (doc *Document) add(method string, path string, operation *Operation) {
+	pathItem, ok := doc.Paths[path]
+	if !ok {
+		pathItem = make(PathItem)
+		doc.Paths[path] = pathItem
+	}
+	pathItem[strings.ToLower(method)] = operation
+}
+
+/// addVersion describes the versioned operations again under prefix,
+/// with the schemas of their responses renamed, and deprecates the
+/// unprefixed ones
+func (doc *Document) addVersion(prefix string, suffix string, renamed map[string]string) {
+	paths := make([]string, 0, len(doc.Paths))
+	for path := range doc.Paths {
+		if !unversionedPaths[path] {
+			paths = append(paths, path)
+		}
+	}
+	for _, path := range paths {
+		for method, operation := range doc.Paths[path] {
+			versioned := *operation
+			versioned.OperationId += suffix
+			versioned.Responses = make(map[string]Response, len(operation.Responses))
+			for status, response := range operation.Responses {
+				content := make(map[string]MediaType, len(response.Content))
+				for contentType, mediaType := range response.Content {
+					content[contentType] = MediaType{Schema: renameRefs(mediaType.Schema, renamed)}
+				}
+				versioned.Responses[status] = Response{Description: response.Description, Content: content}
+			}
+			doc.add(method, prefix+path, &versioned)
+			operation.Deprecated = true
+		}
+	}
+}
+
+/// renameRefs points references to the renamed schemas, directly or as
+/// array items, at their new names
+func renameRefs(schema *Schema, renamed map[string]string) *Schema {
+	if schema == nil {
+		return nil
+	}
+	if name, ok := renamed[strings.TrimPrefix(schema.Ref, schemaRefPrefix)]; ok && schema.Ref != "" {
+		return ref(name)
+	}
+	if schema.Items != nil {
+		copied := *schema
+		copied.Items = renameRefs(schema.Items, renamed)
+		return &copied
+	}
+	return schema
+}
+
+/// secured marks operation as requiring an authenticated caller
+func secured(operation *Operation) *Operation {
+	operation.Security = []map[string][]string{{securityScheme: {}}}
+	if _, ok := operation.Responses["401"]; !ok {
+		operation.Responses["401"] = errorResponse(http.StatusUnauthorized)
+	}
+	return operation
+}
+
+/// responses describes a successful response of status with schema and
+/// the error responses of errorStatuses
+func responses(status int, schema *Schema, errorStatuses ...int) map[string]Response {
+	result := map[string]Response{strconv.Itoa(status): jsonResponse(http.StatusText(status), schema)}
+	for _, errorStatus := range errorStatuses {
+		result[strconv.Itoa(errorStatus)] = errorResponse(errorStatus)
+	}
+	return result
+}
+
+/// errorResponse is an Error, or a Problem for clients that accept them
+func errorResponse(status int) Response {
+	response := jsonResponse(http.StatusText(status), ref("Error"))
+	response.Content[problems.ContentType] = MediaType{Schema: ref("Problem")}
+	return response
+}
+
+func jsonResponse(description string, schema *Schema) Response {
+	return Response{Description: description, Content: map[string]MediaType{jsonContentType: {Schema: schema}}}
+}
+
+/// jsonBody is a required json request body of schema
+func jsonBody(schema *Schema) *RequestBody {
+	return &RequestBody{Required: true, Content: map[string]MediaType{jsonContentType: {Schema: schema}}}
+}
+
+func pathParam(name string) Parameter {
+	return Parameter{Name: name, In: "path", Required: true, Schema: positive()}
+}
+
+func queryParam(name string, schema *Schema) Parameter {
+	return Parameter{Name: name, In: "query", Schema: schema}
+}
+
+/// fieldsParam selects the fields of the users read, public callers only
+/// the public ones
+func fieldsParam() Parameter {
+	parameter := queryParam("fields", str())
+	parameter.Description = "comma separated fields of the user to return, all by default"
+	return parameter
+}
+
+/// includeParam embeds related resources in the users read, for internal
+/// callers only
+func includeParam() Parameter {
+	parameter := queryParam("include", str())
+	parameter.Description = "comma separated resources to embed: " + strings.Join(users.Includes, ", ")
+	return parameter
+}
+
+func requiredQueryParam(name string, schema *Schema) Parameter {
+	return Parameter{Name: name, In: "query", Required: true, Schema: schema}
+}
+
+func ref(name string) *Schema {
+	return &Schema{Ref: schemaRefPrefix + name}
+}
+
+func object(properties map[string]*Schema, required ...string) *Schema {
+	return &Schema{Type: "object", Properties: properties, Required: required}
+}
+
+func array(items *Schema) *Schema {
+	return &Schema{Type: "array", Items: items}
+}
+
+func str() *Schema {
+	return &Schema{Type: "string"}
+}
+
+func nonEmpty() *Schema {
+	return &Schema{Type: "string", MinLength: 1}
+}
+
+func password() *Schema {
+	return &Schema{Type: "string", Format: "password", WriteOnly: true}
+}
+
+func enum(values ...string) *Schema {
+	return &Schema{Type: "string", Enum: values}
+}
+
+func boolean() *Schema {
+	return &Schema{Type: "boolean"}
+}
+
+func integer() *Schema {
+	return &Schema{Type: "integer", Format: "int64"}
+}
+
+func positive() *Schema {
+	minimum := 1.0
+	return &Schema{Type: "integer", Format: "int64", Minimum: &minimum}
+}
+
+func nullable(schema *Schema) *Schema {
+	schema.Nullable = true
+	return schema
+}
+
+/// withNames is profile with the user's names, as nested in v2 users
+func withNames(profile *Schema) *Schema {
+	properties := map[string]*Schema{"first_name": str(), "last_name": str()}
+	for name, property := range profile.Properties {
+		properties[name] = property
+	}
+	return object(properties)
+}
+
+func readOnly(schema *Schema) *Schema {
+	schema.ReadOnly = true
+	return schema
+}
@@ -0,0 +1,181 @@
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const schemaRefPrefix = "#/components/schemas/"
+
+/// ValidateValue checks a decoded json value against schema. Numbers
+/// have to be decoded as json.Number.
+func (doc *Document) ValidateValue(schema *Schema, value interface{}, path string) error {
+	schema = doc.resolve(schema)
+	if schema == nil {
+		return nil
+	}
+	if value == nil {
+		if schema.Nullable || schema.Type == "" {
+			return nil
+		}
+		return invalid(path, "must not be null")
+	}
+	if len(schema.OneOf) > 0 {
+		for _, candidate := range schema.OneOf {
+			if doc.ValidateValue(candidate, value, path) == nil {
+				return nil
+			}
+		}
+		return invalid(path, "doesn't match any of the allowed schemas")
+	}
+
+	switch schema.Type {
+	case "object":
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return invalid(path, "must be an object")
+		}
+		return doc.validateObject(schema, object, path)
+	case "array":
+		array, ok := value.([]interface{})
+		if !ok {
+			return invalid(path, "must be an array")
+		}
+		if len(array) < schema.MinItems {
+			return invalid(path, "must have at least %d items", schema.MinItems)
+		}
+		if schema.MaxItems > 0 && len(array) > schema.MaxItems {
+			return invalid(path, "must have at most %d items", schema.MaxItems)
+		}
+		for i, item := range array {
+			if err := doc.ValidateValue(schema.Items, item, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+	case "string":
+		text, ok := value.(string)
+		if !ok {
+			return invalid(path, "must be a string")
+		}
+		return validateString(schema, text, path)
+	case "integer", "number":
+		number, ok := value.(json.Number)
+		if !ok {
+			return invalid(path, "must be a %s", schema.Type)
+		}
+		return validateNumber(schema, number.String(), path)
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return invalid(path, "must be a boolean")
+		}
+	}
+	return nil
+}
+
+/// ValidateParameter checks the raw value of a path or query parameter
+func (doc *Document) ValidateParameter(schema *Schema, value string, path string) error {
+	schema = doc.resolve(schema)
+	if schema == nil {
+		return nil
+	}
+	switch schema.Type {
+	case "integer", "number":
+		return validateNumber(schema, value, path)
+	case "boolean":
+		if _, err := strconv.ParseBool(value); err != nil {
+			return invalid(path, "must be a boolean")
+		}
+	case "string":
+		return validateString(schema, value, path)
+	}
+	return nil
+}
+
+func (doc *Document) validateObject(schema *Schema, object map[string]interface{}, path string) error {
+	for _, name := range schema.Required {
+		if _, ok := object[name]; !ok {
+			return invalid(path+"."+name, "is required")
+		}
+	}
+	/// Sorted, so that the first error reported is stable
+	names := make([]string, 0, len(object))
+	for name := range object {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		property, ok := schema.Properties[name]
+		if !ok {
+			if additional, isSchema := schema.AdditionalProperties.(*Schema); isSchema {
+				property = additional
+			}
+		}
+		if property == nil {
+			continue
+		}
+		if err := doc.ValidateValue(property, object[name], path+"."+name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateString(schema *Schema, text string, path string) error {
+	if len(schema.Enum) > 0 {
+		for _, allowed := range schema.Enum {
+			if text == allowed {
+				return nil
+			}
+		}
+		return invalid(path, "must be one of %s", strings.Join(schema.Enum, ", "))
+	}
+	if len(strings.TrimSpace(text)) < schema.MinLength {
+		return invalid(path, "must not be empty")
+	}
+	return nil
+}
+
+func validateNumber(schema *Schema, value string, path string) error {
+	var number float64
+	if schema.Type == "integer" {
+		integer, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return invalid(path, "must be an integer")
+		}
+		number = float64(integer)
+	} else {
+		var err error
+		if number, err = strconv.ParseFloat(value, 64); err != nil {
+			return invalid(path, "must be a number")
+		}
+	}
+	if schema.Minimum != nil && number < *schema.Minimum {
+		return invalid(path, "must be at least %v", *schema.Minimum)
+	}
+	return nil
+}
+
+/// ValidationError is a value at Path that doesn't match its schema
+type ValidationError struct {
+	Path   string
+	Reason string
+}
+
+func (ve *ValidationError) Error() string {
+	return ve.Path + " " + ve.Reason
+}
+
+func invalid(path string, format string, args ...interface{}) error {
+	return &ValidationError{Path: path, Reason: fmt.Sprintf(format, args...)}
+}
+
+/// resolve follows a schema reference into the document's components
+func (doc *Document) resolve(schema *Schema) *Schema {
+	for schema != nil && schema.Ref != "" {
+		schema = doc.Components.Schemas[strings.TrimPrefix(schema.Ref, schemaRefPrefix)]
+	}
+	return schema
+}
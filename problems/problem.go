@@ -0,0 +1,88 @@
+package problems
+
+import (
+	"github.com/Abacode7/bookstore_users-api/utils/error_utils"
+	"github.com/Abacode7/bookstore_utils-go/v2/rest_error"
+	"net/http"
+)
+
+/// typeBaseURI prefixes the problem types. Types are identifiers clients
+/// can switch on, they aren't guaranteed to resolve.
+const typeBaseURI = "/problems/"
+
+/// Problem types, stable across messages and languages
+const (
+	TypeBadRequest           = "bad-request"
+	TypeValidation           = "validation-error"
+	TypeUnauthorized         = "unauthorized"
+	TypeForbidden            = "forbidden"
+	TypeNotFound             = "not-found"
+	TypeConflict             = "conflict"
+	TypeGone                 = "gone"
+	TypeRequestTooLarge      = "request-too-large"
+	TypeUnsupportedMediaType = "unsupported-media-type"
+	TypeTooManyRequests      = "too-many-requests"
+	TypeFailedDependency     = "failed-dependency"
+	TypeInternal             = "internal-error"
+)
+
+var typesByStatus = map[int]string{
+	http.StatusBadRequest:            TypeBadRequest,
+	http.StatusUnauthorized:          TypeUnauthorized,
+	http.StatusForbidden:             TypeForbidden,
+	http.StatusNotFound:              TypeNotFound,
+	http.StatusConflict:              TypeConflict,
+	http.StatusGone:                  TypeGone,
+	http.StatusRequestEntityTooLarge: TypeRequestTooLarge,
+	http.StatusUnsupportedMediaType:  TypeUnsupportedMediaType,
+	http.StatusTooManyRequests:       TypeTooManyRequests,
+	http.StatusFailedDependency:      TypeFailedDependency,
+	http.StatusInternalServerError:   TypeInternal,
+}
+
+/// Problem is an RFC 7807 problem details object
+type Problem struct {
+	Type      string                   `json:"type"`
+	Title     string                   `json:"title"`
+	Status    int                      `json:"status"`
+	Detail    string                   `json:"detail,omitempty"`
+	Instance  string                   `json:"instance,omitempty"`
+	RequestId string                   `json:"request_id,omitempty"`
+	Errors    []error_utils.FieldError `json:"errors,omitempty"`
+}
+
+/// legacyError is the message, status and error shape errors had before
+/// problems, still served to clients that don't ask for problems
+type legacyError struct {
+	Message string `json:"message"`
+	Status  int    `json:"status"`
+	Error   string `json:"error"`
+}
+
+/// New converts err into a problem with a title in language
+func New(err rest_error.RestErr, language string) *Problem {
+	problem := &Problem{
+		Type:   "about:blank",
+		Title:  http.StatusText(err.Status()),
+		Status: err.Status(),
+		Detail: err.Message(),
+	}
+	problemType, ok := typesByStatus[err.Status()]
+	if validationErr, isValidation := err.(error_utils.ValidationError); isValidation {
+		problemType, ok = TypeValidation, true
+		problem.Errors = validationErr.FieldErrors()
+	}
+	if ok {
+		problem.Type = typeBaseURI + problemType
+		problem.Title = title(problemType, language)
+	}
+	return problem
+}
+
+func newLegacyError(err rest_error.RestErr) *legacyError {
+	return &legacyError{
+		Message: err.Message(),
+		Status:  err.Status(),
+		Error:   http.StatusText(err.Status()),
+	}
+}
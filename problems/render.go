@@ -0,0 +1,59 @@
+package problems
+
+import (
+	"github.com/Abacode7/bookstore_utils-go/v2/rest_error"
+	"github.com/gin-gonic/gin"
+	"strings"
+)
+
+const (
+	ContentType = "application/problem+json"
+	/// requestIdHeader is the response header the request id middleware
+	/// echoes the request id in
+	requestIdHeader = "X-Request-Id"
+)
+
+/// Respond writes err as a problem when the client accepts
+/// application/problem+json, and in the legacy error shape otherwise
+func Respond(c *gin.Context, err rest_error.RestErr) {
+	body := Body(c, err)
+	if problem, ok := body.(*Problem); ok {
+		/// gin keeps a content type that's already set
+		c.Header("Content-Type", ContentType)
+		c.Header("Content-Language", Language(c.GetHeader("Accept-Language")))
+		c.JSON(problem.Status, problem)
+		return
+	}
+	c.JSON(err.Status(), body)
+}
+
+/// Body is err in the shape Respond writes it, for errors embedded in a
+/// larger response such as the results of a batch
+func Body(c *gin.Context, err rest_error.RestErr) interface{} {
+	if !acceptsProblem(c.GetHeader("Accept")) {
+		return newLegacyError(err)
+	}
+	problem := New(err, Language(c.GetHeader("Accept-Language")))
+	problem.Instance = c.Request.URL.Path
+	problem.RequestId = c.Writer.Header().Get(requestIdHeader)
+	return problem
+}
+
+/// Abort stops the handler chain and responds with err
+func Abort(c *gin.Context, err rest_error.RestErr) {
+	c.Abort()
+	Respond(c, err)
+}
+
+/// acceptsProblem reports whether the Accept header explicitly lists
+/// problems. Wildcards don't count, so existing clients keep the legacy
+/// shape.
+func acceptsProblem(accept string) bool {
+	for _, part := range strings.Split(accept, ",") {
+		fields := strings.Split(part, ";")
+		if strings.EqualFold(strings.TrimSpace(fields[0]), ContentType) {
+			return quality(fields[1:]) > 0
+		}
+	}
+	return false
+}
@@ -0,0 +1,103 @@
+package problems
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const defaultLanguage = "en"
+
+/// titles are the problem titles by language and problem type
+var titles = map[string]map[string]string{
+	"en": {
+		TypeBadRequest:           "The request is invalid",
+		TypeValidation:           "Some fields are invalid",
+		TypeUnauthorized:         "Authentication is required",
+		TypeForbidden:            "You are not allowed to do this",
+		TypeNotFound:             "The resource was not found",
+		TypeConflict:             "The request conflicts with the current state of the resource",
+		TypeGone:                 "The resource is no longer available",
+		TypeRequestTooLarge:      "The request body is too large",
+		TypeUnsupportedMediaType: "The request body format is not supported",
+		TypeTooManyRequests:      "Too many requests, try again later",
+		TypeFailedDependency:     "An operation this one depends on failed",
+		TypeInternal:             "Something went wrong on our side",
+	},
+	"fr": {
+		TypeBadRequest:           "La requête est invalide",
+		TypeValidation:           "Certains champs sont invalides",
+		TypeUnauthorized:         "Une authentification est requise",
+		TypeForbidden:            "Vous n'êtes pas autorisé à faire cela",
+		TypeNotFound:             "La ressource est introuvable",
+		TypeConflict:             "La requête est en conflit avec l'état actuel de la ressource",
+		TypeGone:                 "La ressource n'est plus disponible",
+		TypeRequestTooLarge:      "Le corps de la requête est trop volumineux",
+		TypeUnsupportedMediaType: "Le format du corps de la requête n'est pas pris en charge",
+		TypeTooManyRequests:      "Trop de requêtes, réessayez plus tard",
+		TypeFailedDependency:     "Une opération dont celle-ci dépend a échoué",
+		TypeInternal:             "Une erreur s'est produite de notre côté",
+	},
+	"es": {
+		TypeBadRequest:           "La solicitud no es válida",
+		TypeValidation:           "Algunos campos no son válidos",
+		TypeUnauthorized:         "Se requiere autenticación",
+		TypeForbidden:            "No tiene permiso para hacer esto",
+		TypeNotFound:             "No se encontró el recurso",
+		TypeConflict:             "La solicitud entra en conflicto con el estado actual del recurso",
+		TypeGone:                 "El recurso ya no está disponible",
+		TypeRequestTooLarge:      "El cuerpo de la solicitud es demasiado grande",
+		TypeUnsupportedMediaType: "El formato del cuerpo de la solicitud no es compatible",
+		TypeTooManyRequests:      "Demasiadas solicitudes, inténtelo más tarde",
+		TypeFailedDependency:     "Falló una operación de la que depende esta",
+		TypeInternal:             "Algo salió mal de nuestro lado",
+	},
+}
+
+func title(problemType string, language string) string {
+	if localized, ok := titles[language][problemType]; ok {
+		return localized
+	}
+	return titles[defaultLanguage][problemType]
+}
+
+/// Language picks the supported language the Accept-Language header
+/// prefers, english if it names none
+func Language(acceptLanguage string) string {
+	type preference struct {
+		language string
+		quality  float64
+	}
+	var preferences []preference
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		fields := strings.Split(part, ";")
+		tag := strings.ToLower(strings.TrimSpace(fields[0]))
+		language := strings.SplitN(tag, "-", 2)[0]
+		if _, ok := titles[language]; !ok {
+			continue
+		}
+		preferences = append(preferences, preference{language, quality(fields[1:])})
+	}
+	/// Stable, so that equal qualities keep the order they were listed in
+	sort.SliceStable(preferences, func(i, j int) bool {
+		return preferences[i].quality > preferences[j].quality
+	})
+	if len(preferences) == 0 || preferences[0].quality <= 0 {
+		return defaultLanguage
+	}
+	return preferences[0].language
+}
+
+/// quality reads the q parameter of a header entry, 1 when missing
+func quality(params []string) float64 {
+	for _, param := range params {
+		param = strings.TrimSpace(param)
+		if strings.HasPrefix(param, "q=") {
+			if q, err := strconv.ParseFloat(strings.TrimPrefix(param, "q="), 64); err == nil {
+				return q
+			}
+			return 0
+		}
+	}
+	return 1
+}
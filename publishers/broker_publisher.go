@@ -0,0 +1,33 @@
+package publishers
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/Abacode7/bookstore_users-api/domain/outbox"
+)
+
+/// IBroker is the part of a message broker client (kafka, rabbitmq, ...)
+/// events are published through
+type IBroker interface {
+	Send(topic string, key string, body []byte) error
+}
+
+type brokerPublisher struct {
+	broker IBroker
+	topic  string
+}
+
+/// NewBrokerPublisher is a constructor for a publisher sending events to
+/// topic of broker, keyed by their aggregate id to keep a user's events
+/// in one partition
+func NewBrokerPublisher(broker IBroker, topic string) IPublisher {
+	return &brokerPublisher{broker: broker, topic: topic}
+}
+
+func (bp *brokerPublisher) Publish(event outbox.Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	return bp.broker.Send(bp.topic, fmt.Sprint(event.AggregateId), body)
+}
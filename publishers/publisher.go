@@ -0,0 +1,57 @@
+package publishers
+
+import (
+	"encoding/json"
+	"github.com/Abacode7/bookstore_users-api/domain/outbox"
+	"io"
+	"sync"
+)
+
+/// IPublisher delivers relayed outbox events to their consumers. An
+/// event is published at least once, consumers de-duplicate by its id.
+type IPublisher interface {
+	Publish(outbox.Event) error
+}
+
+type writerPublisher struct {
+	mu     sync.Mutex
+	writer io.Writer
+}
+
+/// NewWriterPublisher is a constructor for a publisher writing events as
+/// json lines to writer, e.g. stdout or an append only file
+func NewWriterPublisher(writer io.Writer) IPublisher {
+	return &writerPublisher{writer: writer}
+}
+
+func (wp *writerPublisher) Publish(event outbox.Event) error {
+	line, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	wp.mu.Lock()
+	defer wp.mu.Unlock()
+	_, err = wp.writer.Write(append(line, '\n'))
+	return err
+}
+
+type multiPublisher struct {
+	publishers []IPublisher
+}
+
+/// NewMultiPublisher is a constructor for a publisher handing every event
+/// to all of publishers. It fails when any of them does, so the event is
+/// published again to all, including those that already had it.
+func NewMultiPublisher(publishers ...IPublisher) IPublisher {
+	return &multiPublisher{publishers}
+}
+
+func (mp *multiPublisher) Publish(event outbox.Event) error {
+	var firstErr error
+	for _, publisher := range mp.publishers {
+		if err := publisher.Publish(event); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
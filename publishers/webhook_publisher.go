@@ -0,0 +1,44 @@
+package publishers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/Abacode7/bookstore_users-api/domain/outbox"
+	"net/http"
+)
+
+type webhookPublisher struct {
+	url    string
+	client *http.Client
+}
+
+/// NewWebhookPublisher is a constructor for a publisher posting each event
+/// as json to url, any non 2xx response fails the delivery
+func NewWebhookPublisher(url string, client *http.Client) IPublisher {
+	return &webhookPublisher{url: url, client: client}
+}
+
+func (wp *webhookPublisher) Publish(event outbox.Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	request, err := http.NewRequest(http.MethodPost, wp.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("X-Event-Id", fmt.Sprint(event.Id))
+	request.Header.Set("X-Event-Type", event.Type)
+
+	response, err := wp.client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return fmt.Errorf("webhook responded with status %d", response.StatusCode)
+	}
+	return nil
+}
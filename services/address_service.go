@@ -0,0 +1,120 @@
+package services
+
+import (
+	"github.com/Abacode7/bookstore_users-api/domain/addresses"
+	"github.com/Abacode7/bookstore_users-api/domain/users"
+	"github.com/Abacode7/bookstore_users-api/utils/date_utils"
+	"github.com/Abacode7/bookstore_utils-go/v2/rest_error"
+)
+
+type IAddressService interface {
+	CreateAddress(addresses.Address) (*addresses.Address, rest_error.RestErr)
+	GetAddress(int64, int64) (*addresses.Address, rest_error.RestErr)
+	ListAddresses(int64) (addresses.Addresses, rest_error.RestErr)
+	GetDefaultAddresses(int64) (*addresses.DefaultAddresses, rest_error.RestErr)
+	ListUsersAddresses([]int64) (map[int64]addresses.Addresses, rest_error.RestErr)
+	GetUsersDefaultAddresses([]int64) (map[int64]*addresses.DefaultAddresses, rest_error.RestErr)
+	UpdateAddress(bool, addresses.Address) (*addresses.Address, rest_error.RestErr)
+	DeleteAddress(int64, int64) rest_error.RestErr
+}
+
+type addressService struct {
+	addressDao addresses.IAddressDao
+	userDao    users.IUserDao
+}
+
+/// NewAddressService is addressService's constructor
+func NewAddressService(addressDao addresses.IAddressDao, userDao users.IUserDao) IAddressService {
+	return &addressService{addressDao: addressDao, userDao: userDao}
+}
+
+/// CreateAddress adds an address to a user's address book. A user's
+/// first address becomes their default shipping and billing address.
+func (as *addressService) CreateAddress(address addresses.Address) (*addresses.Address, rest_error.RestErr) {
+	if err := address.Validate(); err != nil {
+		return nil, err
+	}
+	if _, err := as.userDao.Get(address.UserId); err != nil {
+		return nil, err
+	}
+	existing, err := as.addressDao.FindByUser(address.UserId)
+	if err != nil {
+		return nil, err
+	}
+	if len(existing) == 0 {
+		address.DefaultShipping = true
+		address.DefaultBilling = true
+	}
+	address.DateCreated = date_utils.GetDbFormattedTime()
+	return as.addressDao.Save(address)
+}
+
+func (as *addressService) GetAddress(userId int64, addressId int64) (*addresses.Address, rest_error.RestErr) {
+	return as.addressDao.Get(userId, addressId)
+}
+
+func (as *addressService) ListAddresses(userId int64) (addresses.Addresses, rest_error.RestErr) {
+	return as.addressDao.FindByUser(userId)
+}
+
+func (as *addressService) GetDefaultAddresses(userId int64) (*addresses.DefaultAddresses, rest_error.RestErr) {
+	return as.addressDao.FindDefaults(userId)
+}
+
+/// ListUsersAddresses is ListAddresses for several users at once, keyed
+/// by user id
+func (as *addressService) ListUsersAddresses(userIds []int64) (map[int64]addresses.Addresses, rest_error.RestErr) {
+	return as.addressDao.FindByUsers(userIds)
+}
+
+/// GetUsersDefaultAddresses is GetDefaultAddresses for several users at
+/// once, keyed by user id
+func (as *addressService) GetUsersDefaultAddresses(userIds []int64) (map[int64]*addresses.DefaultAddresses, rest_error.RestErr) {
+	return as.addressDao.FindDefaultsByUsers(userIds)
+}
+
+func (as *addressService) UpdateAddress(isTotalUpdate bool, address addresses.Address) (*addresses.Address, rest_error.RestErr) {
+	oldAddress, err := as.addressDao.Get(address.UserId, address.Id)
+	if err != nil {
+		return nil, err
+	}
+	address.DateCreated = oldAddress.DateCreated
+
+	// For partial updates, fields that aren't provided retain their
+	// old values. Default flags can only be set, a default address
+	// stops being one when another takes its place.
+	if !isTotalUpdate {
+		if address.Recipient == "" {
+			address.Recipient = oldAddress.Recipient
+		}
+		if address.Line1 == "" {
+			address.Line1 = oldAddress.Line1
+		}
+		if address.Line2 == "" {
+			address.Line2 = oldAddress.Line2
+		}
+		if address.City == "" {
+			address.City = oldAddress.City
+		}
+		if address.Region == "" {
+			address.Region = oldAddress.Region
+		}
+		if address.PostalCode == "" {
+			address.PostalCode = oldAddress.PostalCode
+		}
+		if address.Country == "" {
+			address.Country = oldAddress.Country
+		}
+	}
+	address.DefaultShipping = address.DefaultShipping || oldAddress.DefaultShipping
+	address.DefaultBilling = address.DefaultBilling || oldAddress.DefaultBilling
+
+	if err := address.Validate(); err != nil {
+		return nil, err
+	}
+	return as.addressDao.Update(address)
+}
+
+func (as *addressService) DeleteAddress(userId int64, addressId int64) rest_error.RestErr {
+	return as.addressDao.Delete(userId, addressId)
+}
@@ -0,0 +1,10 @@
+package services
+
+/// Caller is who a service call is made on behalf of, for services that
+/// enforce access rules themselves
+type Caller struct {
+	UserId int64
+	/// Privileged callers come from inside the network and may act on
+	/// any record
+	Privileged bool
+}
@@ -0,0 +1,189 @@
+package services
+
+import (
+	"fmt"
+	"github.com/Abacode7/bookstore_users-api/domain/users"
+	"github.com/Abacode7/bookstore_users-api/notifiers"
+	"github.com/Abacode7/bookstore_users-api/utils/crypto_utils"
+	"github.com/Abacode7/bookstore_users-api/utils/date_utils"
+	"github.com/Abacode7/bookstore_users-api/utils/error_utils"
+	"github.com/Abacode7/bookstore_utils-go/v2/logger"
+	"github.com/Abacode7/bookstore_utils-go/v2/rest_error"
+	"strings"
+	"time"
+)
+
+const (
+	/// emailChangeConfirmTTL is how long the new address has to confirm
+	emailChangeConfirmTTL = 24 * time.Hour
+	/// emailChangeCancelWindow is how long the old address can cancel,
+	/// and revert, an email change
+	emailChangeCancelWindow = 72 * time.Hour
+)
+
+type IEmailChangeService interface {
+	CheckNewEmail(string) rest_error.RestErr
+	RequestChange(users.User, string) rest_error.RestErr
+	ConfirmChange(string) (*users.User, rest_error.RestErr)
+	CancelChange(string) rest_error.RestErr
+}
+
+type emailChangeService struct {
+	emailChangeDao users.IEmailChangeDao
+	userDao        users.IUserDao
+	notifier       notifiers.INotifier
+	changeFeed     IUserChangeFeed
+}
+
+/// NewEmailChangeService is emailChangeService's constructor
+func NewEmailChangeService(emailChangeDao users.IEmailChangeDao, userDao users.IUserDao, notifier notifiers.INotifier,
+	changeFeed IUserChangeFeed) IEmailChangeService {
+	return &emailChangeService{
+		emailChangeDao: emailChangeDao,
+		userDao:        userDao,
+		notifier:       notifier,
+		changeFeed:     changeFeed,
+	}
+}
+
+/// RequestChange stores newEmail as the user's pending address, replacing
+/// any earlier pending one, and sends a confirmation token to the new
+/// address and a cancellation token to the current one.
+func (ecs *emailChangeService) RequestChange(user users.User, newEmail string) rest_error.RestErr {
+	newEmail = strings.TrimSpace(newEmail)
+	if err := ecs.CheckNewEmail(newEmail); err != nil {
+		return err
+	}
+	confirmToken, tokenErr := crypto_utils.GenerateToken()
+	if tokenErr != nil {
+		logger.Error("error generating email change token", tokenErr)
+		return rest_error.NewInternalServerError("error requesting email change")
+	}
+	cancelToken, tokenErr := crypto_utils.GenerateToken()
+	if tokenErr != nil {
+		logger.Error("error generating email change token", tokenErr)
+		return rest_error.NewInternalServerError("error requesting email change")
+	}
+
+	if err := ecs.emailChangeDao.CancelPending(user.Id); err != nil {
+		return err
+	}
+	now := date_utils.GetTime()
+	change := users.EmailChange{
+		UserId:           user.Id,
+		OldEmail:         user.Email,
+		NewEmail:         newEmail,
+		ConfirmTokenHash: crypto_utils.GetSha256(confirmToken),
+		CancelTokenHash:  crypto_utils.GetSha256(cancelToken),
+		Status:           users.EmailChangePending,
+		DateCreated:      date_utils.FormatDbTime(now),
+		ConfirmExpiresAt: date_utils.FormatDbTime(now.Add(emailChangeConfirmTTL)),
+		CancelExpiresAt:  date_utils.FormatDbTime(now.Add(emailChangeCancelWindow)),
+	}
+	if _, err := ecs.emailChangeDao.Save(change); err != nil {
+		return err
+	}
+
+	if err := ecs.notifier.Notify(notifiers.Message{
+		To:      newEmail,
+		Subject: "Confirm your new email address",
+		Body:    fmt.Sprintf("Use this token to confirm your new email address within %s: %s", emailChangeConfirmTTL, confirmToken),
+	}); err != nil {
+		logger.Error("error sending email change confirmation", err)
+		return rest_error.NewInternalServerError("error sending email change confirmation")
+	}
+	if err := ecs.notifier.Notify(notifiers.Message{
+		To:      user.Email,
+		Subject: "Your email address is being changed",
+		Body:    fmt.Sprintf("A change of your email address to %s was requested. If this wasn't you, use this token within %s to cancel it: %s", newEmail, emailChangeCancelWindow, cancelToken),
+	}); err != nil {
+		logger.Error("error sending email change notification", err)
+	}
+	return nil
+}
+
+/// ConfirmChange swaps the user's email for the pending address of the
+/// change with the given confirmation token
+func (ecs *emailChangeService) ConfirmChange(token string) (*users.User, rest_error.RestErr) {
+	change, err := ecs.emailChangeDao.FindByConfirmToken(crypto_utils.GetSha256(token))
+	if err != nil {
+		return nil, err
+	}
+	if change.Status != users.EmailChangePending || isExpired(change.ConfirmExpiresAt) {
+		return nil, rest_error.NewBadRequestError("email change is no longer pending")
+	}
+	user, err := ecs.userDao.Get(change.UserId)
+	if err != nil {
+		return nil, err
+	}
+	if user.Email != change.OldEmail {
+		return nil, error_utils.NewConflictError("email address has changed since the request")
+	}
+	if err := ecs.CheckNewEmail(change.NewEmail); err != nil {
+		return nil, err
+	}
+	user.Email = change.NewEmail
+	updatedUser, err := ecs.userDao.Update(*user)
+	if err != nil {
+		return nil, err
+	}
+	ecs.changeFeed.PublishUpdate(user.Status, *updatedUser)
+	if err := ecs.emailChangeDao.UpdateStatus(change.Id, users.EmailChangeConfirmed); err != nil {
+		return nil, err
+	}
+	return updatedUser, nil
+}
+
+/// CancelChange cancels the change with the given cancellation token. A
+/// change that was already confirmed is reverted to the old address.
+func (ecs *emailChangeService) CancelChange(token string) rest_error.RestErr {
+	change, err := ecs.emailChangeDao.FindByCancelToken(crypto_utils.GetSha256(token))
+	if err != nil {
+		return err
+	}
+	if change.Status == users.EmailChangeCancelled || isExpired(change.CancelExpiresAt) {
+		return rest_error.NewBadRequestError("email change can no longer be cancelled")
+	}
+	if change.Status == users.EmailChangeConfirmed {
+		user, err := ecs.userDao.Get(change.UserId)
+		if err != nil {
+			return err
+		}
+		if user.Email == change.NewEmail {
+			user.Email = change.OldEmail
+			updatedUser, err := ecs.userDao.Update(*user)
+			if err != nil {
+				return err
+			}
+			ecs.changeFeed.PublishUpdate(user.Status, *updatedUser)
+		}
+	}
+	return ecs.emailChangeDao.UpdateStatus(change.Id, users.EmailChangeCancelled)
+}
+
+/// CheckNewEmail validates email as the new address of a user, without
+/// requesting the change
+func (ecs *emailChangeService) CheckNewEmail(email string) rest_error.RestErr {
+	email = strings.TrimSpace(email)
+	if email == "" {
+		return rest_error.NewBadRequestError("invalid email address")
+	}
+	inUse, err := ecs.userDao.EmailInUse(email)
+	if err != nil {
+		return err
+	}
+	if inUse {
+		return error_utils.NewConflictError(users.EmailInUseMessage)
+	}
+	return nil
+}
+
+/// isExpired reports whether the database formatted time expiresAt has passed
+func isExpired(expiresAt string) bool {
+	expiry, err := date_utils.ParseDbTime(expiresAt)
+	if err != nil {
+		logger.Error("error parsing expiry time", err)
+		return true
+	}
+	return date_utils.GetTime().After(expiry)
+}
@@ -0,0 +1,37 @@
+package services
+
+import (
+	"github.com/Abacode7/bookstore_users-api/domain/users"
+	"github.com/Abacode7/bookstore_utils-go/v2/rest_error"
+	"net/http"
+	"testing"
+)
+
+/// emailsUserDao knows the emails of users of every status
+type emailsUserDao struct {
+	users.IUserDao
+	emails map[string]string
+}
+
+func (ed *emailsUserDao) EmailInUse(email string) (bool, rest_error.RestErr) {
+	_, ok := ed.emails[email]
+	return ok, nil
+}
+
+func TestCheckNewEmailCountsUsersOfEveryStatus(t *testing.T) {
+	dao := &emailsUserDao{emails: map[string]string{
+		"active@mail.com":  users.StatusActive,
+		"pending@mail.com": users.StatusPending,
+		"deleted@mail.com": users.StatusDeleted,
+	}}
+	service := NewEmailChangeService(nil, dao, nil, nil)
+
+	for email := range dao.emails {
+		if err := service.CheckNewEmail(" " + email); err == nil || err.Status() != http.StatusConflict {
+			t.Errorf("%s: got %v, want a conflict", email, err)
+		}
+	}
+	if err := service.CheckNewEmail("free@mail.com"); err != nil {
+		t.Errorf("free@mail.com: got %v, want it accepted", err)
+	}
+}
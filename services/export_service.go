@@ -0,0 +1,217 @@
+package services
+
+import (
+	"github.com/Abacode7/bookstore_users-api/domain/addresses"
+	"github.com/Abacode7/bookstore_users-api/domain/exports"
+	"github.com/Abacode7/bookstore_users-api/domain/organizations"
+	"github.com/Abacode7/bookstore_users-api/domain/outbox"
+	"github.com/Abacode7/bookstore_users-api/domain/users"
+	"github.com/Abacode7/bookstore_users-api/utils/date_utils"
+	"github.com/Abacode7/bookstore_users-api/utils/error_utils"
+	"github.com/Abacode7/bookstore_utils-go/v2/rest_error"
+	"time"
+)
+
+const (
+	exportBatchSize = 5
+	/// maxExportEvents bounds the sign-ins and activity an archive holds
+	maxExportEvents = 10000
+)
+
+type IExportService interface {
+	RequestExport(Caller, int64, exports.ExportRequest) (*exports.Export, rest_error.RestErr)
+	GetExport(Caller, int64, int64) (*exports.Export, rest_error.RestErr)
+	DownloadExport(Caller, int64, int64) (*exports.Export, []byte, rest_error.RestErr)
+	Run(stop <-chan struct{})
+}
+
+/// ExportSources are the daos an archive is assembled from
+type ExportSources struct {
+	UserDao         users.IUserDao
+	ProfileDao      users.IProfileDao
+	EmailChangeDao  users.IEmailChangeDao
+	ScheduleDao     users.IStatusScheduleDao
+	AddressDao      addresses.IAddressDao
+	OrganizationDao organizations.IOrganizationDao
+	EventDao        outbox.IEventDao
+}
+
+type exportService struct {
+	exportDao exports.IExportDao
+	sources   ExportSources
+	ttl       time.Duration
+	interval  time.Duration
+}
+
+/// NewExportService is exportService's constructor, completed exports can
+/// be downloaded for ttl and pending ones are built every interval
+func NewExportService(exportDao exports.IExportDao, sources ExportSources, ttl time.Duration, interval time.Duration) IExportService {
+	return &exportService{exportDao: exportDao, sources: sources, ttl: ttl, interval: interval}
+}
+
+/// RequestExport queues an export of the data held about user with
+/// userId, only one can be pending at a time
+func (es *exportService) RequestExport(caller Caller, userId int64, request exports.ExportRequest) (*exports.Export, rest_error.RestErr) {
+	if err := authorizeExport(caller, userId); err != nil {
+		return nil, err
+	}
+	if err := request.Validate(); err != nil {
+		return nil, err
+	}
+	if _, err := es.sources.UserDao.Get(userId); err != nil {
+		return nil, err
+	}
+	return es.exportDao.Save(exports.Export{
+		UserId:      userId,
+		RequestedBy: caller.UserId,
+		Format:      request.Format,
+		Status:      exports.StatusPending,
+		DateCreated: date_utils.GetDbFormattedTime(),
+	})
+}
+
+func (es *exportService) GetExport(caller Caller, userId int64, exportId int64) (*exports.Export, rest_error.RestErr) {
+	if err := authorizeExport(caller, userId); err != nil {
+		return nil, err
+	}
+	export, err := es.exportDao.Get(userId, exportId)
+	if err != nil {
+		return nil, err
+	}
+	if export.Status == exports.StatusCompleted && isExpired(export.ExpiresAt) {
+		export.Status = exports.StatusExpired
+	}
+	return export, nil
+}
+
+/// DownloadExport gets a completed export along with its archive
+func (es *exportService) DownloadExport(caller Caller, userId int64, exportId int64) (*exports.Export, []byte, rest_error.RestErr) {
+	export, err := es.GetExport(caller, userId, exportId)
+	if err != nil {
+		return nil, nil, err
+	}
+	switch export.Status {
+	case exports.StatusExpired:
+		return nil, nil, error_utils.NewGoneError("export has expired, request a new one")
+	case exports.StatusFailed:
+		return nil, nil, error_utils.NewConflictError("export failed, request a new one")
+	case exports.StatusPending:
+		return nil, nil, error_utils.NewConflictError("export is not ready yet")
+	}
+	archive, err := es.exportDao.GetArchive(userId, exportId)
+	if err != nil {
+		return nil, nil, err
+	}
+	return export, archive, nil
+}
+
+/// Run builds pending exports and expires the downloads past their ttl
+/// until stop is closed, with a nil stop it runs for the lifetime of the
+/// process
+func (es *exportService) Run(stop <-chan struct{}) {
+	ticker := time.NewTicker(es.interval)
+	defer ticker.Stop()
+	for {
+		es.processPending()
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+/// processPending drains pending exports batch by batch, failures are
+/// logged by the dao and the next tick tries again. Like applyDue, it
+/// stops at a batch with exports left pending for a retry.
+func (es *exportService) processPending() {
+	es.exportDao.ExpireCompleted()
+	for {
+		processed, err := es.exportDao.ProcessPending(exportBatchSize, es.build, es.ttl)
+		if err != nil || processed < exportBatchSize {
+			return
+		}
+	}
+}
+
+/// build assembles and encodes the archive of export
+func (es *exportService) build(export exports.Export) ([]byte, rest_error.RestErr) {
+	archive, err := es.assemble(export.UserId)
+	if err != nil {
+		return nil, err
+	}
+	return archive.Encode(export.Format)
+}
+
+func (es *exportService) assemble(userId int64) (*exports.Archive, rest_error.RestErr) {
+	var err rest_error.RestErr
+	archive := exports.Archive{GeneratedAt: date_utils.GetDbFormattedTime()}
+
+	user, err := es.sources.UserDao.Get(userId)
+	if err != nil {
+		return nil, err
+	}
+	if archive.User, err = user.Marshall(false); err != nil {
+		return nil, err
+	}
+	if archive.Profile, err = es.sources.ProfileDao.Get(userId, 0); err != nil {
+		return nil, err
+	}
+	/// Attributes of every tenant are in ProfileAttributes
+	archive.Profile.Attributes = nil
+	if archive.ProfileAttributes, err = es.sources.ProfileDao.GetAllAttributes(userId); err != nil {
+		return nil, err
+	}
+	if archive.Addresses, err = es.sources.AddressDao.FindByUser(userId); err != nil {
+		return nil, err
+	}
+	if archive.Organizations, err = es.sources.OrganizationDao.FindByUser(userId); err != nil {
+		return nil, err
+	}
+	if archive.StatusHistory, err = es.sources.UserDao.FindStatusHistory(userId); err != nil {
+		return nil, err
+	}
+	if archive.ScheduledStatusChanges, err = es.sources.ScheduleDao.FindByUser(userId); err != nil {
+		return nil, err
+	}
+
+	emailChanges, err := es.sources.EmailChangeDao.FindByUser(userId)
+	if err != nil {
+		return nil, err
+	}
+	archive.EmailChanges = make([]exports.EmailChangeRecord, 0, len(emailChanges))
+	for _, change := range emailChanges {
+		archive.EmailChanges = append(archive.EmailChanges, exports.EmailChangeRecord{
+			OldEmail:    change.OldEmail,
+			NewEmail:    change.NewEmail,
+			Status:      change.Status,
+			DateCreated: change.DateCreated,
+		})
+	}
+
+	events, err := es.sources.EventDao.FindByAggregate(userId, maxExportEvents)
+	if err != nil {
+		return nil, err
+	}
+	archive.SignIns = make([]exports.SignIn, 0)
+	archive.Activity = make([]exports.ActivityEntry, 0)
+	for _, event := range events {
+		if event.Type == users.EventUserLogin {
+			archive.SignIns = append(archive.SignIns, exports.SignIn{DateCreated: event.DateCreated})
+			continue
+		}
+		archive.Activity = append(archive.Activity, exports.ActivityEntry{
+			Type:        event.Type,
+			DateCreated: event.DateCreated,
+			Payload:     event.Payload,
+		})
+	}
+	return &archive, nil
+}
+
+func authorizeExport(caller Caller, userId int64) rest_error.RestErr {
+	if !caller.Privileged && caller.UserId != userId {
+		return error_utils.NewForbiddenError("not allowed to export this user's data")
+	}
+	return nil
+}
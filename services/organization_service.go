@@ -0,0 +1,282 @@
+package services
+
+import (
+	"fmt"
+	"github.com/Abacode7/bookstore_users-api/domain/organizations"
+	"github.com/Abacode7/bookstore_users-api/domain/users"
+	"github.com/Abacode7/bookstore_users-api/notifiers"
+	"github.com/Abacode7/bookstore_users-api/utils/crypto_utils"
+	"github.com/Abacode7/bookstore_users-api/utils/date_utils"
+	"github.com/Abacode7/bookstore_users-api/utils/error_utils"
+	"github.com/Abacode7/bookstore_utils-go/v2/logger"
+	"github.com/Abacode7/bookstore_utils-go/v2/rest_error"
+	"net/http"
+	"strings"
+	"time"
+)
+
+/// organizationInvitationTTL is how long an organization invitation can be accepted
+const organizationInvitationTTL = 7 * 24 * time.Hour
+
+type IOrganizationService interface {
+	CreateOrganization(Caller, organizations.Organization) (*organizations.Organization, rest_error.RestErr)
+	GetOrganization(Caller, int64) (*organizations.Organization, rest_error.RestErr)
+	ListUserOrganizations(Caller, int64) (organizations.Memberships, rest_error.RestErr)
+	ListUsersOrganizations(Caller, []int64) (map[int64]organizations.Memberships, rest_error.RestErr)
+	ListMembers(Caller, int64) (organizations.Members, rest_error.RestErr)
+	InviteMember(Caller, int64, string, string) (*organizations.Invitation, rest_error.RestErr)
+	AcceptInvitation(Caller, string) (*organizations.Member, rest_error.RestErr)
+	UpdateMemberRole(Caller, int64, int64, string) (*organizations.Member, rest_error.RestErr)
+	RemoveMember(Caller, int64, int64) rest_error.RestErr
+}
+
+type organizationService struct {
+	organizationDao organizations.IOrganizationDao
+	invitationDao   organizations.IInvitationDao
+	userDao         users.IUserDao
+	notifier        notifiers.INotifier
+}
+
+/// NewOrganizationService is organizationService's constructor
+func NewOrganizationService(organizationDao organizations.IOrganizationDao, invitationDao organizations.IInvitationDao,
+	userDao users.IUserDao, notifier notifiers.INotifier) IOrganizationService {
+	return &organizationService{
+		organizationDao: organizationDao,
+		invitationDao:   invitationDao,
+		userDao:         userDao,
+		notifier:        notifier,
+	}
+}
+
+/// CreateOrganization creates an organization owned by the caller
+func (orgs *organizationService) CreateOrganization(caller Caller, org organizations.Organization) (*organizations.Organization, rest_error.RestErr) {
+	if err := org.Validate(); err != nil {
+		return nil, err
+	}
+	if caller.UserId <= 0 {
+		return nil, rest_error.NewBadRequestError("organizations must be created by a user")
+	}
+	org.DateCreated = date_utils.GetDbFormattedTime()
+	owner := organizations.Member{
+		UserId:      caller.UserId,
+		Role:        organizations.RoleOwner,
+		DateCreated: org.DateCreated,
+	}
+	return orgs.organizationDao.Save(org, owner)
+}
+
+func (orgs *organizationService) GetOrganization(caller Caller, orgId int64) (*organizations.Organization, rest_error.RestErr) {
+	if _, err := orgs.authorizeMember(caller, orgId); err != nil {
+		return nil, err
+	}
+	return orgs.organizationDao.Get(orgId)
+}
+
+func (orgs *organizationService) ListUserOrganizations(caller Caller, userId int64) (organizations.Memberships, rest_error.RestErr) {
+	if !caller.Privileged && caller.UserId != userId {
+		return nil, error_utils.NewForbiddenError("not allowed to list this user's organizations")
+	}
+	return orgs.organizationDao.FindByUser(userId)
+}
+
+/// ListUsersOrganizations is ListUserOrganizations for several users at
+/// once, keyed by user id. The caller must be allowed to list them all.
+func (orgs *organizationService) ListUsersOrganizations(caller Caller, userIds []int64) (map[int64]organizations.Memberships, rest_error.RestErr) {
+	for _, userId := range userIds {
+		if !caller.Privileged && caller.UserId != userId {
+			return nil, error_utils.NewForbiddenError("not allowed to list this user's organizations")
+		}
+	}
+	return orgs.organizationDao.FindByUsers(userIds)
+}
+
+func (orgs *organizationService) ListMembers(caller Caller, orgId int64) (organizations.Members, rest_error.RestErr) {
+	if _, err := orgs.authorizeMember(caller, orgId); err != nil {
+		return nil, err
+	}
+	return orgs.organizationDao.FindMembers(orgId)
+}
+
+/// InviteMember sends an invitation to join the organization with role
+/// to email. Only members allowed to manage that role may invite.
+func (orgs *organizationService) InviteMember(caller Caller, orgId int64, email string, role string) (*organizations.Invitation, rest_error.RestErr) {
+	email = strings.TrimSpace(email)
+	if email == "" {
+		return nil, rest_error.NewBadRequestError("invalid email address")
+	}
+	if !organizations.ValidRole(role) {
+		return nil, rest_error.NewBadRequestError("invalid organization role")
+	}
+	callerMember, err := orgs.authorizeMember(caller, orgId)
+	if err != nil {
+		return nil, err
+	}
+	if err := authorizeManage(caller, callerMember, role); err != nil {
+		return nil, err
+	}
+	org, err := orgs.organizationDao.Get(orgId)
+	if err != nil {
+		return nil, err
+	}
+
+	token, tokenErr := crypto_utils.GenerateToken()
+	if tokenErr != nil {
+		logger.Error("error generating invitation token", tokenErr)
+		return nil, rest_error.NewInternalServerError("error creating invitation")
+	}
+	now := date_utils.GetTime()
+	invitation, err := orgs.invitationDao.Save(organizations.Invitation{
+		OrganizationId: orgId,
+		Email:          email,
+		Role:           role,
+		InvitedBy:      caller.UserId,
+		TokenHash:      crypto_utils.GetSha256(token),
+		Status:         organizations.InvitationPending,
+		DateCreated:    date_utils.FormatDbTime(now),
+		ExpiresAt:      date_utils.FormatDbTime(now.Add(organizationInvitationTTL)),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := orgs.notifier.Notify(notifiers.Message{
+		To:      email,
+		Subject: fmt.Sprintf("You have been invited to join %s", org.Name),
+		Body:    fmt.Sprintf("Use this token to join %s as %s within %s: %s", org.Name, role, organizationInvitationTTL, token),
+	}); err != nil {
+		logger.Error("error sending organization invitation", err)
+		return nil, rest_error.NewInternalServerError("error sending invitation")
+	}
+	return invitation, nil
+}
+
+/// AcceptInvitation adds the caller to the organization they were
+/// invited to, provided the invitation was sent to their email
+func (orgs *organizationService) AcceptInvitation(caller Caller, token string) (*organizations.Member, rest_error.RestErr) {
+	invitation, err := orgs.invitationDao.FindByToken(crypto_utils.GetSha256(token))
+	if err != nil {
+		return nil, err
+	}
+	if invitation.Status != organizations.InvitationPending || isExpired(invitation.ExpiresAt) {
+		return nil, rest_error.NewBadRequestError("invitation is no longer valid")
+	}
+	user, err := orgs.userDao.Get(caller.UserId)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.EqualFold(user.Email, invitation.Email) {
+		return nil, error_utils.NewForbiddenError("invitation was sent to another email address")
+	}
+	if _, err := orgs.organizationDao.GetMember(invitation.OrganizationId, user.Id); err == nil {
+		return nil, error_utils.NewConflictError("user is already a member of the organization")
+	} else if err.Status() != http.StatusNotFound {
+		return nil, err
+	}
+
+	member := organizations.Member{
+		OrganizationId: invitation.OrganizationId,
+		UserId:         user.Id,
+		Role:           invitation.Role,
+		DateCreated:    date_utils.GetDbFormattedTime(),
+	}
+	if err := orgs.organizationDao.SaveMember(member); err != nil {
+		return nil, err
+	}
+	if err := orgs.invitationDao.UpdateStatus(invitation.Id, organizations.InvitationAccepted); err != nil {
+		return nil, err
+	}
+	return &member, nil
+}
+
+/// UpdateMemberRole changes the role of a member. The caller must be
+/// allowed to manage both the member's current and new role.
+func (orgs *organizationService) UpdateMemberRole(caller Caller, orgId int64, userId int64, role string) (*organizations.Member, rest_error.RestErr) {
+	if !organizations.ValidRole(role) {
+		return nil, rest_error.NewBadRequestError("invalid organization role")
+	}
+	callerMember, err := orgs.authorizeMember(caller, orgId)
+	if err != nil {
+		return nil, err
+	}
+	member, err := orgs.organizationDao.GetMember(orgId, userId)
+	if err != nil {
+		return nil, err
+	}
+	if err := authorizeManage(caller, callerMember, member.Role); err != nil {
+		return nil, err
+	}
+	if err := authorizeManage(caller, callerMember, role); err != nil {
+		return nil, err
+	}
+	if member.Role == organizations.RoleOwner && role != organizations.RoleOwner {
+		if err := orgs.checkNotLastOwner(orgId); err != nil {
+			return nil, err
+		}
+	}
+	member.Role = role
+	if err := orgs.organizationDao.SaveMember(*member); err != nil {
+		return nil, err
+	}
+	return member, nil
+}
+
+/// RemoveMember removes a member from the organization. Members may
+/// always leave themselves, removing others requires managing them.
+func (orgs *organizationService) RemoveMember(caller Caller, orgId int64, userId int64) rest_error.RestErr {
+	callerMember, err := orgs.authorizeMember(caller, orgId)
+	if err != nil {
+		return err
+	}
+	member, err := orgs.organizationDao.GetMember(orgId, userId)
+	if err != nil {
+		return err
+	}
+	if caller.UserId != userId {
+		if err := authorizeManage(caller, callerMember, member.Role); err != nil {
+			return err
+		}
+	}
+	if member.Role == organizations.RoleOwner {
+		if err := orgs.checkNotLastOwner(orgId); err != nil {
+			return err
+		}
+	}
+	return orgs.organizationDao.DeleteMember(orgId, userId)
+}
+
+/// authorizeMember checks the caller belongs to the organization and
+/// returns their membership, which is nil for privileged non members
+func (orgs *organizationService) authorizeMember(caller Caller, orgId int64) (*organizations.Member, rest_error.RestErr) {
+	member, err := orgs.organizationDao.GetMember(orgId, caller.UserId)
+	if err == nil {
+		return member, nil
+	}
+	if err.Status() != http.StatusNotFound {
+		return nil, err
+	}
+	if caller.Privileged {
+		return nil, nil
+	}
+	return nil, error_utils.NewForbiddenError("not a member of the organization")
+}
+
+func (orgs *organizationService) checkNotLastOwner(orgId int64) rest_error.RestErr {
+	owners, err := orgs.organizationDao.CountOwners(orgId)
+	if err != nil {
+		return err
+	}
+	if owners <= 1 {
+		return error_utils.NewConflictError("organization must keep at least one owner")
+	}
+	return nil
+}
+
+/// authorizeManage checks the caller may manage members with targetRole
+func authorizeManage(caller Caller, callerMember *organizations.Member, targetRole string) rest_error.RestErr {
+	if caller.Privileged {
+		return nil
+	}
+	if callerMember == nil || !organizations.CanManage(callerMember.Role, targetRole) {
+		return error_utils.NewForbiddenError("not allowed to manage members with role " + targetRole)
+	}
+	return nil
+}
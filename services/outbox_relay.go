@@ -0,0 +1,72 @@
+package services
+
+import (
+	"github.com/Abacode7/bookstore_users-api/domain/outbox"
+	"github.com/Abacode7/bookstore_users-api/publishers"
+	"time"
+)
+
+const (
+	outboxBatchSize     = 50
+	outboxBaseRetry     = time.Second
+	outboxMaxRetryDelay = time.Hour
+)
+
+type IOutboxRelay interface {
+	Run(stop <-chan struct{})
+}
+
+type outboxRelay struct {
+	eventDao  outbox.IEventDao
+	publisher publishers.IPublisher
+	interval  time.Duration
+}
+
+/// NewOutboxRelay is outboxRelay's constructor, it polls the outbox every
+/// interval
+func NewOutboxRelay(eventDao outbox.IEventDao, publisher publishers.IPublisher, interval time.Duration) IOutboxRelay {
+	return &outboxRelay{eventDao: eventDao, publisher: publisher, interval: interval}
+}
+
+/// Run relays due outbox events to the publisher until stop is closed,
+/// with a nil stop it runs for the lifetime of the process
+func (or *outboxRelay) Run(stop <-chan struct{}) {
+	ticker := time.NewTicker(or.interval)
+	defer ticker.Stop()
+	for {
+		or.relayDue()
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+/// relayDue drains due events batch by batch, failures are logged by the
+/// dao and the next tick tries again
+func (or *outboxRelay) relayDue() {
+	for {
+		processed, err := or.eventDao.ProcessDue(outboxBatchSize, or.publisher.Publish, outboxRetryDelay)
+		if err != nil || processed < outboxBatchSize {
+			return
+		}
+	}
+}
+
+/// outboxRetryDelay doubles with every failed attempt, up to an hour
+func outboxRetryDelay(attempts int) time.Duration {
+	return backoff(outboxBaseRetry, outboxMaxRetryDelay, attempts)
+}
+
+/// backoff is base doubled for every attempt after the first, capped at max
+func backoff(base time.Duration, max time.Duration, attempts int) time.Duration {
+	delay := base
+	for i := 1; i < attempts && delay < max; i++ {
+		delay *= 2
+	}
+	if delay > max {
+		return max
+	}
+	return delay
+}
@@ -0,0 +1,77 @@
+package services
+
+import (
+	"github.com/Abacode7/bookstore_users-api/domain/users"
+	"github.com/Abacode7/bookstore_users-api/utils/crypto_utils"
+	"github.com/Abacode7/bookstore_utils-go/v2/logger"
+	"github.com/Abacode7/bookstore_utils-go/v2/rest_error"
+)
+
+type IPasswordService interface {
+	HashPassword(string) (string, rest_error.RestErr)
+	HashNewPassword(int64, string, string) (string, rest_error.RestErr)
+	RecordPassword(int64, string)
+}
+
+type passwordService struct {
+	passwordHistoryDao   users.IPasswordHistoryDao
+	passwordHistoryDepth int
+}
+
+/// NewPasswordService is passwordService's constructor. passwordHistoryDepth
+/// is the number of previous passwords a user may not reuse, 0 disables
+/// the check.
+func NewPasswordService(passwordHistoryDao users.IPasswordHistoryDao, passwordHistoryDepth int) IPasswordService {
+	return &passwordService{
+		passwordHistoryDao:   passwordHistoryDao,
+		passwordHistoryDepth: passwordHistoryDepth,
+	}
+}
+
+/// HashNewPassword hashes a password that is about to replace currentHash,
+/// rejecting it if it matches the current or a recently used password.
+/// Every flow that sets a user's password must go through it.
+func (ps *passwordService) HashNewPassword(userId int64, password string, currentHash string) (string, rest_error.RestErr) {
+	if ps.passwordHistoryDepth > 0 {
+		hashes, err := ps.passwordHistoryDao.GetRecent(userId, ps.passwordHistoryDepth)
+		if err != nil {
+			return "", err
+		}
+		if currentHash != "" {
+			hashes = append(hashes, currentHash)
+		}
+		for _, hash := range hashes {
+			if crypto_utils.CompareHashAndPassword(hash, password) == nil {
+				return "", rest_error.NewBadRequestError("password has been used recently")
+			}
+		}
+	}
+	return ps.HashPassword(password)
+}
+
+/// HashPassword hashes the password of a user created with it, who has
+/// no password to check it against
+func (ps *passwordService) HashPassword(password string) (string, rest_error.RestErr) {
+	hash, err := crypto_utils.GetHash(password)
+	if err != nil {
+		logger.Error("error generating password hash", err)
+		return "", rest_error.NewBadRequestError("invalid user password")
+	}
+	return hash, nil
+}
+
+/// RecordPassword adds a password hash to the user's history and prunes
+/// entries beyond the configured depth. Failures are logged, not returned,
+/// since the password itself has already been persisted.
+func (ps *passwordService) RecordPassword(userId int64, passwordHash string) {
+	if ps.passwordHistoryDepth <= 0 {
+		return
+	}
+	if err := ps.passwordHistoryDao.Save(userId, passwordHash); err != nil {
+		logger.Error("error saving password history", err)
+		return
+	}
+	if err := ps.passwordHistoryDao.Prune(userId, ps.passwordHistoryDepth); err != nil {
+		logger.Error("error pruning password history", err)
+	}
+}
@@ -0,0 +1,62 @@
+package services
+
+import (
+	"github.com/Abacode7/bookstore_users-api/domain/users"
+	"github.com/Abacode7/bookstore_utils-go/v2/rest_error"
+)
+
+type IProfileService interface {
+	GetProfile(int64, int64) (*users.Profile, rest_error.RestErr)
+	ValidateProfile(int64, *users.Profile) rest_error.RestErr
+	UpdateProfile(int64, int64, users.Profile) (*users.Profile, rest_error.RestErr)
+}
+
+type profileService struct {
+	profileDao users.IProfileDao
+}
+
+/// NewProfileService is profileService's constructor
+func NewProfileService(profileDao users.IProfileDao) IProfileService {
+	return &profileService{profileDao: profileDao}
+}
+
+/// GetProfile gets the profile of user with userId as seen by tenant tenantId
+func (ps *profileService) GetProfile(userId int64, tenantId int64) (*users.Profile, rest_error.RestErr) {
+	definitions, err := ps.profileDao.GetAttributeDefinitions(tenantId)
+	if err != nil {
+		return nil, err
+	}
+	profile, err := ps.profileDao.Get(userId, tenantId)
+	if err != nil {
+		return nil, err
+	}
+	profile.SetAttributeVisibility(definitions)
+	return profile, nil
+}
+
+/// ValidateProfile validates profile against the attributes tenant
+/// tenantId defines, without saving it
+func (ps *profileService) ValidateProfile(tenantId int64, profile *users.Profile) rest_error.RestErr {
+	definitions, err := ps.profileDao.GetAttributeDefinitions(tenantId)
+	if err != nil {
+		return err
+	}
+	return profile.Validate(definitions)
+}
+
+/// UpdateProfile replaces the profile of user with userId and the user's
+/// attributes of tenant tenantId
+func (ps *profileService) UpdateProfile(userId int64, tenantId int64, profile users.Profile) (*users.Profile, rest_error.RestErr) {
+	definitions, err := ps.profileDao.GetAttributeDefinitions(tenantId)
+	if err != nil {
+		return nil, err
+	}
+	if err := profile.Validate(definitions); err != nil {
+		return nil, err
+	}
+	if err := ps.profileDao.Save(userId, tenantId, profile); err != nil {
+		return nil, err
+	}
+	profile.SetAttributeVisibility(definitions)
+	return &profile, nil
+}
@@ -0,0 +1,158 @@
+package services
+
+import (
+	"github.com/Abacode7/bookstore_users-api/domain/users"
+	"github.com/Abacode7/bookstore_users-api/utils/date_utils"
+	"github.com/Abacode7/bookstore_users-api/utils/error_utils"
+	"github.com/Abacode7/bookstore_utils-go/v2/rest_error"
+	"time"
+)
+
+const statusScheduleBatchSize = 50
+
+/// IStatusScheduleService schedules status changes and expiries of users
+/// for admins, and applies them once due while it runs
+type IStatusScheduleService interface {
+	ScheduleChange(Caller, int64, users.StatusScheduleRequest) (*users.ScheduledStatusChange, rest_error.RestErr)
+	ListChanges(Caller, int64) ([]users.ScheduledStatusChange, rest_error.RestErr)
+	CancelChange(caller Caller, userId int64, changeId int64) rest_error.RestErr
+	SetExpiry(Caller, int64, users.ExpiryRequest) (*users.ScheduledStatusChange, rest_error.RestErr)
+	ClearExpiry(Caller, int64) rest_error.RestErr
+	Run(stop <-chan struct{})
+}
+
+type statusScheduleService struct {
+	scheduleDao users.IStatusScheduleDao
+	userService IUserService
+	interval    time.Duration
+}
+
+/// NewStatusScheduleService is statusScheduleService's constructor, it
+/// polls for due changes every interval
+func NewStatusScheduleService(scheduleDao users.IStatusScheduleDao, userService IUserService, interval time.Duration) IStatusScheduleService {
+	return &statusScheduleService{scheduleDao: scheduleDao, userService: userService, interval: interval}
+}
+
+/// ScheduleChange schedules a change of the user with userId. Whether
+/// the user's status allows it is only checked once it is due.
+func (sss *statusScheduleService) ScheduleChange(caller Caller, userId int64, request users.StatusScheduleRequest) (*users.ScheduledStatusChange, rest_error.RestErr) {
+	if err := authorizeStatusSchedule(caller); err != nil {
+		return nil, err
+	}
+	if err := request.Validate(date_utils.GetTime()); err != nil {
+		return nil, err
+	}
+	if _, err := sss.userService.GetUser(userId); err != nil {
+		return nil, err
+	}
+	return sss.scheduleDao.Schedule(users.ScheduledStatusChange{
+		UserId:      userId,
+		Kind:        users.ScheduleKindChange,
+		ToStatus:    request.Status,
+		Reason:      request.Reason,
+		ScheduledBy: caller.UserId,
+		DueAt:       date_utils.FormatDbTime(request.DueAt),
+	})
+}
+
+/// ListChanges gets the latest scheduled changes of the user with userId
+func (sss *statusScheduleService) ListChanges(caller Caller, userId int64) ([]users.ScheduledStatusChange, rest_error.RestErr) {
+	if err := authorizeStatusSchedule(caller); err != nil {
+		return nil, err
+	}
+	if _, err := sss.userService.GetUser(userId); err != nil {
+		return nil, err
+	}
+	return sss.scheduleDao.FindByUser(userId)
+}
+
+func (sss *statusScheduleService) CancelChange(caller Caller, userId int64, changeId int64) rest_error.RestErr {
+	if err := authorizeStatusSchedule(caller); err != nil {
+		return err
+	}
+	return sss.scheduleDao.Cancel(userId, changeId)
+}
+
+/// SetExpiry makes the user with userId expire at request.ExpiresAt,
+/// replacing any expiry it had
+func (sss *statusScheduleService) SetExpiry(caller Caller, userId int64, request users.ExpiryRequest) (*users.ScheduledStatusChange, rest_error.RestErr) {
+	if err := authorizeStatusSchedule(caller); err != nil {
+		return nil, err
+	}
+	if err := request.Validate(date_utils.GetTime()); err != nil {
+		return nil, err
+	}
+	if _, err := sss.userService.GetUser(userId); err != nil {
+		return nil, err
+	}
+	return sss.scheduleDao.SetExpiry(users.ScheduledStatusChange{
+		UserId:      userId,
+		Kind:        users.ScheduleKindExpiry,
+		ToStatus:    users.ExpiryStatus,
+		Reason:      users.ExpiryReason,
+		ScheduledBy: caller.UserId,
+		DueAt:       date_utils.FormatDbTime(request.ExpiresAt),
+	})
+}
+
+func (sss *statusScheduleService) ClearExpiry(caller Caller, userId int64) rest_error.RestErr {
+	if err := authorizeStatusSchedule(caller); err != nil {
+		return err
+	}
+	return sss.scheduleDao.CancelExpiry(userId)
+}
+
+/// Run applies due changes until stop is closed, with a nil stop it runs
+/// for the lifetime of the process
+func (sss *statusScheduleService) Run(stop <-chan struct{}) {
+	ticker := time.NewTicker(sss.interval)
+	defer ticker.Stop()
+	for {
+		sss.applyDue()
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+/// applyDue drains due changes batch by batch, failures are logged by
+/// the dao and the next tick tries again. It stops at a batch that
+/// wasn't fully marked: the changes left pending by a server error are
+/// still due and would be claimed again right away.
+func (sss *statusScheduleService) applyDue() {
+	for {
+		marked, err := sss.scheduleDao.ProcessDue(statusScheduleBatchSize, sss.apply)
+		if err != nil || marked < statusScheduleBatchSize {
+			return
+		}
+	}
+}
+
+/// apply makes a due change on behalf of the admin who scheduled it, so
+/// that it is validated and recorded in the user's status history like
+/// theirs. A user already in the target status counts as applied, the
+/// change may have been applied by a run that didn't get to mark it.
+func (sss *statusScheduleService) apply(change users.ScheduledStatusChange) rest_error.RestErr {
+	user, err := sss.userService.GetUser(change.UserId)
+	if err != nil {
+		return err
+	}
+	if user.Status == change.ToStatus {
+		return nil
+	}
+	_, err = sss.userService.ChangeStatus(Caller{UserId: change.ScheduledBy, Privileged: true}, users.StatusChange{
+		UserId:   change.UserId,
+		ToStatus: change.ToStatus,
+		Reason:   change.Reason,
+	})
+	return err
+}
+
+func authorizeStatusSchedule(caller Caller) rest_error.RestErr {
+	if !caller.Privileged {
+		return error_utils.NewForbiddenError("only admins can schedule status changes")
+	}
+	return nil
+}
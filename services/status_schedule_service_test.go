@@ -0,0 +1,107 @@
+package services
+
+import (
+	"github.com/Abacode7/bookstore_users-api/domain/users"
+	"github.com/Abacode7/bookstore_utils-go/v2/rest_error"
+	"net/http"
+	"testing"
+)
+
+/// memoryScheduleDao claims pending changes in order like the database
+/// one: changes failing with a server error are deferred, and only the
+/// marked changes are counted
+type memoryScheduleDao struct {
+	users.IStatusScheduleDao
+	changes  []users.ScheduledStatusChange
+	deferred map[int64]bool
+	calls    int
+}
+
+func (md *memoryScheduleDao) ProcessDue(limit int, apply func(users.ScheduledStatusChange) rest_error.RestErr) (int, rest_error.RestErr) {
+	md.calls++
+	marked, claimed := 0, 0
+	for i := range md.changes {
+		change := &md.changes[i]
+		if claimed == limit {
+			break
+		}
+		if change.State != users.SchedulePending || md.deferred[change.Id] {
+			continue
+		}
+		claimed++
+		if err := apply(*change); err != nil {
+			if err.Status() >= http.StatusInternalServerError {
+				md.deferred[change.Id] = true
+				continue
+			}
+			change.State = users.ScheduleFailed
+		} else {
+			change.State = users.ScheduleApplied
+		}
+		marked++
+	}
+	return marked, nil
+}
+
+/// unreachableUserService fails to read the users in broken with a
+/// server error
+type unreachableUserService struct {
+	IUserService
+	broken map[int64]bool
+}
+
+func (us *unreachableUserService) GetUser(userId int64) (*users.User, rest_error.RestErr) {
+	if us.broken[userId] {
+		return nil, rest_error.NewInternalServerError("database error")
+	}
+	return &users.User{Id: userId, Status: users.StatusDeactivated}, nil
+}
+
+func TestApplyDueStopsAtServerErrors(t *testing.T) {
+	dao := &memoryScheduleDao{deferred: make(map[int64]bool)}
+	userService := &unreachableUserService{broken: make(map[int64]bool)}
+	for id := int64(1); id <= statusScheduleBatchSize+10; id++ {
+		dao.changes = append(dao.changes, users.ScheduledStatusChange{
+			Id: id, UserId: id, ToStatus: users.StatusDeactivated, State: users.SchedulePending,
+		})
+		if id <= statusScheduleBatchSize {
+			userService.broken[id] = true
+		}
+	}
+	service := &statusScheduleService{scheduleDao: dao, userService: userService}
+
+	service.applyDue()
+	if dao.calls != 1 {
+		t.Fatalf("got %d batches for a batch of server errors, want 1", dao.calls)
+	}
+	service.applyDue()
+	applied := 0
+	for _, change := range dao.changes {
+		if change.State == users.ScheduleApplied {
+			applied++
+		}
+	}
+	if applied != 10 {
+		t.Errorf("got %d changes applied after the deferred ones, want 10", applied)
+	}
+}
+
+func TestApplyDueDrainsFullBatches(t *testing.T) {
+	dao := &memoryScheduleDao{deferred: make(map[int64]bool)}
+	for id := int64(1); id <= 2*statusScheduleBatchSize+1; id++ {
+		dao.changes = append(dao.changes, users.ScheduledStatusChange{
+			Id: id, UserId: id, ToStatus: users.StatusDeactivated, State: users.SchedulePending,
+		})
+	}
+	service := &statusScheduleService{scheduleDao: dao, userService: &unreachableUserService{}}
+
+	service.applyDue()
+	if dao.calls != 3 {
+		t.Errorf("got %d batches, want 3", dao.calls)
+	}
+	for _, change := range dao.changes {
+		if change.State != users.ScheduleApplied {
+			t.Fatalf("change %d is %s, want all applied", change.Id, change.State)
+		}
+	}
+}
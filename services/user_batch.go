@@ -0,0 +1,203 @@
+package services
+
+import (
+	"fmt"
+	"github.com/Abacode7/bookstore_users-api/domain/users"
+	"github.com/Abacode7/bookstore_users-api/utils/error_utils"
+	"github.com/Abacode7/bookstore_utils-go/v2/rest_error"
+)
+
+/// ApplyBatch applies the operations of request in order, through the
+/// same validation as the single user endpoints. There is a result for
+/// every operation, an atomic batch that failed reports the failing
+/// operation's error and a failed dependency for the others.
+func (us *userService) ApplyBatch(caller Caller, request users.BatchRequest) ([]users.BatchOperationResult, rest_error.RestErr) {
+	if !caller.Privileged {
+		return nil, error_utils.NewForbiddenError("only admins can apply batches")
+	}
+	if err := request.Validate(); err != nil {
+		return nil, err
+	}
+	if request.Atomic {
+		return us.applyAtomic(caller, request.Operations), nil
+	}
+	results := make([]users.BatchOperationResult, len(request.Operations))
+	for i, operation := range request.Operations {
+		results[i] = us.applyOperation(caller, i, operation)
+	}
+	return results, nil
+}
+
+/// applyAtomic applies operations in a single transaction. Passwords are
+/// hashed before it starts, and the password history and change feed are
+/// only written once it has committed.
+func (us *userService) applyAtomic(caller Caller, operations []users.BatchOperation) []users.BatchOperationResult {
+	results := make([]users.BatchOperationResult, len(operations))
+	for i, operation := range operations {
+		results[i] = users.BatchOperationResult{Index: i, Op: operation.Op}
+	}
+	hashes, failed, hashErr := us.hashPasswords(operations)
+	if hashErr != nil {
+		results[failed].Err = hashErr
+		return failDependents(results, failed, "not attempted")
+	}
+	var effects []func()
+	txErr := us.userDao.Transaction(func(txDao users.IUserDao) rest_error.RestErr {
+		txService := &userService{
+			userDao:            txDao,
+			passwordService:    deferredPasswordService{us.passwordService, hashes, &effects},
+			emailChangeService: us.emailChangeService,
+			changeFeed:         deferredChangeFeed{us.changeFeed, &effects},
+		}
+		for i, operation := range operations {
+			results[i] = txService.applyOperation(caller, i, operation)
+			if results[i].Err != nil {
+				failed = i
+				return results[i].Err
+			}
+		}
+		return nil
+	})
+	if txErr == nil {
+		for _, effect := range effects {
+			effect()
+		}
+		return results
+	}
+
+	if failed < 0 {
+		/// Every operation succeeded but the commit didn't
+		for i := range results {
+			results[i].User, results[i].Err = nil, txErr
+		}
+		return results
+	}
+	return failDependents(results, failed, "rolled back")
+}
+
+/// failDependents fails the operations of an atomic batch on the one at
+/// failed, which keeps its own error. Those after it weren't attempted,
+/// outcome tells what became of those before it.
+func failDependents(results []users.BatchOperationResult, failed int, outcome string) []users.BatchOperationResult {
+	for i := range results {
+		results[i].User = nil
+		switch {
+		case i < failed:
+			results[i].Err = error_utils.NewFailedDependencyError(fmt.Sprintf("%s, operation %d failed", outcome, failed))
+		case i > failed:
+			results[i].Err = error_utils.NewFailedDependencyError(fmt.Sprintf("not attempted, operation %d failed", failed))
+		}
+	}
+	return results
+}
+
+/// passwordKey identifies a password set by an atomic batch, userId is 0
+/// for the users the batch creates
+type passwordKey struct {
+	userId   int64
+	password string
+}
+
+/// hashPasswords hashes the passwords set by operations ahead of their
+/// transaction, which would otherwise hold its locks through bcrypt.
+/// Updated passwords are checked against the user's history like single
+/// updates. It returns the hashes of each password in operation order,
+/// or the index of the operation that failed.
+func (us *userService) hashPasswords(operations []users.BatchOperation) (map[passwordKey][]string, int, rest_error.RestErr) {
+	hashes := make(map[passwordKey][]string)
+	for i, operation := range operations {
+		if operation.User == nil || operation.User.Password == "" {
+			continue
+		}
+		key := passwordKey{password: operation.User.Password}
+		var hash string
+		var err rest_error.RestErr
+		switch operation.Op {
+		case users.BatchOpCreate:
+			hash, err = us.passwordService.HashPassword(key.password)
+		case users.BatchOpUpdate:
+			key.userId = operation.UserId
+			var user *users.User
+			if user, err = us.userDao.Get(key.userId); err == nil {
+				hash, err = us.passwordService.HashNewPassword(key.userId, key.password, user.Password)
+			}
+		default:
+			continue
+		}
+		if err != nil {
+			return nil, i, err
+		}
+		hashes[key] = append(hashes[key], hash)
+	}
+	return hashes, -1, nil
+}
+
+func (us *userService) applyOperation(caller Caller, index int, operation users.BatchOperation) users.BatchOperationResult {
+	result := users.BatchOperationResult{Index: index, Op: operation.Op}
+	switch operation.Op {
+	case users.BatchOpCreate:
+		result.User, result.Err = us.CreateUser(*operation.User)
+	case users.BatchOpUpdate:
+		user := *operation.User
+		user.Id = operation.UserId
+		result.User, result.Err = us.UpdateUser(false, user)
+	case users.BatchOpStatus:
+		result.User, result.Err = us.ChangeStatus(caller, users.StatusChange{
+			UserId: operation.UserId, ToStatus: operation.Status, Reason: operation.Reason,
+		})
+	case users.BatchOpDelete:
+		result.Err = us.DeleteUser(caller, operation.UserId)
+	}
+	return result
+}
+
+/// deferredPasswordService serves the passwords of an atomic batch from
+/// the hashes made before its transaction, and holds back their history
+/// writes until it commits
+type deferredPasswordService struct {
+	IPasswordService
+	hashes  map[passwordKey][]string
+	effects *[]func()
+}
+
+func (dps deferredPasswordService) HashPassword(password string) (string, rest_error.RestErr) {
+	return dps.hashed(passwordKey{password: password})
+}
+
+func (dps deferredPasswordService) HashNewPassword(userId int64, password string, currentHash string) (string, rest_error.RestErr) {
+	return dps.hashed(passwordKey{userId: userId, password: password})
+}
+
+func (dps deferredPasswordService) hashed(key passwordKey) (string, rest_error.RestErr) {
+	hashes := dps.hashes[key]
+	if len(hashes) == 0 {
+		return "", rest_error.NewInternalServerError("password wasn't hashed ahead of the batch")
+	}
+	dps.hashes[key] = hashes[1:]
+	return hashes[0], nil
+}
+
+func (dps deferredPasswordService) RecordPassword(userId int64, passwordHash string) {
+	*dps.effects = append(*dps.effects, func() {
+		dps.IPasswordService.RecordPassword(userId, passwordHash)
+	})
+}
+
+/// deferredChangeFeed holds back the changes published by an atomic
+/// batch until its transaction commits
+type deferredChangeFeed struct {
+	IUserChangeFeed
+	effects *[]func()
+}
+
+func (dcf deferredChangeFeed) Publish(eventType string, user users.User) {
+	*dcf.effects = append(*dcf.effects, func() {
+		dcf.IUserChangeFeed.Publish(eventType, user)
+	})
+}
+
+func (dcf deferredChangeFeed) PublishUpdate(oldStatus string, user users.User) {
+	*dcf.effects = append(*dcf.effects, func() {
+		dcf.IUserChangeFeed.PublishUpdate(oldStatus, user)
+	})
+}
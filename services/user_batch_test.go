@@ -0,0 +1,150 @@
+package services
+
+import (
+	"github.com/Abacode7/bookstore_users-api/domain/users"
+	"github.com/Abacode7/bookstore_utils-go/v2/rest_error"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+/// batchUserDao runs transactions on a copy of its users, which replaces
+/// them if the transaction succeeds
+type batchUserDao struct {
+	*statusUserDao
+	transactions int
+}
+
+func (bd *batchUserDao) Save(user users.User) (*users.User, rest_error.RestErr) {
+	user.Id = int64(100 + len(bd.users))
+	bd.users[user.Id] = user
+	return &user, nil
+}
+
+func (bd *batchUserDao) Update(user users.User) (*users.User, rest_error.RestErr) {
+	bd.users[user.Id] = user
+	return &user, nil
+}
+
+func (bd *batchUserDao) Transaction(fn func(users.IUserDao) rest_error.RestErr) rest_error.RestErr {
+	bd.transactions++
+	tx := &statusUserDao{users: make(map[int64]users.User)}
+	for id, user := range bd.users {
+		tx.users[id] = user
+	}
+	if err := fn(&batchUserDao{statusUserDao: tx}); err != nil {
+		return err
+	}
+	bd.users = tx.users
+	return nil
+}
+
+/// batchPasswordService hashes passwords without bcrypt and records the
+/// hashes written to the history
+type batchPasswordService struct {
+	hashed   []string
+	recorded []string
+}
+
+func (bs *batchPasswordService) HashPassword(password string) (string, rest_error.RestErr) {
+	bs.hashed = append(bs.hashed, password)
+	return "hash:" + password, nil
+}
+
+func (bs *batchPasswordService) HashNewPassword(userId int64, password string, currentHash string) (string, rest_error.RestErr) {
+	if currentHash == "hash:"+password {
+		return "", rest_error.NewBadRequestError("password has been used recently")
+	}
+	return bs.HashPassword(password)
+}
+
+func (bs *batchPasswordService) RecordPassword(userId int64, passwordHash string) {
+	bs.recorded = append(bs.recorded, passwordHash)
+}
+
+func newBatchUserService() (IUserService, *batchUserDao, *batchPasswordService) {
+	dao := &batchUserDao{statusUserDao: &statusUserDao{users: map[int64]users.User{
+		7: {Id: 7, FirstName: "Ann", Email: "ann@mail.com", Status: users.StatusActive, Password: "hash:old"},
+	}}}
+	passwords := &batchPasswordService{}
+	return NewUserService(dao, passwords, nil, NewUserChangeFeed(10)), dao, passwords
+}
+
+func TestApplyAtomicBatchRollsBackOnFailure(t *testing.T) {
+	service, dao, passwords := newBatchUserService()
+	results, err := service.ApplyBatch(Caller{Privileged: true}, users.BatchRequest{Atomic: true, Operations: []users.BatchOperation{
+		{Op: users.BatchOpCreate, User: &users.User{Email: "bob@mail.com", Password: "secret"}},
+		{Op: users.BatchOpStatus, UserId: 7, Status: users.StatusSuspended, Reason: "chargeback"},
+		{Op: users.BatchOpStatus, UserId: 7, Status: users.StatusPending},
+		{Op: users.BatchOpDelete, UserId: 7},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(dao.users) != 1 || dao.users[7].Status != users.StatusActive {
+		t.Errorf("got users %v, want the batch rolled back", dao.users)
+	}
+	if len(passwords.hashed) != 1 || len(passwords.recorded) != 0 {
+		t.Errorf("got %d passwords hashed and %d recorded, want 1 hashed ahead and none recorded",
+			len(passwords.hashed), len(passwords.recorded))
+	}
+	tests := []struct {
+		status  int
+		message string
+	}{
+		{http.StatusFailedDependency, "rolled back, operation 2 failed"},
+		{http.StatusFailedDependency, "rolled back, operation 2 failed"},
+		{http.StatusConflict, "a suspended user can't become pending"},
+		{http.StatusFailedDependency, "not attempted, operation 2 failed"},
+	}
+	for i, test := range tests {
+		result := results[i]
+		if result.Err == nil || result.Err.Status() != test.status || result.Err.Message() != test.message {
+			t.Errorf("operation %d: got %v, want %d %q", i, result.Err, test.status, test.message)
+		}
+		if result.User != nil {
+			t.Errorf("operation %d: got user %+v, want none", i, result.User)
+		}
+	}
+}
+
+func TestApplyAtomicBatchHashesPasswordsBeforeItsTransaction(t *testing.T) {
+	service, dao, passwords := newBatchUserService()
+	results, err := service.ApplyBatch(Caller{Privileged: true}, users.BatchRequest{Atomic: true, Operations: []users.BatchOperation{
+		{Op: users.BatchOpCreate, User: &users.User{Email: "bob@mail.com", Password: "secret"}},
+		{Op: users.BatchOpUpdate, UserId: 7, User: &users.User{Password: "old"}},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dao.transactions != 0 {
+		t.Errorf("got %d transactions, want none for a password rejected ahead of the batch", dao.transactions)
+	}
+	if results[1].Err == nil || results[1].Err.Status() != http.StatusBadRequest {
+		t.Errorf("got %v, want the reused password rejected", results[1].Err)
+	}
+	if results[0].Err == nil || !strings.HasPrefix(results[0].Err.Message(), "not attempted") {
+		t.Errorf("got %v, want the create not attempted", results[0].Err)
+	}
+
+	results, err = service.ApplyBatch(Caller{Privileged: true}, users.BatchRequest{Atomic: true, Operations: []users.BatchOperation{
+		{Op: users.BatchOpCreate, User: &users.User{Email: "bob@mail.com", Password: "secret"}},
+		{Op: users.BatchOpCreate, User: &users.User{Email: "eve@mail.com", Password: "secret"}},
+		{Op: users.BatchOpUpdate, UserId: 7, User: &users.User{Password: "new"}},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, result := range results {
+		if result.Err != nil {
+			t.Fatalf("operation %d: got %v", result.Index, result.Err)
+		}
+	}
+	if got := dao.users[7].Password; got != "hash:new" {
+		t.Errorf("got password %q, want the hash made ahead of the batch", got)
+	}
+	if len(passwords.recorded) != 3 {
+		t.Errorf("got %d passwords recorded after the commit, want 3", len(passwords.recorded))
+	}
+}
@@ -0,0 +1,157 @@
+package services
+
+import (
+	"fmt"
+	"github.com/Abacode7/bookstore_users-api/domain/users"
+	"github.com/Abacode7/bookstore_users-api/utils/date_utils"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+/// subscriberBuffer is how many changes a subscriber may fall behind
+/// before it is dropped, it then resumes from the log on reconnect
+const subscriberBuffer = 64
+
+/// IUserChangeFeed broadcasts the changes services make to users and
+/// keeps the latest of them for subscribers resuming after a disconnect
+type IUserChangeFeed interface {
+	Publish(eventType string, user users.User)
+	PublishUpdate(oldStatus string, user users.User)
+	Subscribe(lastEventId string) (*UserChangeSubscription, func())
+}
+
+/// UserChangeSubscription is what a subscriber resuming after
+/// lastEventId receives
+type UserChangeSubscription struct {
+	/// Epoch identifies the feed the changes' ids were issued by
+	Epoch string
+	/// Backlog are the logged changes after lastEventId
+	Backlog []users.UserChange
+	/// Resync is set when changes after lastEventId are no longer logged,
+	/// or it was issued by another instance or before a restart, the
+	/// subscriber has to reload its state
+	Resync bool
+	/// Changes streams live changes, it is closed if the subscriber
+	/// falls behind
+	Changes <-chan users.UserChange
+}
+
+/// userChangeFeed numbers changes per process, event ids carry the epoch
+/// of the feed so ids of another replica or run are never resumed from
+type userChangeFeed struct {
+	epoch       string
+	mu          sync.Mutex
+	log         []users.UserChange
+	logSize     int
+	lastId      int64
+	subscribers map[chan users.UserChange]struct{}
+}
+
+/// NewUserChangeFeed is userChangeFeed's constructor, it logs the latest
+/// logSize changes
+func NewUserChangeFeed(logSize int) IUserChangeFeed {
+	return &userChangeFeed{
+		epoch:       strconv.FormatInt(date_utils.GetTime().UnixNano(), 36),
+		log:         make([]users.UserChange, 0, logSize),
+		logSize:     logSize,
+		subscribers: make(map[chan users.UserChange]struct{}),
+	}
+}
+
+/// Publish logs and broadcasts an eventType change of user
+func (ucf *userChangeFeed) Publish(eventType string, user users.User) {
+	change := users.UserChange{
+		Type:        eventType,
+		UserId:      user.Id,
+		Status:      user.Status,
+		DateCreated: date_utils.GetDbFormattedTime(),
+	}
+	if eventType != users.EventUserDeleted {
+		change.User, _ = user.Marshall(false)
+	}
+
+	ucf.mu.Lock()
+	defer ucf.mu.Unlock()
+	ucf.lastId++
+	change.Id = ucf.lastId
+	if len(ucf.log) == ucf.logSize && ucf.logSize > 0 {
+		copy(ucf.log, ucf.log[1:])
+		ucf.log = ucf.log[:len(ucf.log)-1]
+	}
+	if ucf.logSize > 0 {
+		ucf.log = append(ucf.log, change)
+	}
+	for subscriber := range ucf.subscribers {
+		select {
+		case subscriber <- change:
+		default:
+			delete(ucf.subscribers, subscriber)
+			close(subscriber)
+		}
+	}
+}
+
+/// PublishUpdate publishes an update of user, and its deactivation or
+/// deletion if its status was oldStatus before
+func (ucf *userChangeFeed) PublishUpdate(oldStatus string, user users.User) {
+	ucf.Publish(users.EventUserUpdated, user)
+	if user.Status == users.StatusDeactivated && oldStatus != users.StatusDeactivated {
+		ucf.Publish(users.EventUserDeactivated, user)
+	}
+	if user.Status == users.StatusDeleted && oldStatus != users.StatusDeleted {
+		ucf.Publish(users.EventUserDeleted, users.User{Id: user.Id})
+	}
+}
+
+/// Subscribe registers a subscriber resuming after lastEventId, empty for
+/// a new subscriber. The returned func unsubscribes.
+func (ucf *userChangeFeed) Subscribe(lastEventId string) (*UserChangeSubscription, func()) {
+	subscriber := make(chan users.UserChange, subscriberBuffer)
+
+	ucf.mu.Lock()
+	defer ucf.mu.Unlock()
+	subscription := &UserChangeSubscription{Epoch: ucf.epoch, Changes: subscriber}
+	if lastEventId != "" {
+		lastChangeId, ok := ucf.parseEventId(lastEventId)
+		oldestId := ucf.lastId + 1
+		if len(ucf.log) > 0 {
+			oldestId = ucf.log[0].Id
+		}
+		subscription.Resync = !ok || lastChangeId > ucf.lastId || lastChangeId < oldestId-1
+		for _, change := range ucf.log {
+			if subscription.Resync || change.Id > lastChangeId {
+				subscription.Backlog = append(subscription.Backlog, change)
+			}
+		}
+	}
+	ucf.subscribers[subscriber] = struct{}{}
+
+	unsubscribe := func() {
+		ucf.mu.Lock()
+		defer ucf.mu.Unlock()
+		if _, ok := ucf.subscribers[subscriber]; ok {
+			delete(ucf.subscribers, subscriber)
+			close(subscriber)
+		}
+	}
+	return subscription, unsubscribe
+}
+
+/// EventId is the id subscribers resume after change with
+func (subscription *UserChangeSubscription) EventId(change users.UserChange) string {
+	return fmt.Sprintf("%s-%d", subscription.Epoch, change.Id)
+}
+
+/// parseEventId gets the change id of an event id issued by this feed
+func (ucf *userChangeFeed) parseEventId(eventId string) (int64, bool) {
+	separator := strings.LastIndex(eventId, "-")
+	if separator < 0 || eventId[:separator] != ucf.epoch {
+		return 0, false
+	}
+	changeId, err := strconv.ParseInt(eventId[separator+1:], 10, 64)
+	if err != nil || changeId < 0 {
+		return 0, false
+	}
+	return changeId, true
+}
@@ -0,0 +1,59 @@
+package services
+
+import (
+	"github.com/Abacode7/bookstore_users-api/domain/users"
+	"strings"
+	"testing"
+)
+
+func publishChanges(feed IUserChangeFeed, count int) {
+	for i := 1; i <= count; i++ {
+		feed.Publish(users.EventUserUpdated, users.User{Id: int64(i), Status: users.StatusActive})
+	}
+}
+
+func TestUserChangeFeedResumesAfterItsOwnEventIds(t *testing.T) {
+	feed := NewUserChangeFeed(10)
+	publishChanges(feed, 3)
+	first, unsubscribe := feed.Subscribe("")
+	unsubscribe()
+
+	subscription, unsubscribe := feed.Subscribe(first.Epoch + "-1")
+	defer unsubscribe()
+	if subscription.Resync || len(subscription.Backlog) != 2 || subscription.Backlog[0].Id != 2 {
+		t.Errorf("subscription = %+v, want changes 2 and 3", subscription)
+	}
+	if id := subscription.EventId(subscription.Backlog[0]); id != first.Epoch+"-2" {
+		t.Errorf("event id = %q, want the epoch and change id", id)
+	}
+}
+
+func TestUserChangeFeedResyncsEventIdsOfAnotherInstance(t *testing.T) {
+	feed := NewUserChangeFeed(10)
+	other := NewUserChangeFeed(10)
+	publishChanges(feed, 3)
+	publishChanges(other, 3)
+	otherSubscription, unsubscribe := other.Subscribe("")
+	unsubscribe()
+
+	for _, lastEventId := range []string{otherSubscription.Epoch + "-1", "1", "garbage", strings.Repeat("-", 3)} {
+		subscription, unsubscribe := feed.Subscribe(lastEventId)
+		unsubscribe()
+		if !subscription.Resync || len(subscription.Backlog) != 3 {
+			t.Errorf("subscription after %q = %+v, want a resync with the whole log", lastEventId, subscription)
+		}
+	}
+}
+
+func TestUserChangeFeedResyncsPurgedChanges(t *testing.T) {
+	feed := NewUserChangeFeed(2)
+	publishChanges(feed, 5)
+	first, unsubscribe := feed.Subscribe("")
+	unsubscribe()
+
+	subscription, unsubscribe := feed.Subscribe(first.Epoch + "-1")
+	defer unsubscribe()
+	if !subscription.Resync {
+		t.Errorf("subscription = %+v, want a resync once changes are no longer logged", subscription)
+	}
+}
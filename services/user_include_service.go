@@ -0,0 +1,85 @@
+package services
+
+import (
+	"github.com/Abacode7/bookstore_users-api/domain/addresses"
+	"github.com/Abacode7/bookstore_users-api/domain/organizations"
+	"github.com/Abacode7/bookstore_users-api/domain/users"
+	"github.com/Abacode7/bookstore_utils-go/v2/rest_error"
+)
+
+type IUserIncludeService interface {
+	LoadIncludes(Caller, int64, []string) (map[string]interface{}, rest_error.RestErr)
+	LoadUsersIncludes(Caller, []int64, []string) (map[int64]map[string]interface{}, rest_error.RestErr)
+}
+
+type userIncludeService struct {
+	addressService      IAddressService
+	organizationService IOrganizationService
+}
+
+/// NewUserIncludeService is userIncludeService's constructor
+func NewUserIncludeService(addressService IAddressService, organizationService IOrganizationService) IUserIncludeService {
+	return &userIncludeService{
+		addressService:      addressService,
+		organizationService: organizationService,
+	}
+}
+
+/// LoadIncludes loads the related resources of the user named by
+/// includes, keyed by include
+func (uis *userIncludeService) LoadIncludes(caller Caller, userId int64, includes []string) (map[string]interface{}, rest_error.RestErr) {
+	embedded, err := uis.LoadUsersIncludes(caller, []int64{userId}, includes)
+	if err != nil {
+		return nil, err
+	}
+	return embedded[userId], nil
+}
+
+/// LoadUsersIncludes is LoadIncludes for several users, keyed by user
+/// id. Each include is loaded with one read for all the users.
+func (uis *userIncludeService) LoadUsersIncludes(caller Caller, userIds []int64, includes []string) (map[int64]map[string]interface{}, rest_error.RestErr) {
+	embedded := make(map[int64]map[string]interface{}, len(userIds))
+	for _, userId := range userIds {
+		embedded[userId] = make(map[string]interface{}, len(includes))
+	}
+	/// Organizations and roles both come from the memberships
+	var memberships map[int64]organizations.Memberships
+	for _, include := range includes {
+		switch include {
+		case users.IncludeAddresses:
+			userAddresses, err := uis.addressService.ListUsersAddresses(userIds)
+			if err != nil {
+				return nil, err
+			}
+			for _, userId := range userIds {
+				if userAddresses[userId] == nil {
+					userAddresses[userId] = addresses.Addresses{}
+				}
+				embedded[userId][include] = userAddresses[userId]
+			}
+		case users.IncludeDefaultAddresses:
+			defaultAddresses, err := uis.addressService.GetUsersDefaultAddresses(userIds)
+			if err != nil {
+				return nil, err
+			}
+			for _, userId := range userIds {
+				embedded[userId][include] = defaultAddresses[userId]
+			}
+		case users.IncludeOrganizations, users.IncludeRoles:
+			if memberships == nil {
+				var err rest_error.RestErr
+				if memberships, err = uis.organizationService.ListUsersOrganizations(caller, userIds); err != nil {
+					return nil, err
+				}
+			}
+			for _, userId := range userIds {
+				if include == users.IncludeOrganizations {
+					embedded[userId][include] = memberships[userId].Organizations()
+				} else {
+					embedded[userId][include] = memberships[userId].Roles()
+				}
+			}
+		}
+	}
+	return embedded, nil
+}
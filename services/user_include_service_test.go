@@ -0,0 +1,96 @@
+package services
+
+import (
+	"github.com/Abacode7/bookstore_users-api/domain/addresses"
+	"github.com/Abacode7/bookstore_users-api/domain/organizations"
+	"github.com/Abacode7/bookstore_users-api/domain/users"
+	"github.com/Abacode7/bookstore_users-api/utils/error_utils"
+	"github.com/Abacode7/bookstore_utils-go/v2/rest_error"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+/// countingAddressService serves the addresses of user 1 and counts the
+/// reads
+type countingAddressService struct {
+	IAddressService
+	reads int
+}
+
+func (cs *countingAddressService) ListUsersAddresses(userIds []int64) (map[int64]addresses.Addresses, rest_error.RestErr) {
+	cs.reads++
+	return map[int64]addresses.Addresses{
+		1: {{Id: 10, UserId: 1, DefaultShipping: true}, {Id: 11, UserId: 1, DefaultBilling: true}},
+	}, nil
+}
+
+func (cs *countingAddressService) GetUsersDefaultAddresses(userIds []int64) (map[int64]*addresses.DefaultAddresses, rest_error.RestErr) {
+	cs.reads++
+	result := make(map[int64]*addresses.DefaultAddresses, len(userIds))
+	for _, userId := range userIds {
+		result[userId] = &addresses.DefaultAddresses{}
+	}
+	return result, nil
+}
+
+/// countingOrganizationService makes user 2 an owner of organization 5
+/// and counts the reads
+type countingOrganizationService struct {
+	IOrganizationService
+	reads int
+}
+
+func (cs *countingOrganizationService) ListUsersOrganizations(caller Caller, userIds []int64) (map[int64]organizations.Memberships, rest_error.RestErr) {
+	cs.reads++
+	for _, userId := range userIds {
+		if !caller.Privileged && caller.UserId != userId {
+			return nil, error_utils.NewForbiddenError("not allowed to list this user's organizations")
+		}
+	}
+	return map[int64]organizations.Memberships{
+		2: {{Organization: organizations.Organization{Id: 5, Name: "Acme"}, Role: "owner"}},
+	}, nil
+}
+
+func TestLoadUsersIncludesReadsOncePerInclude(t *testing.T) {
+	addressService := &countingAddressService{}
+	organizationService := &countingOrganizationService{}
+	service := NewUserIncludeService(addressService, organizationService)
+
+	embedded, err := service.LoadUsersIncludes(Caller{Privileged: true}, []int64{1, 2, 3}, users.Includes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if addressService.reads != 2 || organizationService.reads != 1 {
+		t.Errorf("got %d address and %d organization reads, want 2 and 1", addressService.reads, organizationService.reads)
+	}
+	if got := embedded[1][users.IncludeAddresses].(addresses.Addresses); len(got) != 2 {
+		t.Errorf("got user 1 addresses %v, want 2", got)
+	}
+	if got := embedded[3][users.IncludeAddresses].(addresses.Addresses); got == nil || len(got) != 0 {
+		t.Errorf("got user 3 addresses %#v, want an empty list", got)
+	}
+	wantRoles := []organizations.OrganizationRole{{OrganizationId: 5, Role: "owner"}}
+	if got := embedded[2][users.IncludeRoles]; !reflect.DeepEqual(got, wantRoles) {
+		t.Errorf("got user 2 roles %v, want %v", got, wantRoles)
+	}
+	if got := embedded[1][users.IncludeOrganizations].([]organizations.Organization); len(got) != 0 {
+		t.Errorf("got user 1 organizations %v, want none", got)
+	}
+}
+
+func TestLoadUsersIncludesChecksEveryUser(t *testing.T) {
+	service := NewUserIncludeService(&countingAddressService{}, &countingOrganizationService{})
+	_, err := service.LoadUsersIncludes(Caller{UserId: 1}, []int64{1, 2}, []string{users.IncludeOrganizations})
+	if err == nil || err.Status() != http.StatusForbidden {
+		t.Errorf("got %v, want forbidden", err)
+	}
+	embedded, err := service.LoadIncludes(Caller{UserId: 2}, 2, []string{users.IncludeOrganizations})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := embedded[users.IncludeOrganizations].([]organizations.Organization); len(got) != 1 || got[0].Name != "Acme" {
+		t.Errorf("got organizations %v, want Acme", got)
+	}
+}
@@ -0,0 +1,235 @@
+package services
+
+import (
+	"fmt"
+	"github.com/Abacode7/bookstore_users-api/domain/users"
+	"github.com/Abacode7/bookstore_users-api/notifiers"
+	"github.com/Abacode7/bookstore_users-api/utils/crypto_utils"
+	"github.com/Abacode7/bookstore_users-api/utils/date_utils"
+	"github.com/Abacode7/bookstore_users-api/utils/error_utils"
+	"github.com/Abacode7/bookstore_utils-go/v2/logger"
+	"github.com/Abacode7/bookstore_utils-go/v2/rest_error"
+	"strings"
+	"time"
+)
+
+/// userInvitationTTL is how long an invited user has to accept
+const userInvitationTTL = 7 * 24 * time.Hour
+
+type IUserInvitationService interface {
+	InviteUser(Caller, users.UserInvitationRequest) (*users.UserInvitation, rest_error.RestErr)
+	AcceptInvitation(users.AcceptInvitationRequest) (*users.User, rest_error.RestErr)
+	RevokeInvitation(Caller, int64) rest_error.RestErr
+	ResendInvitation(Caller, int64) (*users.UserInvitation, rest_error.RestErr)
+}
+
+type userInvitationService struct {
+	invitationDao   users.IUserInvitationDao
+	userDao         users.IUserDao
+	passwordService IPasswordService
+	notifier        notifiers.INotifier
+	changeFeed      IUserChangeFeed
+}
+
+/// NewUserInvitationService is userInvitationService's constructor
+func NewUserInvitationService(invitationDao users.IUserInvitationDao, userDao users.IUserDao,
+	passwordService IPasswordService, notifier notifiers.INotifier, changeFeed IUserChangeFeed) IUserInvitationService {
+	return &userInvitationService{
+		invitationDao:   invitationDao,
+		userDao:         userDao,
+		passwordService: passwordService,
+		notifier:        notifier,
+		changeFeed:      changeFeed,
+	}
+}
+
+/// InviteUser provisions an account without a password for the invitee
+/// and sends them a token to set it up with. The account is removed
+/// again if its invitation can't be saved, so it doesn't hold the email.
+func (uis *userInvitationService) InviteUser(caller Caller, request users.UserInvitationRequest) (*users.UserInvitation, rest_error.RestErr) {
+	if !caller.Privileged {
+		return nil, error_utils.NewForbiddenError("only admins can invite users")
+	}
+	if err := request.Validate(); err != nil {
+		return nil, err
+	}
+	user, err := uis.userDao.Save(users.User{
+		FirstName:   strings.TrimSpace(request.FirstName),
+		LastName:    strings.TrimSpace(request.LastName),
+		Email:       request.Email,
+		DateCreated: date_utils.GetDbFormattedTime(),
+		Status:      users.StatusPending,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	invitation := users.UserInvitation{
+		UserId:      user.Id,
+		InvitedBy:   caller.UserId,
+		Status:      users.InvitationPending,
+		DateCreated: date_utils.GetDbFormattedTime(),
+	}
+	token, err := renewInvitationToken(&invitation)
+	if err != nil {
+		uis.removeInvitee(user.Id)
+		return nil, err
+	}
+	savedInvitation, err := uis.invitationDao.Save(invitation)
+	if err != nil {
+		uis.removeInvitee(user.Id)
+		return nil, err
+	}
+	uis.changeFeed.Publish(users.EventUserCreated, *user)
+	if err := uis.sendInvitation(user.Email, token); err != nil {
+		return nil, err
+	}
+	return savedInvitation, nil
+}
+
+/// AcceptInvitation sets the invited user's password, and names if
+/// given, and activates their account
+func (uis *userInvitationService) AcceptInvitation(request users.AcceptInvitationRequest) (*users.User, rest_error.RestErr) {
+	if err := request.Validate(); err != nil {
+		return nil, err
+	}
+	invitation, err := uis.invitationDao.FindByToken(crypto_utils.GetSha256(request.Token))
+	if err != nil {
+		return nil, err
+	}
+	if invitation.Status != users.InvitationPending || isExpired(invitation.ExpiresAt) {
+		return nil, rest_error.NewBadRequestError("invitation is no longer valid")
+	}
+	user, err := uis.userDao.Get(invitation.UserId)
+	if err != nil {
+		return nil, err
+	}
+	if user.Status != users.StatusPending {
+		return nil, error_utils.NewConflictError("account has already been set up")
+	}
+
+	user.Password, err = uis.passwordService.HashNewPassword(user.Id, request.Password, "")
+	if err != nil {
+		return nil, err
+	}
+	if firstName := strings.TrimSpace(request.FirstName); firstName != "" {
+		user.FirstName = firstName
+	}
+	if lastName := strings.TrimSpace(request.LastName); lastName != "" {
+		user.LastName = lastName
+	}
+	oldStatus := user.Status
+	user.Status = users.StatusActive
+	updatedUser, err := uis.userDao.Update(*user)
+	if err != nil {
+		return nil, err
+	}
+	uis.changeFeed.PublishUpdate(oldStatus, *updatedUser)
+	uis.passwordService.RecordPassword(updatedUser.Id, updatedUser.Password)
+
+	invitation.Status = users.InvitationAccepted
+	if err := uis.invitationDao.Update(*invitation); err != nil {
+		return nil, err
+	}
+	return updatedUser, nil
+}
+
+/// RevokeInvitation invalidates a pending invitation and removes the
+/// account provisioned for it
+func (uis *userInvitationService) RevokeInvitation(caller Caller, invitationId int64) rest_error.RestErr {
+	if !caller.Privileged {
+		return error_utils.NewForbiddenError("only admins can revoke invitations")
+	}
+	invitation, err := uis.pendingInvitation(invitationId)
+	if err != nil {
+		return err
+	}
+	invitation.Status = users.InvitationRevoked
+	if err := uis.invitationDao.Update(*invitation); err != nil {
+		return err
+	}
+	user, err := uis.userDao.Get(invitation.UserId)
+	if err != nil {
+		return err
+	}
+	if user.Status != users.StatusPending {
+		return nil
+	}
+	if err := uis.userDao.Delete(user.Id); err != nil {
+		return err
+	}
+	uis.changeFeed.Publish(users.EventUserDeleted, users.User{Id: user.Id})
+	return nil
+}
+
+/// ResendInvitation sends a pending invitation again with a new token,
+/// invalidating the previous one, and restarts its expiry
+func (uis *userInvitationService) ResendInvitation(caller Caller, invitationId int64) (*users.UserInvitation, rest_error.RestErr) {
+	if !caller.Privileged {
+		return nil, error_utils.NewForbiddenError("only admins can resend invitations")
+	}
+	invitation, err := uis.pendingInvitation(invitationId)
+	if err != nil {
+		return nil, err
+	}
+	user, err := uis.userDao.Get(invitation.UserId)
+	if err != nil {
+		return nil, err
+	}
+	token, err := renewInvitationToken(invitation)
+	if err != nil {
+		return nil, err
+	}
+	if err := uis.invitationDao.Update(*invitation); err != nil {
+		return nil, err
+	}
+	if err := uis.sendInvitation(user.Email, token); err != nil {
+		return nil, err
+	}
+	return invitation, nil
+}
+
+/// removeInvitee removes the pending user of an invitation that failed
+/// to be created
+func (uis *userInvitationService) removeInvitee(userId int64) {
+	if err := uis.userDao.Delete(userId); err != nil {
+		logger.Error(fmt.Sprintf("error removing invited user %d", userId), err)
+	}
+}
+
+func (uis *userInvitationService) pendingInvitation(invitationId int64) (*users.UserInvitation, rest_error.RestErr) {
+	invitation, err := uis.invitationDao.Get(invitationId)
+	if err != nil {
+		return nil, err
+	}
+	if invitation.Status != users.InvitationPending {
+		return nil, error_utils.NewConflictError("invitation is no longer pending")
+	}
+	return invitation, nil
+}
+
+func (uis *userInvitationService) sendInvitation(email string, token string) rest_error.RestErr {
+	err := uis.notifier.Notify(notifiers.Message{
+		To:      email,
+		Subject: "You have been invited to the bookstore",
+		Body:    fmt.Sprintf("Use this token to set up your account within %s: %s", userInvitationTTL, token),
+	})
+	if err != nil {
+		logger.Error("error sending user invitation", err)
+		return rest_error.NewInternalServerError("error sending invitation")
+	}
+	return nil
+}
+
+/// renewInvitationToken gives the invitation a new token and expiry and
+/// returns the token, which is only stored hashed
+func renewInvitationToken(invitation *users.UserInvitation) (string, rest_error.RestErr) {
+	token, err := crypto_utils.GenerateToken()
+	if err != nil {
+		logger.Error("error generating invitation token", err)
+		return "", rest_error.NewInternalServerError("error creating invitation")
+	}
+	invitation.TokenHash = crypto_utils.GetSha256(token)
+	invitation.ExpiresAt = date_utils.FormatDbTime(date_utils.GetTime().Add(userInvitationTTL))
+	return token, nil
+}
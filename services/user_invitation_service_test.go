@@ -0,0 +1,52 @@
+package services
+
+import (
+	"github.com/Abacode7/bookstore_users-api/domain/users"
+	"github.com/Abacode7/bookstore_utils-go/v2/rest_error"
+	"testing"
+)
+
+/// inviteeUserDao saves users in memory and removes them
+type inviteeUserDao struct {
+	users.IUserDao
+	users map[int64]users.User
+}
+
+func (id *inviteeUserDao) Save(user users.User) (*users.User, rest_error.RestErr) {
+	user.Id = int64(len(id.users) + 1)
+	id.users[user.Id] = user
+	return &user, nil
+}
+
+func (id *inviteeUserDao) Delete(userId int64) rest_error.RestErr {
+	delete(id.users, userId)
+	return nil
+}
+
+/// failingInvitationDao fails to save invitations
+type failingInvitationDao struct {
+	users.IUserInvitationDao
+}
+
+func (fd *failingInvitationDao) Save(users.UserInvitation) (*users.UserInvitation, rest_error.RestErr) {
+	return nil, rest_error.NewInternalServerError("database error")
+}
+
+func TestInviteUserRemovesTheInviteeWhenTheInvitationFails(t *testing.T) {
+	userDao := &inviteeUserDao{users: map[int64]users.User{}}
+	feed := NewUserChangeFeed(10)
+	service := NewUserInvitationService(&failingInvitationDao{}, userDao, nil, nil, feed)
+	subscription, unsubscribe := feed.Subscribe("")
+	defer unsubscribe()
+
+	_, err := service.InviteUser(Caller{Privileged: true}, users.UserInvitationRequest{Email: "ann@mail.com"})
+	if err == nil {
+		t.Fatal("got an invitation, want the dao's error")
+	}
+	if len(userDao.users) != 0 {
+		t.Errorf("got users %v left holding their email, want none", userDao.users)
+	}
+	if len(subscription.Changes) != 0 {
+		t.Errorf("got %d changes published for the removed invitee, want none", len(subscription.Changes))
+	}
+}
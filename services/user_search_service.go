@@ -0,0 +1,28 @@
+package services
+
+import (
+	"github.com/Abacode7/bookstore_users-api/domain/users"
+	"github.com/Abacode7/bookstore_utils-go/v2/rest_error"
+)
+
+type IUserSearchService interface {
+	SearchUsers(users.SearchQuery) (users.SearchResults, rest_error.RestErr)
+}
+
+type userSearchService struct {
+	searchIndex users.ISearchIndex
+}
+
+/// NewUserSearchService is userSearchService's constructor
+func NewUserSearchService(searchIndex users.ISearchIndex) IUserSearchService {
+	return &userSearchService{searchIndex: searchIndex}
+}
+
+/// SearchUsers finds users whose names or email match the words of the
+/// query exactly, by prefix or with a typo, most relevant first
+func (uss *userSearchService) SearchUsers(query users.SearchQuery) (users.SearchResults, rest_error.RestErr) {
+	if err := query.Validate(); err != nil {
+		return nil, err
+	}
+	return uss.searchIndex.Search(query)
+}
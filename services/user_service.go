@@ -4,38 +4,54 @@ import (
 	"github.com/Abacode7/bookstore_users-api/domain/users"
 	"github.com/Abacode7/bookstore_users-api/utils/crypto_utils"
 	"github.com/Abacode7/bookstore_users-api/utils/date_utils"
+	"github.com/Abacode7/bookstore_users-api/utils/error_utils"
 	"github.com/Abacode7/bookstore_utils-go/v2/logger"
 	"github.com/Abacode7/bookstore_utils-go/v2/rest_error"
+	"strings"
 )
 
 type IUserService interface {
 	CreateUser(users.User) (*users.User, rest_error.RestErr)
 	GetUser(int64) (*users.User, rest_error.RestErr)
+	GetUsers(users.BatchGetRequest) (*users.BatchGetResult, rest_error.RestErr)
 	SearchUser(string) (users.Users, rest_error.RestErr)
 	UpdateUser(bool, users.User) (*users.User, rest_error.RestErr)
-	DeleteUser(int64) rest_error.RestErr
+	PatchUser(int64, users.UserPatch) (*users.User, rest_error.RestErr)
+	DeleteUser(Caller, int64) rest_error.RestErr
 	LoginUser(users.UserLoginRequest) (*users.User, rest_error.RestErr)
+	ApplyBatch(Caller, users.BatchRequest) ([]users.BatchOperationResult, rest_error.RestErr)
+	ChangeStatus(Caller, users.StatusChange) (*users.User, rest_error.RestErr)
+	SuspendUser(Caller, int64, users.StatusChangeRequest) (*users.User, rest_error.RestErr)
+	ReactivateUser(Caller, int64, users.StatusChangeRequest) (*users.User, rest_error.RestErr)
+	GetStatusHistory(Caller, int64) ([]users.StatusChange, rest_error.RestErr)
 }
 
 type userService struct {
-	userDao users.IUserDao
+	userDao            users.IUserDao
+	passwordService    IPasswordService
+	emailChangeService IEmailChangeService
+	changeFeed         IUserChangeFeed
 }
 
 /// NewUserService is userService's constructor
-func NewUserService(userDao users.IUserDao) IUserService {
-	return &userService{userDao: userDao}
+func NewUserService(userDao users.IUserDao, passwordService IPasswordService, emailChangeService IEmailChangeService,
+	changeFeed IUserChangeFeed) IUserService {
+	return &userService{
+		userDao:            userDao,
+		passwordService:    passwordService,
+		emailChangeService: emailChangeService,
+		changeFeed:         changeFeed,
+	}
 }
 
 func (us *userService) CreateUser(user users.User) (*users.User, rest_error.RestErr) {
 	if err := user.Validate(); err != nil {
 		return nil, err
 	}
-	var err error
-	user.Password, err = crypto_utils.GetHash(user.Password)
+	var err rest_error.RestErr
+	user.Password, err = us.passwordService.HashPassword(user.Password)
 	if err != nil {
-		logger.Error("error generating password hash", err)
-		restErr := rest_error.NewBadRequestError("invalid user password")
-		return nil, restErr
+		return nil, err
 	}
 	user.DateCreated = date_utils.GetDbFormattedTime()
 	user.Status = users.StatusActive
@@ -44,42 +60,84 @@ func (us *userService) CreateUser(user users.User) (*users.User, rest_error.Rest
 	if daoErr != nil {
 		return nil, daoErr
 	}
+	us.passwordService.RecordPassword(newUser.Id, newUser.Password)
+	us.changeFeed.Publish(users.EventUserCreated, *newUser)
 	return newUser, nil
 }
 
+/// GetUser gets the user with userID. Deleted users are only kept as
+/// tombstones, they are gone for the api.
 func (us *userService) GetUser(userID int64) (*users.User, rest_error.RestErr) {
-	return us.userDao.Get(userID)
+	user, err := us.userDao.Get(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.Status == users.StatusDeleted {
+		return nil, error_utils.NewGoneError("user has been deleted")
+	}
+	return user, nil
+}
+
+/// GetUsers gets the requested users with a single read, in the order
+/// they were asked for. Repeated ids are only returned once, and deleted
+/// users are missing like users that never existed.
+func (us *userService) GetUsers(request users.BatchGetRequest) (*users.BatchGetResult, rest_error.RestErr) {
+	if err := request.Validate(); err != nil {
+		return nil, err
+	}
+	userIds := make([]int64, 0, len(request.Ids))
+	requested := make(map[int64]bool, len(request.Ids))
+	for _, userId := range request.Ids {
+		if !requested[userId] {
+			requested[userId] = true
+			userIds = append(userIds, userId)
+		}
+	}
+	found, err := us.userDao.GetMany(userIds)
+	if err != nil {
+		return nil, err
+	}
+	byId := make(map[int64]users.User, len(found))
+	for _, user := range found {
+		byId[user.Id] = user
+	}
+
+	result := &users.BatchGetResult{Users: make(users.Users, 0, len(found)), Missing: make([]int64, 0)}
+	for _, userId := range userIds {
+		if user, ok := byId[userId]; ok && user.Status != users.StatusDeleted {
+			result.Users = append(result.Users, user)
+		} else {
+			result.Missing = append(result.Missing, userId)
+		}
+	}
+	return result, nil
 }
 
 func (us *userService) SearchUser(status string) (users.Users, rest_error.RestErr) {
+	if err := users.ValidateStatus(status); err != nil {
+		return nil, err
+	}
 	return us.userDao.FindByStatus(status)
 }
 
 func (us *userService) UpdateUser(isTotalUpdate bool, user users.User) (*users.User, rest_error.RestErr) {
-	oldUser, getErr := us.userDao.Get(user.Id)
+	oldUser, getErr := us.GetUser(user.Id)
 	if getErr != nil {
 		return nil, getErr
 	}
-	// For fields email, password, status and date_created, if values
-	// aren't provided, they retain their old values.
-	if user.Password == "" {
-		user.Password = oldUser.Password
-	} else {
-		var err error
-		user.Password, err = crypto_utils.GetHash(user.Password)
-		if err != nil {
-			logger.Error("error generating password hash", err)
-			restErr := rest_error.NewBadRequestError("invalid user password")
-			return nil, restErr
-		}
+	// A total update replaces the user, so the email it can't do
+	// without has to be provided.
+	if isTotalUpdate && user.Email == "" {
+		return nil, rest_error.NewBadRequestError("invalid email address")
 	}
+	// For fields email, password and status, if values
+	// aren't provided, they retain their old values.
 	if user.Email == "" {
 		user.Email = oldUser.Email
 	}
 	if user.Status == "" {
 		user.Status = oldUser.Status
 	}
-	user.DateCreated = oldUser.DateCreated
 
 	// For total update if values aren't provided for fields first_name
 	// and last_name they take and empty default value
@@ -91,11 +149,148 @@ func (us *userService) UpdateUser(isTotalUpdate bool, user users.User) (*users.U
 			user.LastName = oldUser.LastName
 		}
 	}
-	return us.userDao.Update(user)
+	return us.saveUpdate(user, oldUser)
 }
 
-func (us *userService) DeleteUser(userId int64) rest_error.RestErr {
-	return us.userDao.Delete(userId)
+/// PatchUser applies a merge patch or JSON patch to the user with userId.
+/// Unlike UpdateUser, fields can be cleared by patching them to null.
+func (us *userService) PatchUser(userId int64, patch users.UserPatch) (*users.User, rest_error.RestErr) {
+	oldUser, getErr := us.GetUser(userId)
+	if getErr != nil {
+		return nil, getErr
+	}
+	user, patchErr := patch.Apply(*oldUser)
+	if patchErr != nil {
+		return nil, patchErr
+	}
+	return us.saveUpdate(*user, oldUser)
+}
+
+/// saveUpdate validates and persists user over oldUser. A non empty
+/// user.Password is a new plain text password, an empty one keeps the
+/// old password. A new email isn't saved, it has to be confirmed first.
+func (us *userService) saveUpdate(user users.User, oldUser *users.User) (*users.User, rest_error.RestErr) {
+	user.Id = oldUser.Id
+	user.DateCreated = oldUser.DateCreated
+	if err := user.ValidateUpdate(); err != nil {
+		return nil, err
+	}
+	if user.Status != oldUser.Status {
+		if err := users.ValidateTransition(oldUser.Status, user.Status); err != nil {
+			return nil, err
+		}
+		if !users.IsSelfServiceTransition(oldUser.Status, user.Status) {
+			return nil, error_utils.NewForbiddenError("users can only deactivate themselves, admins make other status changes")
+		}
+	}
+	/// The email change is only requested once the rest of the update
+	/// is saved, so a rejected update sends no confirmation. The update
+	/// is committed by then, so a failed request doesn't fail it, and
+	/// the user can ask for the change again.
+	newEmail := ""
+	user.Email = strings.TrimSpace(user.Email)
+	if user.Email != oldUser.Email {
+		if err := us.emailChangeService.CheckNewEmail(user.Email); err != nil {
+			return nil, err
+		}
+		newEmail = user.Email
+		user.Email = oldUser.Email
+	}
+
+	passwordChanged := user.Password != ""
+	if !passwordChanged {
+		user.Password = oldUser.Password
+	} else {
+		hash, err := us.passwordService.HashNewPassword(user.Id, user.Password, oldUser.Password)
+		if err != nil {
+			return nil, err
+		}
+		user.Password = hash
+	}
+	updatedUser, updateErr := us.userDao.Update(user)
+	if updateErr != nil {
+		return nil, updateErr
+	}
+	if passwordChanged {
+		us.passwordService.RecordPassword(updatedUser.Id, updatedUser.Password)
+	}
+	us.changeFeed.PublishUpdate(oldUser.Status, *updatedUser)
+	if newEmail != "" {
+		if err := us.emailChangeService.RequestChange(*updatedUser, newEmail); err != nil {
+			logger.Error("error requesting email change", err)
+		}
+	}
+	return updatedUser, nil
+}
+
+/// DeleteUser moves the user to StatusDeleted on behalf of the caller,
+/// who the controller has checked may act on the user. The user is kept
+/// as a tombstone, with their status history.
+func (us *userService) DeleteUser(caller Caller, userId int64) rest_error.RestErr {
+	change := users.StatusChange{UserId: userId, ToStatus: users.StatusDeleted, Reason: users.DeletionReason, ChangedBy: caller.UserId}
+	user, err := us.userDao.ChangeStatus(&change)
+	if err != nil {
+		return err
+	}
+	us.changeFeed.PublishUpdate(change.FromStatus, *user)
+	return nil
+}
+
+/// ChangeStatus moves a user to change.ToStatus on behalf of an admin,
+/// if its current status allows it
+func (us *userService) ChangeStatus(caller Caller, change users.StatusChange) (*users.User, rest_error.RestErr) {
+	if !caller.Privileged {
+		return nil, error_utils.NewForbiddenError("only admins can change a user's status")
+	}
+	if err := users.ValidateStatus(change.ToStatus); err != nil {
+		return nil, err
+	}
+	change.ChangedBy = caller.UserId
+	user, err := us.userDao.ChangeStatus(&change)
+	if err != nil {
+		return nil, err
+	}
+	us.changeFeed.PublishUpdate(change.FromStatus, *user)
+	return user, nil
+}
+
+/// SuspendUser suspends an active or locked user until an admin
+/// reactivates them
+func (us *userService) SuspendUser(caller Caller, userId int64, request users.StatusChangeRequest) (*users.User, rest_error.RestErr) {
+	if err := request.Validate(); err != nil {
+		return nil, err
+	}
+	return us.ChangeStatus(caller, users.StatusChange{UserId: userId, ToStatus: users.StatusSuspended, Reason: request.Reason})
+}
+
+/// ReactivateUser makes a suspended, locked or deactivated user active
+/// again. Pending users become active by accepting their invitation.
+func (us *userService) ReactivateUser(caller Caller, userId int64, request users.StatusChangeRequest) (*users.User, rest_error.RestErr) {
+	if err := request.Validate(); err != nil {
+		return nil, err
+	}
+	if !caller.Privileged {
+		return nil, error_utils.NewForbiddenError("only admins can change a user's status")
+	}
+	user, err := us.userDao.Get(userId)
+	if err != nil {
+		return nil, err
+	}
+	if user.Status == users.StatusPending {
+		return nil, error_utils.NewConflictError("a pending user is activated by accepting their invitation")
+	}
+	return us.ChangeStatus(caller, users.StatusChange{UserId: userId, ToStatus: users.StatusActive, Reason: request.Reason})
+}
+
+/// GetStatusHistory gets the status changes of a user, latest first
+func (us *userService) GetStatusHistory(caller Caller, userId int64) ([]users.StatusChange, rest_error.RestErr) {
+	if !caller.Privileged {
+		return nil, error_utils.NewForbiddenError("only admins can read a user's status history")
+	}
+	if _, err := us.userDao.Get(userId); err != nil {
+		return nil, err
+	}
+	return us.userDao.FindStatusHistory(userId)
 }
 
 func (us *userService) LoginUser(request users.UserLoginRequest) (*users.User, rest_error.RestErr) {
@@ -110,5 +305,9 @@ func (us *userService) LoginUser(request users.UserLoginRequest) (*users.User, r
 		logger.Error("passwords do not match", err)
 		return nil, rest_error.NewBadRequestError("wrong user password")
 	}
+	/// A lost login event doesn't fail the login
+	if err := us.userDao.RecordLogin(*user); err != nil {
+		logger.Error("error recording user login", err)
+	}
 	return user, nil
 }
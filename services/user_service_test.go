@@ -0,0 +1,185 @@
+package services
+
+import (
+	"github.com/Abacode7/bookstore_users-api/domain/users"
+	"github.com/Abacode7/bookstore_users-api/utils/error_utils"
+	"github.com/Abacode7/bookstore_utils-go/v2/rest_error"
+	"net/http"
+	"testing"
+)
+
+/// statusUserDao keeps users in memory and applies status changes like
+/// the database one, recording them
+type statusUserDao struct {
+	users.IUserDao
+	users   map[int64]users.User
+	changes []users.StatusChange
+	deleted []int64
+}
+
+func (sd *statusUserDao) Get(userId int64) (*users.User, rest_error.RestErr) {
+	user, ok := sd.users[userId]
+	if !ok {
+		return nil, rest_error.NewNotFoundError("user not found")
+	}
+	return &user, nil
+}
+
+func (sd *statusUserDao) GetMany(userIds []int64) (users.Users, rest_error.RestErr) {
+	found := make(users.Users, 0)
+	for _, userId := range userIds {
+		if user, ok := sd.users[userId]; ok {
+			found = append(found, user)
+		}
+	}
+	return found, nil
+}
+
+func (sd *statusUserDao) ChangeStatus(change *users.StatusChange) (*users.User, rest_error.RestErr) {
+	user, ok := sd.users[change.UserId]
+	if !ok {
+		return nil, rest_error.NewNotFoundError("user not found")
+	}
+	if err := users.ValidateTransition(user.Status, change.ToStatus); err != nil {
+		return nil, err
+	}
+	change.FromStatus = user.Status
+	user.Status = change.ToStatus
+	sd.users[user.Id] = user
+	sd.changes = append(sd.changes, *change)
+	return &user, nil
+}
+
+func (sd *statusUserDao) Delete(userId int64) rest_error.RestErr {
+	sd.deleted = append(sd.deleted, userId)
+	return error_utils.NewConflictError("only a pending user can be removed, other users are deleted by status")
+}
+
+func TestDeleteUserKeepsATombstone(t *testing.T) {
+	dao := &statusUserDao{users: map[int64]users.User{7: {Id: 7, Status: users.StatusActive}}}
+	feed := NewUserChangeFeed(10)
+	service := NewUserService(dao, nil, nil, feed)
+	subscription, unsubscribe := feed.Subscribe("")
+	defer unsubscribe()
+
+	if err := service.DeleteUser(Caller{UserId: 7}, 7); err != nil {
+		t.Fatal(err)
+	}
+	if len(dao.deleted) != 0 {
+		t.Errorf("got hard deletes of %v, want none", dao.deleted)
+	}
+	if got := dao.users[7].Status; got != users.StatusDeleted {
+		t.Errorf("got status %q, want %q", got, users.StatusDeleted)
+	}
+	want := users.StatusChange{UserId: 7, FromStatus: users.StatusActive, ToStatus: users.StatusDeleted,
+		Reason: users.DeletionReason, ChangedBy: 7}
+	if len(dao.changes) != 1 || dao.changes[0] != want {
+		t.Errorf("got status changes %+v, want %+v", dao.changes, want)
+	}
+
+	var events []string
+	for len(subscription.Changes) > 0 {
+		events = append(events, (<-subscription.Changes).Type)
+	}
+	if len(events) != 2 || events[0] != users.EventUserUpdated || events[1] != users.EventUserDeleted {
+		t.Errorf("got events %v, want %s then %s", events, users.EventUserUpdated, users.EventUserDeleted)
+	}
+
+	if err := service.DeleteUser(Caller{Privileged: true}, 7); err == nil || err.Status() != http.StatusConflict {
+		t.Errorf("deleting a deleted user: got %v, want a conflict", err)
+	}
+}
+
+/// updateUserDao serves and saves a single user
+type updateUserDao struct {
+	users.IUserDao
+	user users.User
+}
+
+func (ud *updateUserDao) Get(userId int64) (*users.User, rest_error.RestErr) {
+	user := ud.user
+	return &user, nil
+}
+
+func (ud *updateUserDao) Update(user users.User) (*users.User, rest_error.RestErr) {
+	ud.user = user
+	return &user, nil
+}
+
+/// failingEmailChangeService accepts new emails but fails to request
+/// their change
+type failingEmailChangeService struct {
+	IEmailChangeService
+	requested []string
+}
+
+func (fs *failingEmailChangeService) CheckNewEmail(string) rest_error.RestErr {
+	return nil
+}
+
+func (fs *failingEmailChangeService) RequestChange(user users.User, newEmail string) rest_error.RestErr {
+	fs.requested = append(fs.requested, newEmail)
+	return rest_error.NewInternalServerError("database error")
+}
+
+func TestUpdateUserKeepsTheSavedUpdateWhenTheEmailChangeFails(t *testing.T) {
+	dao := &updateUserDao{user: users.User{Id: 7, FirstName: "Ann", LastName: "Lee", Email: "ann@mail.com",
+		Status: users.StatusActive, Password: "hash"}}
+	emailChanges := &failingEmailChangeService{}
+	service := NewUserService(dao, nil, emailChanges, NewUserChangeFeed(10))
+
+	updated, err := service.UpdateUser(false, users.User{Id: 7, FirstName: "Anna", Email: "anna@mail.com"})
+	if err != nil {
+		t.Fatalf("got %v, want the saved update", err)
+	}
+	if updated.FirstName != "Anna" || updated.Email != "ann@mail.com" {
+		t.Errorf("got %s <%s>, want Anna with the unconfirmed email unchanged", updated.FirstName, updated.Email)
+	}
+	if len(emailChanges.requested) != 1 || emailChanges.requested[0] != "anna@mail.com" {
+		t.Errorf("got email changes requested for %v, want anna@mail.com", emailChanges.requested)
+	}
+}
+
+func TestUpdateUserTakesItsOwnEmailWithSpacesAsUnchanged(t *testing.T) {
+	dao := &updateUserDao{user: users.User{Id: 7, FirstName: "Ann", LastName: "Lee", Email: "ann@mail.com",
+		Status: users.StatusActive, Password: "hash"}}
+	emailChanges := &failingEmailChangeService{}
+	service := NewUserService(dao, nil, emailChanges, NewUserChangeFeed(10))
+
+	updated, err := service.UpdateUser(true, users.User{Id: 7, FirstName: "Ann", LastName: "Lee", Email: " ann@mail.com "})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.Email != "ann@mail.com" || len(emailChanges.requested) != 0 {
+		t.Errorf("got email %q and changes requested for %v, want ann@mail.com and none", updated.Email, emailChanges.requested)
+	}
+}
+
+func TestDeletedUsersAreGone(t *testing.T) {
+	dao := &statusUserDao{users: map[int64]users.User{
+		7: {Id: 7, Email: "ann@mail.com", Status: users.StatusActive},
+		8: {Id: 8, Email: "bob@mail.com", Status: users.StatusDeleted},
+	}}
+	service := NewUserService(dao, nil, nil, NewUserChangeFeed(10))
+
+	if _, err := service.GetUser(8); err == nil || err.Status() != http.StatusGone {
+		t.Errorf("getting a deleted user: got %v, want gone", err)
+	}
+	if _, err := service.UpdateUser(false, users.User{Id: 8, FirstName: "Bob"}); err == nil || err.Status() != http.StatusGone {
+		t.Errorf("updating a deleted user: got %v, want gone", err)
+	}
+	if user, err := service.GetUser(7); err != nil || user.Id != 7 {
+		t.Errorf("getting an active user: got %v, %v", user, err)
+	}
+
+	batch, err := service.GetUsers(users.BatchGetRequest{Ids: []int64{8, 7, 9}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(batch.Users) != 1 || batch.Users[0].Id != 7 {
+		t.Errorf("got users %+v, want only 7", batch.Users)
+	}
+	if len(batch.Missing) != 2 || batch.Missing[0] != 8 || batch.Missing[1] != 9 {
+		t.Errorf("got missing %v, want 8 and 9", batch.Missing)
+	}
+}
@@ -0,0 +1,138 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/Abacode7/bookstore_users-api/domain/outbox"
+	"github.com/Abacode7/bookstore_users-api/domain/webhooks"
+	"github.com/Abacode7/bookstore_users-api/utils/crypto_utils"
+	"github.com/Abacode7/bookstore_users-api/utils/date_utils"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	webhookBatchSize     = 20
+	webhookMaxAttempts   = 8
+	webhookBaseRetry     = 30 * time.Second
+	webhookMaxRetryDelay = time.Hour
+
+	/// Receivers verify WebhookSignatureHeader against the HMAC-SHA256,
+	/// keyed with their secret, of WebhookTimestampHeader + "." + body
+	WebhookSignatureHeader = "X-Webhook-Signature"
+	WebhookTimestampHeader = "X-Webhook-Timestamp"
+	WebhookEventHeader     = "X-Webhook-Event"
+	WebhookDeliveryHeader  = "X-Webhook-Delivery"
+)
+
+/// IWebhookDispatcher is published the relayed outbox events, queueing a
+/// delivery for every subscription to them, and sends queued deliveries
+/// while it runs
+type IWebhookDispatcher interface {
+	Publish(outbox.Event) error
+	Run(stop <-chan struct{})
+}
+
+type webhookDispatcher struct {
+	subscriptionDao webhooks.ISubscriptionDao
+	deliveryDao     webhooks.IDeliveryDao
+	client          *http.Client
+	interval        time.Duration
+}
+
+/// NewWebhookDispatcher is webhookDispatcher's constructor, it polls for
+/// due deliveries every interval
+func NewWebhookDispatcher(subscriptionDao webhooks.ISubscriptionDao, deliveryDao webhooks.IDeliveryDao,
+	client *http.Client, interval time.Duration) IWebhookDispatcher {
+	return &webhookDispatcher{
+		subscriptionDao: subscriptionDao,
+		deliveryDao:     deliveryDao,
+		client:          client,
+		interval:        interval,
+	}
+}
+
+/// Publish queues event for its subscriptions. Failing makes the outbox
+/// relay it again, deliveries already queued are not duplicated.
+func (wd *webhookDispatcher) Publish(event outbox.Event) error {
+	subscriptions, err := wd.subscriptionDao.FindByEventType(event.Type)
+	if err != nil {
+		return err
+	}
+	if len(subscriptions) == 0 {
+		return nil
+	}
+	payload, marshalErr := json.Marshal(event)
+	if marshalErr != nil {
+		return marshalErr
+	}
+	for _, subscription := range subscriptions {
+		err := wd.deliveryDao.Enqueue(webhooks.Delivery{
+			SubscriptionId: subscription.Id,
+			EventId:        event.Id,
+			EventType:      event.Type,
+			Payload:        payload,
+			DateCreated:    date_utils.GetDbFormattedTime(),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+/// Run sends due deliveries until stop is closed, with a nil stop it runs
+/// for the lifetime of the process
+func (wd *webhookDispatcher) Run(stop <-chan struct{}) {
+	ticker := time.NewTicker(wd.interval)
+	defer ticker.Stop()
+	for {
+		wd.sendDue()
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (wd *webhookDispatcher) sendDue() {
+	for {
+		processed, err := wd.deliveryDao.ProcessDue(webhookBatchSize, wd.send, webhookRetryDelay, webhookMaxAttempts)
+		if err != nil || processed < webhookBatchSize {
+			return
+		}
+	}
+}
+
+/// send posts the signed delivery, any non 2xx response is a failure
+func (wd *webhookDispatcher) send(delivery webhooks.Delivery) (int, error) {
+	request, err := http.NewRequest(http.MethodPost, delivery.Url, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+	timestamp := strconv.FormatInt(date_utils.GetTime().Unix(), 10)
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set(WebhookEventHeader, delivery.EventType)
+	request.Header.Set(WebhookDeliveryHeader, strconv.FormatInt(delivery.Id, 10))
+	request.Header.Set(WebhookTimestampHeader, timestamp)
+	request.Header.Set(WebhookSignatureHeader, "sha256="+crypto_utils.GetHmacSha256(delivery.Secret, timestamp+"."+string(delivery.Payload)))
+
+	response, err := wd.client.Do(request)
+	if err != nil {
+		return 0, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return response.StatusCode, fmt.Errorf("webhook responded with status %d", response.StatusCode)
+	}
+	return response.StatusCode, nil
+}
+
+/// webhookRetryDelay doubles from 30 seconds with every failed attempt,
+/// up to an hour
+func webhookRetryDelay(attempts int) time.Duration {
+	return backoff(webhookBaseRetry, webhookMaxRetryDelay, attempts)
+}
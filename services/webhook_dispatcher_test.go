@@ -0,0 +1,221 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"github.com/Abacode7/bookstore_users-api/domain/outbox"
+	"github.com/Abacode7/bookstore_users-api/domain/users"
+	"github.com/Abacode7/bookstore_users-api/domain/webhooks"
+	"github.com/Abacode7/bookstore_users-api/utils/date_utils"
+	"github.com/Abacode7/bookstore_utils-go/v2/rest_error"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+const testWebhookSecret = "partner-secret"
+
+/// memorySubscriptionDao holds a single subscription to every event
+type memorySubscriptionDao struct {
+	webhooks.ISubscriptionDao
+	subscription webhooks.Subscription
+}
+
+func (msd *memorySubscriptionDao) FindByEventType(string) (webhooks.Subscriptions, rest_error.RestErr) {
+	return webhooks.Subscriptions{msd.subscription}, nil
+}
+
+/// memoryDeliveryDao queues deliveries in memory, on a clock the test
+/// moves, and records their attempts like deliveryDao
+type memoryDeliveryDao struct {
+	webhooks.IDeliveryDao
+	subscription webhooks.Subscription
+	deliveries   []webhooks.Delivery
+	now          time.Time
+}
+
+func (mdd *memoryDeliveryDao) Enqueue(delivery webhooks.Delivery) rest_error.RestErr {
+	delivery.Id = int64(len(mdd.deliveries) + 1)
+	delivery.Status = webhooks.DeliveryPending
+	delivery.NextAttemptAt = date_utils.FormatDbTime(mdd.now)
+	mdd.deliveries = append(mdd.deliveries, delivery)
+	return nil
+}
+
+func (mdd *memoryDeliveryDao) ProcessDue(limit int, send func(webhooks.Delivery) (int, error),
+	retryDelay func(attempts int) time.Duration, maxAttempts int) (int, rest_error.RestErr) {
+	processed := 0
+	for i := range mdd.deliveries {
+		delivery := &mdd.deliveries[i]
+		if processed == limit || delivery.Status != webhooks.DeliveryPending ||
+			delivery.NextAttemptAt > date_utils.FormatDbTime(mdd.now) {
+			continue
+		}
+		delivery.Url, delivery.Secret = mdd.subscription.Url, mdd.subscription.Secret
+		statusCode, err := send(*delivery)
+		delivery.RecordAttempt(statusCode, err, mdd.now, retryDelay, maxAttempts)
+		processed++
+	}
+	return processed, nil
+}
+
+/// webhookReceiver is a partner endpoint verifying the signature of the
+/// deliveries it receives, and answering with status
+type webhookReceiver struct {
+	mu       sync.Mutex
+	status   int
+	received []*http.Request
+	bodies   [][]byte
+	invalid  []string
+}
+
+func (wr *webhookReceiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := ioutil.ReadAll(r.Body)
+	wr.mu.Lock()
+	defer wr.mu.Unlock()
+	wr.received = append(wr.received, r)
+	wr.bodies = append(wr.bodies, body)
+
+	mac := hmac.New(sha256.New, []byte(testWebhookSecret))
+	mac.Write([]byte(r.Header.Get(WebhookTimestampHeader) + "." + string(body)))
+	expected := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+	if !hmac.Equal([]byte(expected), []byte(r.Header.Get(WebhookSignatureHeader))) {
+		wr.invalid = append(wr.invalid, r.Header.Get(WebhookDeliveryHeader))
+	}
+	w.WriteHeader(wr.status)
+}
+
+func (wr *webhookReceiver) count() int {
+	wr.mu.Lock()
+	defer wr.mu.Unlock()
+	return len(wr.received)
+}
+
+func newTestDispatcher(t *testing.T, status int) (*webhookDispatcher, *memoryDeliveryDao, *webhookReceiver, func()) {
+	receiver := &webhookReceiver{status: status}
+	server := httptest.NewServer(receiver)
+	subscription := webhooks.Subscription{Id: 1, Url: server.URL, Secret: testWebhookSecret}
+	deliveryDao := &memoryDeliveryDao{subscription: subscription, now: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)}
+	dispatcher := NewWebhookDispatcher(&memorySubscriptionDao{subscription: subscription}, deliveryDao,
+		server.Client(), time.Second).(*webhookDispatcher)
+
+	event, err := outbox.NewEvent(users.EventUserCreated, 7, map[string]interface{}{"id": 7})
+	if err != nil {
+		t.Fatal(err)
+	}
+	event.Id = 42
+	if err := dispatcher.Publish(*event); err != nil {
+		t.Fatalf("publish: %v", err)
+	}
+	return dispatcher, deliveryDao, receiver, server.Close
+}
+
+func TestWebhookDispatcherSendsSignedDeliveries(t *testing.T) {
+	dispatcher, deliveryDao, receiver, closeServer := newTestDispatcher(t, http.StatusNoContent)
+	defer closeServer()
+
+	dispatcher.sendDue()
+
+	if receiver.count() != 1 {
+		t.Fatalf("receiver got %d requests, want 1", receiver.count())
+	}
+	if len(receiver.invalid) != 0 {
+		t.Errorf("deliveries %v failed signature verification", receiver.invalid)
+	}
+	request := receiver.received[0]
+	if got := request.Header.Get(WebhookEventHeader); got != users.EventUserCreated {
+		t.Errorf("event header = %q", got)
+	}
+	if got := request.Header.Get(WebhookDeliveryHeader); got != "1" {
+		t.Errorf("delivery header = %q, want 1", got)
+	}
+	if _, err := strconv.ParseInt(request.Header.Get(WebhookTimestampHeader), 10, 64); err != nil {
+		t.Errorf("timestamp header = %q", request.Header.Get(WebhookTimestampHeader))
+	}
+	var event outbox.Event
+	if err := json.Unmarshal(receiver.bodies[0], &event); err != nil || event.Id != 42 || event.AggregateId != 7 {
+		t.Errorf("body = %s, want the event", receiver.bodies[0])
+	}
+
+	delivery := deliveryDao.deliveries[0]
+	if delivery.Status != webhooks.DeliveryDelivered || delivery.Attempts != 1 || delivery.LastStatusCode != http.StatusNoContent {
+		t.Errorf("delivery = %+v, want delivered after one attempt", delivery)
+	}
+}
+
+func TestWebhookDispatcherSignatureFailsWithAnotherSecret(t *testing.T) {
+	dispatcher, deliveryDao, receiver, closeServer := newTestDispatcher(t, http.StatusOK)
+	defer closeServer()
+	deliveryDao.subscription.Secret = "another-secret"
+
+	dispatcher.sendDue()
+
+	if len(receiver.invalid) != 1 {
+		t.Errorf("receiver accepted a delivery signed with another secret")
+	}
+}
+
+func TestWebhookDispatcherRetriesWithBackoff(t *testing.T) {
+	dispatcher, deliveryDao, receiver, closeServer := newTestDispatcher(t, http.StatusInternalServerError)
+	defer closeServer()
+
+	for attempt, delay := range []time.Duration{30 * time.Second, time.Minute, 2 * time.Minute} {
+		dispatcher.sendDue()
+		delivery := deliveryDao.deliveries[0]
+		wantNext := date_utils.FormatDbTime(deliveryDao.now.Add(delay))
+		if delivery.Status != webhooks.DeliveryPending || delivery.Attempts != attempt+1 || delivery.NextAttemptAt != wantNext {
+			t.Fatalf("after attempt %d delivery = %+v, want pending until %s", attempt+1, delivery, wantNext)
+		}
+		if delivery.LastStatusCode != http.StatusInternalServerError || delivery.LastError == "" {
+			t.Errorf("delivery = %+v, want the failure recorded", delivery)
+		}
+
+		/// Not due before its retry delay
+		deliveryDao.now = deliveryDao.now.Add(delay - time.Second)
+		dispatcher.sendDue()
+		if receiver.count() != attempt+1 {
+			t.Fatalf("receiver got %d requests before the retry was due, want %d", receiver.count(), attempt+1)
+		}
+		deliveryDao.now = deliveryDao.now.Add(time.Second)
+	}
+}
+
+func TestWebhookDispatcherMovesDeliveryToDeadAfterMaxAttempts(t *testing.T) {
+	dispatcher, deliveryDao, receiver, closeServer := newTestDispatcher(t, http.StatusBadGateway)
+	defer closeServer()
+
+	for i := 0; i < webhookMaxAttempts+2; i++ {
+		dispatcher.sendDue()
+		deliveryDao.now = deliveryDao.now.Add(webhookMaxRetryDelay)
+	}
+
+	if receiver.count() != webhookMaxAttempts {
+		t.Errorf("receiver got %d requests, want %d", receiver.count(), webhookMaxAttempts)
+	}
+	delivery := deliveryDao.deliveries[0]
+	if delivery.Status != webhooks.DeliveryDead || delivery.Attempts != webhookMaxAttempts || delivery.NextAttemptAt != "" {
+		t.Errorf("delivery = %+v, want dead after %d attempts", delivery, webhookMaxAttempts)
+	}
+}
+
+func TestWebhookRetryDelayIsCapped(t *testing.T) {
+	tests := map[int]time.Duration{
+		1:  30 * time.Second,
+		2:  time.Minute,
+		5:  8 * time.Minute,
+		7:  32 * time.Minute,
+		8:  time.Hour,
+		20: time.Hour,
+	}
+	for attempts, want := range tests {
+		if got := webhookRetryDelay(attempts); got != want {
+			t.Errorf("webhookRetryDelay(%d) = %s, want %s", attempts, got, want)
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package services
+
+import (
+	"github.com/Abacode7/bookstore_users-api/domain/webhooks"
+	"github.com/Abacode7/bookstore_users-api/utils/crypto_utils"
+	"github.com/Abacode7/bookstore_users-api/utils/date_utils"
+	"github.com/Abacode7/bookstore_users-api/utils/error_utils"
+	"github.com/Abacode7/bookstore_utils-go/v2/logger"
+	"github.com/Abacode7/bookstore_utils-go/v2/rest_error"
+)
+
+const (
+	defaultDeliveryLogLimit = 50
+	maxDeliveryLogLimit     = 500
+)
+
+type IWebhookService interface {
+	CreateSubscription(Caller, webhooks.Subscription) (*webhooks.Subscription, rest_error.RestErr)
+	GetSubscription(Caller, int64) (*webhooks.Subscription, rest_error.RestErr)
+	ListSubscriptions(Caller) (webhooks.Subscriptions, rest_error.RestErr)
+	DeleteSubscription(Caller, int64) rest_error.RestErr
+	ListDeliveries(Caller, int64, string, int) (webhooks.Deliveries, rest_error.RestErr)
+	ListDeadLetters(Caller, int) (webhooks.Deliveries, rest_error.RestErr)
+	RetryDelivery(Caller, int64) rest_error.RestErr
+}
+
+type webhookService struct {
+	subscriptionDao webhooks.ISubscriptionDao
+	deliveryDao     webhooks.IDeliveryDao
+}
+
+/// NewWebhookService is webhookService's constructor
+func NewWebhookService(subscriptionDao webhooks.ISubscriptionDao, deliveryDao webhooks.IDeliveryDao) IWebhookService {
+	return &webhookService{subscriptionDao: subscriptionDao, deliveryDao: deliveryDao}
+}
+
+/// CreateSubscription registers a webhook. The secret deliveries are
+/// signed with is generated unless given, and only returned here.
+func (ws *webhookService) CreateSubscription(caller Caller, subscription webhooks.Subscription) (*webhooks.Subscription, rest_error.RestErr) {
+	if err := authorizeWebhooks(caller); err != nil {
+		return nil, err
+	}
+	if err := subscription.Validate(); err != nil {
+		return nil, err
+	}
+	if subscription.Secret == "" {
+		secret, err := crypto_utils.GenerateToken()
+		if err != nil {
+			logger.Error("error generating webhook secret", err)
+			return nil, rest_error.NewInternalServerError("error generating webhook secret")
+		}
+		subscription.Secret = secret
+	}
+	subscription.Id = 0
+	subscription.DateCreated = date_utils.GetDbFormattedTime()
+	return ws.subscriptionDao.Save(subscription)
+}
+
+func (ws *webhookService) GetSubscription(caller Caller, subscriptionId int64) (*webhooks.Subscription, rest_error.RestErr) {
+	if err := authorizeWebhooks(caller); err != nil {
+		return nil, err
+	}
+	subscription, err := ws.subscriptionDao.Get(subscriptionId)
+	if err != nil {
+		return nil, err
+	}
+	subscription.Secret = ""
+	return subscription, nil
+}
+
+func (ws *webhookService) ListSubscriptions(caller Caller) (webhooks.Subscriptions, rest_error.RestErr) {
+	if err := authorizeWebhooks(caller); err != nil {
+		return nil, err
+	}
+	subscriptions, err := ws.subscriptionDao.FindAll()
+	if err != nil {
+		return nil, err
+	}
+	for i := range subscriptions {
+		subscriptions[i].Secret = ""
+	}
+	return subscriptions, nil
+}
+
+func (ws *webhookService) DeleteSubscription(caller Caller, subscriptionId int64) rest_error.RestErr {
+	if err := authorizeWebhooks(caller); err != nil {
+		return err
+	}
+	return ws.subscriptionDao.Delete(subscriptionId)
+}
+
+/// ListDeliveries is the delivery log of a subscription, latest first,
+/// optionally only the deliveries in status
+func (ws *webhookService) ListDeliveries(caller Caller, subscriptionId int64, status string, limit int) (webhooks.Deliveries, rest_error.RestErr) {
+	if err := authorizeWebhooks(caller); err != nil {
+		return nil, err
+	}
+	if status != "" && !webhooks.ValidDeliveryStatus(status) {
+		return nil, rest_error.NewBadRequestError("invalid delivery status")
+	}
+	if _, err := ws.subscriptionDao.Get(subscriptionId); err != nil {
+		return nil, err
+	}
+	return ws.deliveryDao.FindBySubscription(subscriptionId, status, deliveryLogLimit(limit))
+}
+
+/// ListDeadLetters lists the deliveries that ran out of attempts
+func (ws *webhookService) ListDeadLetters(caller Caller, limit int) (webhooks.Deliveries, rest_error.RestErr) {
+	if err := authorizeWebhooks(caller); err != nil {
+		return nil, err
+	}
+	return ws.deliveryDao.FindDead(deliveryLogLimit(limit))
+}
+
+/// RetryDelivery moves a dead delivery back into the queue
+func (ws *webhookService) RetryDelivery(caller Caller, deliveryId int64) rest_error.RestErr {
+	if err := authorizeWebhooks(caller); err != nil {
+		return err
+	}
+	return ws.deliveryDao.Requeue(deliveryId)
+}
+
+func authorizeWebhooks(caller Caller) rest_error.RestErr {
+	if !caller.Privileged {
+		return error_utils.NewForbiddenError("only admins can manage webhooks")
+	}
+	return nil
+}
+
+func deliveryLogLimit(limit int) int {
+	if limit <= 0 {
+		return defaultDeliveryLogLimit
+	}
+	if limit > maxDeliveryLogLimit {
+		return maxDeliveryLogLimit
+	}
+	return limit
+}
@@ -1,6 +1,10 @@
 package crypto_utils
 
 import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
 	"golang.org/x/crypto/bcrypt"
 )
 
@@ -18,3 +22,27 @@ func CompareHashAndPassword(hash, password string) error {
 	}
 	return nil
 }
+
+/// GenerateToken returns a random url safe token for links sent to users
+func GenerateToken() (string, error) {
+	bytes := make([]byte, 32)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(bytes), nil
+}
+
+/// GetSha256 returns the hex encoded sha256 of input. Tokens are stored
+/// by their sha256 so that a leaked table can't be used to redeem them.
+func GetSha256(input string) string {
+	sum := sha256.Sum256([]byte(input))
+	return hex.EncodeToString(sum[:])
+}
+
+/// GetHmacSha256 returns the hex encoded HMAC-SHA256 of message keyed
+/// with secret
+func GetHmacSha256(secret string, message string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(message))
+	return hex.EncodeToString(mac.Sum(nil))
+}
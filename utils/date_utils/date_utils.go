@@ -2,6 +2,8 @@ package date_utils
 
 import "time"
 
+const dbTimeLayout = "2006-01-02 15:04:05"
+
 func GetTime() time.Time {
 	return time.Now().UTC()
 }
@@ -12,6 +14,13 @@ func GetFormattedTime() string {
 }
 
 func GetDbFormattedTime() string {
-	time := GetTime()
-	return time.Format("2006-01-02 15:04:05")
+	return FormatDbTime(GetTime())
+}
+
+func FormatDbTime(t time.Time) string {
+	return t.UTC().Format(dbTimeLayout)
+}
+
+func ParseDbTime(value string) (time.Time, error) {
+	return time.ParseInLocation(dbTimeLayout, value, time.UTC)
 }
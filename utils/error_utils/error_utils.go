@@ -0,0 +1,65 @@
+package error_utils
+
+import (
+	"fmt"
+	"github.com/Abacode7/bookstore_utils-go/v2/rest_error"
+	"net/http"
+)
+
+/// restErr implements rest_error.RestErr for statuses
+/// rest_error has no constructor for
+type restErr struct {
+	message string
+	status  int
+	error   string
+}
+
+func (re *restErr) Message() string {
+	return re.message
+}
+
+func (re *restErr) Status() int {
+	return re.status
+}
+
+func (re *restErr) Error() string {
+	return fmt.Sprintf("message: %s; status: %d; error; %s", re.message, re.status, re.error)
+}
+
+/// NewRestErr creates a rest_error.RestErr with any status, for errors
+/// coming from outside this service
+func NewRestErr(message string, status int) rest_error.RestErr {
+	return &restErr{
+		message: message,
+		status:  status,
+		error:   http.StatusText(status),
+	}
+}
+
+func NewRequestEntityTooLargeError(message string) rest_error.RestErr {
+	return NewRestErr(message, http.StatusRequestEntityTooLarge)
+}
+
+func NewTooManyRequestsError(message string) rest_error.RestErr {
+	return NewRestErr(message, http.StatusTooManyRequests)
+}
+
+func NewForbiddenError(message string) rest_error.RestErr {
+	return NewRestErr(message, http.StatusForbidden)
+}
+
+func NewConflictError(message string) rest_error.RestErr {
+	return NewRestErr(message, http.StatusConflict)
+}
+
+func NewGoneError(message string) rest_error.RestErr {
+	return NewRestErr(message, http.StatusGone)
+}
+
+func NewFailedDependencyError(message string) rest_error.RestErr {
+	return NewRestErr(message, http.StatusFailedDependency)
+}
+
+func NewUnsupportedMediaTypeError(message string) rest_error.RestErr {
+	return NewRestErr(message, http.StatusUnsupportedMediaType)
+}
@@ -0,0 +1,45 @@
+package error_utils
+
+import (
+	"github.com/Abacode7/bookstore_utils-go/v2/rest_error"
+	"net/http"
+)
+
+/// FieldError is a problem with a single field of a request
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+/// ValidationError is a bad request error that knows which fields of the
+/// request were invalid
+type ValidationError interface {
+	rest_error.RestErr
+	FieldErrors() []FieldError
+}
+
+type validationError struct {
+	restErr
+	fieldErrors []FieldError
+}
+
+func (ve *validationError) FieldErrors() []FieldError {
+	return ve.fieldErrors
+}
+
+/// NewValidationError creates a bad request error for the invalid fields
+func NewValidationError(message string, fieldErrors ...FieldError) rest_error.RestErr {
+	return &validationError{
+		restErr: restErr{
+			message: message,
+			status:  http.StatusBadRequest,
+			error:   http.StatusText(http.StatusBadRequest),
+		},
+		fieldErrors: fieldErrors,
+	}
+}
+
+/// NewFieldError creates a validation error for a single invalid field
+func NewFieldError(field string, message string) rest_error.RestErr {
+	return NewValidationError(message, FieldError{Field: field, Message: message})
+}
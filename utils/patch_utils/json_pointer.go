@@ -0,0 +1,153 @@
+package patch_utils
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+/// parsePointer splits an RFC 6901 JSON pointer into its unescaped tokens
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return []string{}, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("invalid json pointer %q", pointer)
+	}
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		token = strings.Replace(token, "~1", "/", -1)
+		tokens[i] = strings.Replace(token, "~0", "~", -1)
+	}
+	return tokens, nil
+}
+
+/// arrayIndex parses token as an index into an array of length size.
+/// When appending, "-" and size itself are valid too.
+func arrayIndex(token string, size int, appending bool) (int, error) {
+	if appending && token == "-" {
+		return size, nil
+	}
+	index, err := strconv.Atoi(token)
+	if err != nil || index < 0 || (token != "0" && strings.HasPrefix(token, "0")) {
+		return 0, fmt.Errorf("invalid array index %q", token)
+	}
+	if index > size || (!appending && index == size) {
+		return 0, fmt.Errorf("array index %d out of bounds", index)
+	}
+	return index, nil
+}
+
+var errPathNotFound = errors.New("path not found")
+
+/// getAt returns the value at tokens in node
+func getAt(node interface{}, tokens []string) (interface{}, error) {
+	for _, token := range tokens {
+		switch n := node.(type) {
+		case map[string]interface{}:
+			child, ok := n[token]
+			if !ok {
+				return nil, errPathNotFound
+			}
+			node = child
+		case []interface{}:
+			index, err := arrayIndex(token, len(n), false)
+			if err != nil {
+				return nil, err
+			}
+			node = n[index]
+		default:
+			return nil, errPathNotFound
+		}
+	}
+	return node, nil
+}
+
+/// addAt adds value at tokens in node and returns the resulting node.
+/// Object members are created or replaced, array elements are inserted.
+func addAt(node interface{}, tokens []string, value interface{}) (interface{}, error) {
+	if len(tokens) == 0 {
+		return value, nil
+	}
+	token, last := tokens[0], len(tokens) == 1
+	switch n := node.(type) {
+	case map[string]interface{}:
+		if last {
+			n[token] = value
+			return n, nil
+		}
+		child, ok := n[token]
+		if !ok {
+			return nil, errPathNotFound
+		}
+		newChild, err := addAt(child, tokens[1:], value)
+		if err != nil {
+			return nil, err
+		}
+		n[token] = newChild
+		return n, nil
+	case []interface{}:
+		index, err := arrayIndex(token, len(n), last)
+		if err != nil {
+			return nil, err
+		}
+		if last {
+			n = append(n, nil)
+			copy(n[index+1:], n[index:])
+			n[index] = value
+			return n, nil
+		}
+		newChild, err := addAt(n[index], tokens[1:], value)
+		if err != nil {
+			return nil, err
+		}
+		n[index] = newChild
+		return n, nil
+	default:
+		return nil, errPathNotFound
+	}
+}
+
+/// removeAt removes the value at tokens in node and returns the
+/// resulting node along with the removed value
+func removeAt(node interface{}, tokens []string) (interface{}, interface{}, error) {
+	if len(tokens) == 0 {
+		return nil, nil, errors.New("cannot remove the whole document")
+	}
+	token, last := tokens[0], len(tokens) == 1
+	switch n := node.(type) {
+	case map[string]interface{}:
+		child, ok := n[token]
+		if !ok {
+			return nil, nil, errPathNotFound
+		}
+		if last {
+			delete(n, token)
+			return n, child, nil
+		}
+		newChild, removed, err := removeAt(child, tokens[1:])
+		if err != nil {
+			return nil, nil, err
+		}
+		n[token] = newChild
+		return n, removed, nil
+	case []interface{}:
+		index, err := arrayIndex(token, len(n), false)
+		if err != nil {
+			return nil, nil, err
+		}
+		if last {
+			removed := n[index]
+			return append(n[:index], n[index+1:]...), removed, nil
+		}
+		newChild, removed, err := removeAt(n[index], tokens[1:])
+		if err != nil {
+			return nil, nil, err
+		}
+		n[index] = newChild
+		return n, removed, nil
+	default:
+		return nil, nil, errPathNotFound
+	}
+}
@@ -0,0 +1,153 @@
+package patch_utils
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+/// ErrTestFailed is returned when a JSON Patch "test" operation fails
+var ErrTestFailed = errors.New("test operation failed")
+
+/// Operation is a single RFC 6902 JSON Patch operation. Value is kept
+/// raw so that an explicit null can be told apart from no value.
+type Operation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	From  string          `json:"from"`
+	Value json.RawMessage `json:"value"`
+}
+
+/// MergePatch applies an RFC 7396 merge patch to target and returns the
+/// result. Null members of the patch remove the matching target members.
+func MergePatch(target interface{}, patch interface{}) interface{} {
+	patchObject, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	targetObject, ok := target.(map[string]interface{})
+	if !ok {
+		targetObject = make(map[string]interface{})
+	}
+	for name, value := range patchObject {
+		if value == nil {
+			delete(targetObject, name)
+			continue
+		}
+		targetObject[name] = MergePatch(targetObject[name], value)
+	}
+	return targetObject
+}
+
+/// ApplyJSONPatch applies RFC 6902 JSON Patch operations to doc in order
+/// and returns the result. doc is modified in place and must not be used
+/// after an error.
+func ApplyJSONPatch(doc interface{}, operations []Operation) (interface{}, error) {
+	for i, operation := range operations {
+		var err error
+		doc, err = applyOperation(doc, operation)
+		if err == ErrTestFailed {
+			return nil, err
+		}
+		if err != nil {
+			return nil, fmt.Errorf("operation %d (%s %s): %s", i, operation.Op, operation.Path, err.Error())
+		}
+	}
+	return doc, nil
+}
+
+func applyOperation(doc interface{}, operation Operation) (interface{}, error) {
+	path, err := parsePointer(operation.Path)
+	if err != nil {
+		return nil, err
+	}
+	switch operation.Op {
+	case "add", "replace", "test":
+		value, err := operation.value()
+		if err != nil {
+			return nil, err
+		}
+		if operation.Op == "add" {
+			return addAt(doc, path, value)
+		}
+		current, err := getAt(doc, path)
+		if err != nil {
+			return nil, err
+		}
+		if operation.Op == "test" {
+			if !reflect.DeepEqual(current, value) {
+				return nil, ErrTestFailed
+			}
+			return doc, nil
+		}
+		if len(path) == 0 {
+			return value, nil
+		}
+		doc, _, err = removeAt(doc, path)
+		if err != nil {
+			return nil, err
+		}
+		return addAt(doc, path, value)
+	case "remove":
+		doc, _, err = removeAt(doc, path)
+		return doc, err
+	case "move", "copy":
+		from, err := parsePointer(operation.From)
+		if err != nil {
+			return nil, err
+		}
+		value, err := getAt(doc, from)
+		if err != nil {
+			return nil, err
+		}
+		if operation.Op == "copy" {
+			if value, err = deepCopy(value); err != nil {
+				return nil, err
+			}
+			return addAt(doc, path, value)
+		}
+		if isPrefix(from, path) && len(from) < len(path) {
+			return nil, errors.New("cannot move a value into one of its children")
+		}
+		if doc, _, err = removeAt(doc, from); err != nil {
+			return nil, err
+		}
+		return addAt(doc, path, value)
+	default:
+		return nil, fmt.Errorf("unknown operation %q", operation.Op)
+	}
+}
+
+func (operation Operation) value() (interface{}, error) {
+	if len(operation.Value) == 0 {
+		return nil, errors.New("missing value")
+	}
+	var value interface{}
+	if err := json.Unmarshal(operation.Value, &value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+func isPrefix(prefix []string, tokens []string) bool {
+	if len(prefix) > len(tokens) {
+		return false
+	}
+	for i := range prefix {
+		if prefix[i] != tokens[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func deepCopy(value interface{}) (interface{}, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	var result interface{}
+	err = json.Unmarshal(data, &result)
+	return result, err
+}
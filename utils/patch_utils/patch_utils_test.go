@@ -0,0 +1,112 @@
+package patch_utils
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func decode(t *testing.T, data string) interface{} {
+	t.Helper()
+	var value interface{}
+	if err := json.Unmarshal([]byte(data), &value); err != nil {
+		t.Fatalf("invalid json %s: %v", data, err)
+	}
+	return value
+}
+
+func TestMergePatch(t *testing.T) {
+	tests := []struct {
+		name, target, patch, want string
+	}{
+		{"sets a member", `{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{"adds a member", `{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{"removes a null member", `{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+		{"removing a missing member is a no-op", `{"a":"b"}`, `{"c":null}`, `{"a":"b"}`},
+		{"replaces arrays whole", `{"a":["b","c"]}`, `{"a":["d"]}`, `{"a":["d"]}`},
+		{"merges nested objects", `{"a":{"b":"c","d":"e"}}`, `{"a":{"d":null,"f":"g"}}`, `{"a":{"b":"c","f":"g"}}`},
+		{"replaces a non object target", `{"a":"b"}`, `{"a":{"c":"d"}}`, `{"a":{"c":"d"}}`},
+		{"a non object patch replaces the target", `{"a":"b"}`, `["c"]`, `["c"]`},
+		{"an empty patch changes nothing", `{"a":"b"}`, `{}`, `{"a":"b"}`},
+	}
+	for _, test := range tests {
+		got := MergePatch(decode(t, test.target), decode(t, test.patch))
+		if want := decode(t, test.want); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: got %v, want %v", test.name, got, want)
+		}
+	}
+}
+
+func TestApplyJSONPatch(t *testing.T) {
+	tests := []struct {
+		name, doc, patch, want string
+	}{
+		{"replaces a member", `{"a":"b"}`, `[{"op":"replace","path":"/a","value":"c"}]`, `{"a":"c"}`},
+		{"adds a null member", `{"a":"b"}`, `[{"op":"add","path":"/c","value":null}]`, `{"a":"b","c":null}`},
+		{"removes a member", `{"a":"b","c":"d"}`, `[{"op":"remove","path":"/a"}]`, `{"c":"d"}`},
+		{"unescapes ~1 to a slash", `{"a/b":"c"}`, `[{"op":"replace","path":"/a~1b","value":"d"}]`, `{"a/b":"d"}`},
+		{"unescapes ~0 to a tilde", `{"a~b":"c"}`, `[{"op":"replace","path":"/a~0b","value":"d"}]`, `{"a~b":"d"}`},
+		{"unescapes ~01 to ~1", `{"~1":"c"}`, `[{"op":"remove","path":"/~01"}]`, `{}`},
+		{"the empty pointer is the whole document", `{"a":"b"}`, `[{"op":"replace","path":"","value":{"c":"d"}}]`, `{"c":"d"}`},
+		{"inserts into arrays", `{"a":["b","d"]}`, `[{"op":"add","path":"/a/1","value":"c"}]`, `{"a":["b","c","d"]}`},
+		{"appends to arrays with -", `{"a":["b"]}`, `[{"op":"add","path":"/a/-","value":"c"}]`, `{"a":["b","c"]}`},
+		{"passing tests change nothing", `{"a":"b"}`, `[{"op":"test","path":"/a","value":"b"}]`, `{"a":"b"}`},
+		{"moves a member", `{"a":"b"}`, `[{"op":"move","from":"/a","path":"/c"}]`, `{"c":"b"}`},
+		{"moves array elements", `{"a":["b","c"]}`, `[{"op":"move","from":"/a/0","path":"/a/-"}]`, `{"a":["c","b"]}`},
+		{"copies a member", `{"a":{"b":"c"}}`, `[{"op":"copy","from":"/a","path":"/d"}]`, `{"a":{"b":"c"},"d":{"b":"c"}}`},
+		{"applies operations in order", `{"a":"b"}`,
+			`[{"op":"test","path":"/a","value":"b"},{"op":"replace","path":"/a","value":"c"},{"op":"test","path":"/a","value":"c"}]`,
+			`{"a":"c"}`},
+	}
+	for _, test := range tests {
+		var operations []Operation
+		if err := json.Unmarshal([]byte(test.patch), &operations); err != nil {
+			t.Fatalf("%s: invalid patch: %v", test.name, err)
+		}
+		got, err := ApplyJSONPatch(decode(t, test.doc), operations)
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		if want := decode(t, test.want); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: got %v, want %v", test.name, got, want)
+		}
+	}
+}
+
+func TestApplyJSONPatchErrors(t *testing.T) {
+	tests := []struct {
+		name, doc, patch string
+		want             error
+	}{
+		{"a failed test", `{"a":"b"}`, `[{"op":"test","path":"/a","value":"c"}]`, ErrTestFailed},
+		{"a failed test after other operations", `{"a":"b"}`,
+			`[{"op":"replace","path":"/a","value":"c"},{"op":"test","path":"/a","value":"b"}]`, ErrTestFailed},
+		{"a test of a missing member", `{"a":"b"}`, `[{"op":"test","path":"/c","value":"b"}]`, nil},
+		{"a pointer without a leading slash", `{"a":"b"}`, `[{"op":"replace","path":"a","value":"c"}]`, nil},
+		{"replacing a missing member", `{"a":"b"}`, `[{"op":"replace","path":"/c","value":"d"}]`, nil},
+		{"adding under a missing member", `{"a":"b"}`, `[{"op":"add","path":"/c/d","value":"e"}]`, nil},
+		{"an index out of bounds", `{"a":["b"]}`, `[{"op":"replace","path":"/a/1","value":"c"}]`, nil},
+		{"an index with a leading zero", `{"a":["b","c"]}`, `[{"op":"remove","path":"/a/01"}]`, nil},
+		{"- outside of an add", `{"a":["b"]}`, `[{"op":"remove","path":"/a/-"}]`, nil},
+		{"removing the whole document", `{"a":"b"}`, `[{"op":"remove","path":""}]`, nil},
+		{"moving a member into its child", `{"a":{"b":"c"}}`, `[{"op":"move","from":"/a","path":"/a/b"}]`, nil},
+		{"moving from a missing member", `{"a":"b"}`, `[{"op":"move","from":"/c","path":"/d"}]`, nil},
+		{"a missing value", `{"a":"b"}`, `[{"op":"add","path":"/a"}]`, nil},
+		{"an unknown operation", `{"a":"b"}`, `[{"op":"merge","path":"/a","value":"c"}]`, nil},
+	}
+	for _, test := range tests {
+		var operations []Operation
+		if err := json.Unmarshal([]byte(test.patch), &operations); err != nil {
+			t.Fatalf("%s: invalid patch: %v", test.name, err)
+		}
+		_, err := ApplyJSONPatch(decode(t, test.doc), operations)
+		if err == nil {
+			t.Errorf("%s: got no error", test.name)
+			continue
+		}
+		if (test.want == ErrTestFailed) != (err == ErrTestFailed) {
+			t.Errorf("%s: got %v, want ErrTestFailed only for failed tests", test.name, err)
+		}
+	}
+}